@@ -1,50 +1,390 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"github.com/seantur/ray_tracer_challenge/datatypes"
-	"github.com/seantur/ray_tracer_challenge/raytracing"
 	"github.com/seantur/ray_tracer_challenge/scene"
-	"github.com/seantur/ray_tracer_challenge/shapes"
-	"math"
+	"image"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"time"
 )
 
-func saveScene(path string) {
-	room := shapes.GetCube()
-	mat := room.GetMaterial()
-	mat.Pattern = raytracing.GetCheckers(raytracing.HexColor(raytracing.White), raytracing.HexColor(raytracing.Black))
-	mat.Pattern.SetTransform(datatypes.GetScaling(0.1, 0.1, 0.1))
-	room.SetMaterial(mat)
-	room.SetTransform(datatypes.GetScaling(100, 100, 100))
+type options struct {
+	scenePath, output string
+	width, height     int
+	fov               float64
+	depth, workers    int
+	progress          bool
+	samples           int
+	jitter            bool
+	filter            string
+	adaptive          bool
+	threshold         float64
+	adaptiveDepth     int
+	sampleImage       string
+	aperture, focus   float64
+	projection        string
+	tileSize          int
+	tileOrder         string
+	timeout           time.Duration
+	passes            int
+	listen, worker    string
+	exr               scene.ExrOptions
+	toneMap           scene.ToneMap
+	set               map[string]bool // flags given on the command line
+}
+
+func parseFlags(args []string) (options, error) {
+	var opts options
+
+	fs := flag.NewFlagSet("ray_tracer_challenge", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s [flags] scene.yml\n       %s -worker host:port [-workers n]\n", fs.Name(), fs.Name())
+		fs.PrintDefaults()
+	}
+
+	fs.StringVar(&opts.output, "o", "scene.png", "output image, the format is taken from the extension (.png, .jpg, .hdr, .exr)")
+	fs.IntVar(&opts.width, "width", 0, "override the camera width in pixels")
+	fs.IntVar(&opts.height, "height", 0, "override the camera height in pixels")
+	fs.Float64Var(&opts.fov, "fov", 0, "override the camera field of view in radians")
+	fs.IntVar(&opts.depth, "depth", scene.DefaultDepth, "maximum reflection/refraction depth")
+	fs.IntVar(&opts.workers, "workers", 0, "number of render goroutines (default NumCPU*4)")
+	fs.BoolVar(&opts.progress, "progress", true, "show a progress bar on stderr")
+	fs.IntVar(&opts.samples, "samples", 0, "override the samples per pixel")
+	fs.BoolVar(&opts.jitter, "jitter", false, "override whether samples are jittered within the pixel")
+	fs.StringVar(&opts.filter, "filter", "", "override the reconstruction filter (box, tent, mitchell, gaussian)")
+	fs.BoolVar(&opts.adaptive, "adaptive", false, "override whether pixels are adaptively subdivided by contrast")
+	fs.Float64Var(&opts.threshold, "threshold", 0, "override the contrast above which adaptive pixels are subdivided")
+	fs.IntVar(&opts.adaptiveDepth, "adaptive-depth", 0, "override how many times an adaptive pixel may be subdivided")
+	fs.Float64Var(&opts.aperture, "aperture", 0, "override the lens radius, 0 is a pinhole camera")
+	fs.Float64Var(&opts.focus, "focal-distance", 0, "override the distance to the plane in focus")
+	fs.StringVar(&opts.projection, "projection", "", "override the projection (perspective, orthographic, fisheye, equirectangular)")
+	fs.IntVar(&opts.tileSize, "tile-size", scene.DefaultTileSize, "side of the square tiles the image is rendered in")
+	fs.StringVar(&opts.tileOrder, "tile-order", "spiral", "order tiles are rendered in (spiral, scanline)")
+	fs.DurationVar(&opts.timeout, "timeout", 0, "give up rendering after this long, e.g. 90s (default no limit)")
+	fs.IntVar(&opts.passes, "passes", 0, "render progressively, saving the output after each of this many passes, negative keeps refining until interrupted")
+	fs.StringVar(&opts.sampleImage, "sample-image", "", "also save an image of the rays traced per pixel to this path")
+	opts.toneMap = scene.GetToneMap()
+	fs.Float64Var(&opts.toneMap.Exposure, "exposure", 0, "brighten (or darken when negative) PNG and JPEG output by this many stops")
+	toneMap := fs.String("tonemap", "clamp", "how PNG and JPEG output fits highlights into range (clamp, reinhard, reinhard-extended, aces)")
+	fs.Float64Var(&opts.toneMap.WhitePoint, "white-point", opts.toneMap.WhitePoint, "the brightness reinhard-extended maps to white")
+	exrFloat := fs.Bool("exr-float", false, "write 32 bit float instead of half float EXR channels")
+	exrUncompressed := fs.Bool("exr-uncompressed", false, "write EXR files without ZIP compression")
+	fs.StringVar(&opts.listen, "listen", "", "distribute the render to the workers that connect to this address, e.g. :7878")
+	fs.StringVar(&opts.worker, "worker", "", "render tiles for the coordinator at this address instead of a scene file")
+
+	if err := fs.Parse(args); err != nil {
+		return opts, err
+	}
+
+	var err error
+	if opts.toneMap.Operator, err = scene.GetToneOperator(*toneMap); err != nil {
+		return opts, err
+	}
+
+	if *exrFloat {
+		opts.exr.PixelType = scene.ExrFloat
+	}
+	if *exrUncompressed {
+		opts.exr.Compression = scene.ExrNone
+	}
+
+	opts.set = map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		opts.set[f.Name] = true
+	})
+
+	if opts.worker != "" {
+		if fs.NArg() != 0 || opts.listen != "" {
+			return opts, errors.New("a worker takes its scene from the coordinator")
+		}
+		return opts, nil
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return opts, errors.New("expected exactly one scene file")
+	}
+	opts.scenePath = fs.Arg(0)
+
+	if opts.listen != "" && (opts.passes != 0 || opts.sampleImage != "") {
+		return opts, errors.New("-passes and -sample-image cannot be used with -listen")
+	}
+
+	if opts.passes != 0 && opts.sampleImage != "" {
+		return opts, errors.New("-sample-image cannot be used with -passes")
+	}
+
+	for _, path := range []string{opts.output, opts.sampleImage} {
+		if path != "" && !scene.SupportedFormat(path) {
+			return opts, fmt.Errorf("unsupported output format for %q", path)
+		}
+	}
+
+	if opts.width < 0 || opts.height < 0 || opts.fov < 0 || opts.depth < 0 || opts.workers < 0 || opts.samples < 0 ||
+		opts.threshold < 0 || opts.adaptiveDepth < 0 || opts.aperture < 0 || opts.focus < 0 ||
+		opts.tileSize < 1 || opts.timeout < 0 {
+		return opts, errors.New("numeric flags must not be negative")
+	}
+
+	return opts, nil
+}
+
+// save writes im to path in the format named by its extension. HDR formats
+// keep the full range, EXR files with the -exr flags, and anything else is
+// tone mapped to sRGB first.
+func (opts options) save(im image.Image, path string) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".hdr":
+		return scene.SaveImage(im, path)
+	case ".exr":
+	default:
+		return scene.SaveImage(opts.toneMap.Apply(im), path)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := scene.EncodeExr(f, im, &opts.exr); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// progressBar draws a single updating line such as [=====>    ]  50% 1m5s left
+func progressBar(out io.Writer) func(scene.RenderProgress) {
+	const width = 40
+
+	return func(p scene.RenderProgress) {
+		filled := width * p.TilesDone / p.Tiles
+		bar := strings.Repeat("=", filled)
+		if filled < width {
+			bar += ">" + strings.Repeat(" ", width-filled-1)
+		}
+
+		fmt.Fprintf(out, "\r[%s] %3d%% %v left   ", bar, 100*p.TilesDone/p.Tiles, p.Remaining.Round(time.Second))
+		if p.TilesDone == p.Tiles {
+			fmt.Fprintln(out)
+		}
+	}
+}
+
+func run(args []string) error {
+	opts, err := parseFlags(args)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := renderContext(opts.timeout)
+	defer cancel()
+
+	if opts.worker != "" {
+		workers := opts.workers
+		if workers == 0 {
+			workers = runtime.NumCPU()
+		}
 
-	//s1 := shapes.GetSphere()
-	s2 := shapes.GetSphere()
-	s3 := shapes.GetSphere()
+		fmt.Fprintf(os.Stderr, "Rendering tiles for %s with %d goroutines\n", opts.worker, workers)
+		return scene.DialWorker(ctx, opts.worker, workers)
+	}
 
-	mat = s2.GetMaterial()
-	mat.RGB = raytracing.HexColor(raytracing.Red)
-	s2.SetMaterial(mat)
+	world, camera, err := scene.LoadSceneFile(opts.scenePath)
+	if err != nil {
+		return err
+	}
 
-	s2.SetTransform(datatypes.GetTranslation(0, 0, -3))
-	s3.SetTransform(datatypes.GetTranslation(-5, 0, -10))
+	if opts.width > 0 || opts.height > 0 || opts.fov > 0 {
+		hsize, vsize, fov := camera.Hsize, camera.Vsize, camera.Fov
+		if opts.width > 0 {
+			hsize = opts.width
+		}
+		if opts.height > 0 {
+			vsize = opts.height
+		}
+		if opts.fov > 0 {
+			fov = opts.fov
+		}
+
+		camera.SetView(hsize, vsize, fov)
+	}
+
+	if opts.samples > 0 {
+		camera.SamplesPerPixel = opts.samples
+	}
+	if opts.set["jitter"] {
+		camera.Jitter = opts.jitter
+	}
+	if opts.filter != "" {
+		if camera.Filter, err = scene.GetFilter(opts.filter); err != nil {
+			return err
+		}
+	}
+	if opts.set["adaptive"] {
+		camera.Adaptive = opts.adaptive
+	}
+	if opts.set["threshold"] {
+		camera.AdaptiveThreshold = opts.threshold
+	}
+	if opts.set["adaptive-depth"] {
+		camera.AdaptiveDepth = opts.adaptiveDepth
+	}
+	if opts.set["aperture"] {
+		camera.Aperture = opts.aperture
+	}
+	if opts.focus > 0 {
+		camera.FocalDistance = opts.focus
+	}
+	if opts.projection != "" {
+		if camera.Projection, err = scene.GetProjection(opts.projection); err != nil {
+			return err
+		}
+	}
+
+	camera.Depth = opts.depth
+	if opts.workers > 0 {
+		camera.Workers = opts.workers
+	}
+	if opts.progress {
+		camera.Progress = progressBar(os.Stderr)
+	}
+
+	camera.TileSize = opts.tileSize
+	if camera.TileOrder, err = scene.GetTileOrder(opts.tileOrder); err != nil {
+		return err
+	}
+
+	if opts.listen != "" {
+		data, err := ioutil.ReadFile(opts.scenePath)
+		if err != nil {
+			return err
+		}
+		return renderDistributed(ctx, opts, scene.GetCoordinator(data, filepath.Dir(opts.scenePath), camera))
+	}
+
+	fmt.Fprintf(os.Stderr, "Rendering %s at %dx%d with %d goroutines\n", opts.scenePath, camera.Hsize, camera.Vsize, camera.Workers)
+
+	if opts.passes != 0 {
+		return renderProgressive(ctx, opts, &camera, world)
+	}
+
+	start := time.Now()
+	output, counts, err := camera.RenderContext(ctx, world)
+	if err != nil {
+		fmt.Fprintln(os.Stderr)
+		return fmt.Errorf("render stopped after %v: %v", time.Since(start).Round(time.Millisecond), err)
+	}
+	fmt.Fprintf(os.Stderr, "done (%v elapsed)\n", time.Since(start))
+
+	if opts.sampleImage != "" {
+		if err := scene.SaveImage(counts, opts.sampleImage); err != nil {
+			return err
+		}
+	}
+
+	return opts.save(output, opts.output)
+}
+
+// renderContext is cancelled on ^C or when the timeout runs out, so renders
+// stop cleanly
+func renderContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+
+	go func() {
+		select {
+		case <-interrupt:
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(interrupt)
+	}()
+
+	if timeout <= 0 {
+		return ctx, cancel
+	}
+
+	ctx, cancelTimeout := context.WithTimeout(ctx, timeout)
+	return ctx, func() {
+		cancelTimeout()
+		cancel()
+	}
+}
+
+type progressiveRenderer interface {
+	RenderProgressive(ctx context.Context, w scene.World, passes int, onPass func(int, image.Image) bool) (image.Image, error)
+}
+
+// renderProgressive saves the output after every pass, so stopping early
+// still leaves the best image so far
+func renderProgressive(ctx context.Context, opts options, camera progressiveRenderer, world scene.World) error {
+	start := time.Now()
+	saved := 0
+
+	var saveErr error
+
+	_, err := camera.RenderProgressive(ctx, world, opts.passes, func(pass int, im image.Image) bool {
+		if saveErr = opts.save(im, opts.output); saveErr != nil {
+			return false
+		}
+
+		saved = pass
+		fmt.Fprintf(os.Stderr, "pass %d saved (%v elapsed)\n", pass, time.Since(start).Round(time.Millisecond))
+		return true
+	})
+
+	if saveErr != nil {
+		return saveErr
+	} else if err != nil && saved == 0 {
+		return fmt.Errorf("render stopped before the first pass: %v", err)
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "\nstopped after pass %d: %v\n", saved, err)
+	}
+
+	return nil
+}
 
-	world := scene.GetWorld()
-	world.Light.Position = datatypes.Point(10, 10, 10)
-	world.Shapes = []shapes.Shape{room, s2, s3}
+// renderDistributed hands the coordinator's tiles to the workers that connect
+// to opts.listen and saves the image they render
+func renderDistributed(ctx context.Context, opts options, co *scene.Coordinator) error {
+	l, err := net.Listen("tcp", opts.listen)
+	if err != nil {
+		return err
+	}
 
-	camera := scene.GetCamera(500, 500, math.Pi/3)
-	//camera.Transform = datatypes.ViewTransform(datatypes.Point(0, 0, 0), datatypes.Point(0, 0, -1), datatypes.Vector(0, 1, 0))
+	fmt.Fprintf(os.Stderr, "Waiting for workers on %s\n", l.Addr())
 
-	fmt.Println("Rendering...")
 	start := time.Now()
-	output := camera.RenderConcurrent(world)
-	duration := time.Since(start)
-	fmt.Printf("done (%v elapsed)\n", duration)
+	output, err := co.Render(ctx, l)
+	if err != nil {
+		fmt.Fprintln(os.Stderr)
+		return fmt.Errorf("render stopped after %v: %v", time.Since(start).Round(time.Millisecond), err)
+	}
+	fmt.Fprintf(os.Stderr, "done (%v elapsed)\n", time.Since(start))
 
-	scene.SavePng(output, path)
+	return opts.save(output, opts.output)
 }
 
 func main() {
-	saveScene("scene.png")
+	err := run(os.Args[1:])
+	if err == flag.ErrHelp {
+		return
+	} else if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"github.com/seantur/ray_tracer_challenge/scene"
+	"image"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFlags(t *testing.T) {
+
+	t.Run("Flags are parsed and validated", func(t *testing.T) {
+		tests := []struct {
+			name  string
+			args  []string
+			err   string
+			check func(t *testing.T, opts options)
+		}{
+			{"defaults", []string{"scene.yml"}, "", func(t *testing.T, opts options) {
+				if opts.scenePath != "scene.yml" || opts.output != "scene.png" || opts.tileSize != scene.DefaultTileSize {
+					t.Errorf("got %+v", opts)
+				}
+				if len(opts.set) != 0 || opts.toneMap.Operator != scene.Clamp {
+					t.Errorf("expected nothing to be overridden, got %v", opts.set)
+				}
+			}},
+			{"false booleans still override the scene", []string{"-jitter=false", "-adaptive=false", "scene.yml"}, "", func(t *testing.T, opts options) {
+				if !opts.set["jitter"] || !opts.set["adaptive"] || opts.jitter || opts.adaptive {
+					t.Errorf("got jitter %v, adaptive %v, set %v", opts.jitter, opts.adaptive, opts.set)
+				}
+			}},
+			{"zero values still override the scene", []string{"-threshold", "0", "-aperture", "0", "scene.yml"}, "", func(t *testing.T, opts options) {
+				if !opts.set["threshold"] || !opts.set["aperture"] || opts.set["adaptive-depth"] {
+					t.Errorf("got set %v", opts.set)
+				}
+			}},
+			{"output options", []string{"-tonemap", "aces", "-exr-float", "-exr-uncompressed", "-o", "out.exr", "scene.yml"}, "", func(t *testing.T, opts options) {
+				if opts.toneMap.Operator != scene.ACES || opts.exr.PixelType != scene.ExrFloat || opts.exr.Compression != scene.ExrNone {
+					t.Errorf("got tone map %+v, exr %+v", opts.toneMap, opts.exr)
+				}
+			}},
+			{"a worker needs no scene", []string{"-worker", "host:7878"}, "", func(t *testing.T, opts options) {
+				if opts.worker != "host:7878" || opts.scenePath != "" {
+					t.Errorf("got %+v", opts)
+				}
+			}},
+			{"a worker with a scene", []string{"-worker", "host:7878", "scene.yml"}, "a worker takes its scene from the coordinator", nil},
+			{"a worker that listens", []string{"-worker", "host:7878", "-listen", ":7878"}, "a worker takes its scene from the coordinator", nil},
+			{"no scene", []string{}, "expected exactly one scene file", nil},
+			{"two scenes", []string{"a.yml", "b.yml"}, "expected exactly one scene file", nil},
+			{"passes with listen", []string{"-listen", ":7878", "-passes", "4", "scene.yml"}, "cannot be used with -listen", nil},
+			{"sample image with listen", []string{"-listen", ":7878", "-sample-image", "counts.png", "scene.yml"}, "cannot be used with -listen", nil},
+			{"sample image with passes", []string{"-passes", "-1", "-sample-image", "counts.png", "scene.yml"}, "-sample-image cannot be used with -passes", nil},
+			{"unknown output format", []string{"-o", "scene.gif", "scene.yml"}, "unsupported output format for \"scene.gif\"", nil},
+			{"unknown sample image format", []string{"-sample-image", "counts.txt", "scene.yml"}, "unsupported output format for \"counts.txt\"", nil},
+			{"unknown tone map", []string{"-tonemap", "sepia", "scene.yml"}, "unknown tone mapping operator \"sepia\"", nil},
+			{"negative width", []string{"-width", "-1", "scene.yml"}, "numeric flags must not be negative", nil},
+			{"empty tiles", []string{"-tile-size", "0", "scene.yml"}, "numeric flags must not be negative", nil},
+			{"unknown flag", []string{"-colour", "scene.yml"}, "flag provided but not defined", nil},
+		}
+
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				opts, err := parseFlags(test.args)
+
+				if test.err != "" {
+					if err == nil || !strings.Contains(err.Error(), test.err) {
+						t.Fatalf("got error %v, want %q", err, test.err)
+					}
+					return
+				}
+
+				if err != nil {
+					t.Fatal(err)
+				}
+				test.check(t, opts)
+			})
+		}
+	})
+
+	t.Run("Flags override the scene's camera", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "main")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "scene.yml")
+		if err := ioutil.WriteFile(path, []byte("- add: camera\n  width: 10\n  height: 10\n  field-of-view: 1\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		output, counts := filepath.Join(dir, "out.png"), filepath.Join(dir, "counts.png")
+		if err := run([]string{"-progress=false", "-width", "4", "-height", "3", "-o", output, "-sample-image", counts, path}); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, file := range []string{output, counts} {
+			f, err := os.Open(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			config, _, err := image.DecodeConfig(f)
+			f.Close()
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.Width != 4 || config.Height != 3 {
+				t.Errorf("%s is %dx%d, want 4x3", filepath.Base(file), config.Width, config.Height)
+			}
+		}
+	})
+}
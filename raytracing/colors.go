@@ -1,6 +1,9 @@
 package raytracing
 
-import "image/color"
+import (
+	"image/color"
+	"math"
+)
 
 // RGB
 type RGB struct {
@@ -35,6 +38,40 @@ func (c RGB) Cvt() color.RGBA64 {
 	return color.RGBA64{R: uint16(r), G: uint16(g), B: uint16(b), A: uint16(a)}
 }
 
+// Luminance is the perceived brightness of a linear color, weighted by the
+// Rec. 709 primaries
+func (c RGB) Luminance() float64 {
+	return 0.2126*c.Red + 0.7152*c.Green + 0.0722*c.Blue
+}
+
+// RGBE packs the color into the shared exponent format of Radiance .hdr
+// files, a byte of mantissa per channel and a common exponent biased by 128.
+// Negative channels are stored as 0.
+func (c RGB) RGBE() [4]byte {
+	r, g, b := math.Max(c.Red, 0), math.Max(c.Green, 0), math.Max(c.Blue, 0)
+
+	v := math.Max(r, math.Max(g, b))
+	if v < 1e-32 {
+		return [4]byte{}
+	}
+
+	mantissa, exp := math.Frexp(v)
+	scale := mantissa * 256 / v
+
+	return [4]byte{byte(r * scale), byte(g * scale), byte(b * scale), byte(exp + 128)}
+}
+
+// RGBEColor unpacks a color stored by RGBE
+func RGBEColor(rgbe [4]byte) RGB {
+	if rgbe[3] == 0 {
+		return RGB{}
+	}
+
+	scale := math.Ldexp(1, int(rgbe[3])-128-8)
+
+	return RGB{(float64(rgbe[0]) + 0.5) * scale, (float64(rgbe[1]) + 0.5) * scale, (float64(rgbe[2]) + 0.5) * scale}
+}
+
 func (c *RGB) Multiply(a float64) RGB {
 	return RGB{c.Red * a, c.Green * a, c.Blue * a}
 }
@@ -60,6 +97,30 @@ func HexColor(hex int) RGB {
 	return RGB{float64((hex&0xFF0000)>>16) / 255.0, float64((hex&0x00FF00)>>8) / 255.0, float64(hex&0x0000FF) / 255.}
 }
 
+// HexColorSRGB reads hex as an sRGB color, as picked in most paint programs,
+// and converts it to the linear values lighting is calculated in
+func HexColorSRGB(hex int) RGB {
+	c := HexColor(hex)
+	return RGB{SRGBToLinear(c.Red), SRGBToLinear(c.Green), SRGBToLinear(c.Blue)}
+}
+
+// SRGBToLinear undoes the sRGB transfer curve of a channel in [0, 1]
+func SRGBToLinear(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+// LinearToSRGB applies the sRGB transfer curve to a channel in [0, 1], which
+// spends more of the 8 bits of an image on the darks where eyes notice them
+func LinearToSRGB(v float64) float64 {
+	if v <= 0.0031308 {
+		return v * 12.92
+	}
+	return 1.055*math.Pow(v, 1/2.4) - 0.055
+}
+
 const (
 	Black   = 0x000000
 	White   = 0xFFFFFF
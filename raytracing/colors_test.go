@@ -2,6 +2,7 @@ package raytracing
 
 import (
 	"github.com/seantur/ray_tracer_challenge/datatypes"
+	"math"
 	"testing"
 )
 
@@ -64,4 +65,51 @@ func TestColors(t *testing.T) {
 		AssertColorsEqual(t, Add(c1, c2, c3), RGB{5, 6, 5})
 	})
 
+	t.Run("Luminance weights green the most", func(t *testing.T) {
+		datatypes.AssertVal(t, RGB{1, 1, 1}.Luminance(), 1)
+		datatypes.AssertVal(t, RGB{0, 1, 0}.Luminance(), 0.7152)
+	})
+
+	t.Run("RGBE shares the exponent of the brightest channel", func(t *testing.T) {
+		if got := (RGB{1, 0.5, 0.25}).RGBE(); got != [4]byte{128, 64, 32, 129} {
+			t.Errorf("got %v", got)
+		}
+
+		if got := (RGB{0, -1, 0}).RGBE(); got != [4]byte{} {
+			t.Errorf("black should be all zero, got %v", got)
+		}
+
+		AssertColorsEqual(t, RGBEColor([4]byte{}), RGB{})
+	})
+
+	t.Run("RGBE round trips within its precision", func(t *testing.T) {
+		for _, c := range []RGB{{1, 0.5, 0.25}, {1500, 20, 0.3}, {0.001, 0.002, 0.0005}} {
+			got := RGBEColor(c.RGBE())
+			brightest := math.Max(c.Red, math.Max(c.Green, c.Blue))
+
+			for _, diff := range []float64{got.Red - c.Red, got.Green - c.Green, got.Blue - c.Blue} {
+				if math.Abs(diff) > brightest/128 {
+					t.Errorf("%v came back as %v", c, got)
+				}
+			}
+		}
+	})
+
+	t.Run("sRGB hex colors are converted to linear", func(t *testing.T) {
+		AssertColorsEqual(t, HexColorSRGB(White), RGB{1, 1, 1})
+		AssertColorsEqual(t, HexColorSRGB(Black), RGB{0, 0, 0})
+
+		// sRGB 50% grey is about 21% linear
+		grey := HexColorSRGB(0x808080)
+		datatypes.AssertVal(t, math.Round(grey.Red*1000)/1000, 0.216)
+	})
+
+	t.Run("The sRGB curves undo each other", func(t *testing.T) {
+		for _, v := range []float64{0, 0.001, 0.04, 0.2, 0.5, 0.9, 1} {
+			if got := SRGBToLinear(LinearToSRGB(v)); !datatypes.IsClose(got, v) {
+				t.Errorf("got %f want %f", got, v)
+			}
+		}
+	})
+
 }
@@ -6,6 +6,11 @@ type Material struct {
 	RGB                                                                              RGB
 	Ambient, Diffuse, Specular, Shininess, Reflective, Transparency, RefractiveIndex float64
 	Pattern                                                                          Pattern
+
+	// Absorption is how quickly light passing through a transparent material
+	// takes on its color, following the Beer-Lambert law. At 0 only the
+	// surfaces tint the light.
+	Absorption float64
 }
 
 func GetMaterial() Material {
@@ -0,0 +1,270 @@
+package raytracing
+
+import (
+	"github.com/seantur/ray_tracer_challenge/datatypes"
+	"math"
+	"math/rand"
+)
+
+// Noise is 3D gradient noise in the style of Perlin's improved noise. The
+// permutation comes from the seed, so noise with the same seed always gives
+// the same values.
+type Noise struct {
+	perm [512]int
+}
+
+func GetNoise(seed int64) *Noise {
+	n := Noise{}
+
+	for i, v := range rand.New(rand.NewSource(seed)).Perm(256) {
+		n.perm[i] = v
+		n.perm[i+256] = v
+	}
+
+	return &n
+}
+
+// fade eases t so the noise is smooth across lattice cells
+func fade(t float64) float64 {
+	return t * t * t * (t*(t*6-15) + 10)
+}
+
+func lerp(t, a, b float64) float64 {
+	return a + t*(b-a)
+}
+
+// grad is the dot product of (x, y, z) with one of 12 gradients picked by hash
+func grad(hash int, x, y, z float64) float64 {
+	h := hash & 15
+
+	u := y
+	if h < 8 {
+		u = x
+	}
+
+	v := z
+	if h < 4 {
+		v = y
+	} else if h == 12 || h == 14 {
+		v = x
+	}
+
+	if h&1 != 0 {
+		u = -u
+	}
+	if h&2 != 0 {
+		v = -v
+	}
+
+	return u + v
+}
+
+// At returns the noise at (x, y, z), roughly from -1 to 1 and 0 at every
+// integer point
+func (n *Noise) At(x, y, z float64) float64 {
+	fx, fy, fz := math.Floor(x), math.Floor(y), math.Floor(z)
+	X, Y, Z := int(fx)&255, int(fy)&255, int(fz)&255
+	x, y, z = x-fx, y-fy, z-fz
+
+	u, v, w := fade(x), fade(y), fade(z)
+	p := &n.perm
+
+	a := p[X] + Y
+	aa, ab := p[a]+Z, p[a+1]+Z
+	b := p[X+1] + Y
+	ba, bb := p[b]+Z, p[b+1]+Z
+
+	return lerp(w,
+		lerp(v,
+			lerp(u, grad(p[aa], x, y, z), grad(p[ba], x-1, y, z)),
+			lerp(u, grad(p[ab], x, y-1, z), grad(p[bb], x-1, y-1, z))),
+		lerp(v,
+			lerp(u, grad(p[aa+1], x, y, z-1), grad(p[ba+1], x-1, y, z-1)),
+			lerp(u, grad(p[ab+1], x, y-1, z-1), grad(p[bb+1], x-1, y-1, z-1))))
+}
+
+// Fractal is how octaves of noise are summed, each octave's frequency is
+// Lacunarity times the last one's and its amplitude Gain times the last one's
+type Fractal struct {
+	Octaves    int
+	Lacunarity float64
+	Gain       float64
+}
+
+func GetFractal() Fractal {
+	return Fractal{Octaves: 4, Lacunarity: 2, Gain: 0.5}
+}
+
+// sum adds up the octaves of f(x, y, z), at least one, and divides by the
+// total amplitude so the result has the same range as f
+func (f Fractal) sum(point datatypes.Tuple, noise func(x, y, z float64) float64) float64 {
+	total, amplitude, frequency, norm := 0.0, 1.0, 1.0, 0.0
+
+	for i := 0; i < f.Octaves || i == 0; i++ {
+		total += amplitude * noise(point.X*frequency, point.Y*frequency, point.Z*frequency)
+		norm += amplitude
+
+		amplitude *= f.Gain
+		frequency *= f.Lacunarity
+	}
+
+	if norm == 0 {
+		return 0
+	}
+
+	return total / norm
+}
+
+// FBm is fractal Brownian motion, octaves of noise summed, from about -1 to 1
+func (n *Noise) FBm(point datatypes.Tuple, f Fractal) float64 {
+	return f.sum(point, n.At)
+}
+
+// Turbulence sums the absolute value of each octave, giving creases where the
+// noise crosses zero, from 0 to about 1
+func (n *Noise) Turbulence(point datatypes.Tuple, f Fractal) float64 {
+	return f.sum(point, func(x, y, z float64) float64 {
+		return math.Abs(n.At(x, y, z))
+	})
+}
+
+// FBmPattern blends from A to B with fractal noise, cloudy at the defaults
+type FBmPattern struct {
+	A, B  Pattern
+	Noise *Noise
+	Fractal
+	Transform datatypes.Matrix
+}
+
+func GetFBmPattern(a, b Pattern, seed int64) *FBmPattern {
+	return &FBmPattern{A: a, B: b, Noise: GetNoise(seed), Fractal: GetFractal(), Transform: datatypes.GetIdentity()}
+}
+
+func (p *FBmPattern) At(point datatypes.Tuple) RGB {
+	return blendAt(p.A, p.B, point, (p.Noise.FBm(point, p.Fractal)+1)/2)
+}
+
+func (p *FBmPattern) SetTransform(m datatypes.Matrix) {
+	p.Transform = m
+}
+
+func (p *FBmPattern) GetTransform() datatypes.Matrix {
+	return p.Transform
+}
+
+// TurbulencePattern blends from A to B with turbulence
+type TurbulencePattern struct {
+	A, B  Pattern
+	Noise *Noise
+	Fractal
+	Transform datatypes.Matrix
+}
+
+func GetTurbulencePattern(a, b Pattern, seed int64) *TurbulencePattern {
+	return &TurbulencePattern{A: a, B: b, Noise: GetNoise(seed), Fractal: GetFractal(), Transform: datatypes.GetIdentity()}
+}
+
+func (p *TurbulencePattern) At(point datatypes.Tuple) RGB {
+	return blendAt(p.A, p.B, point, p.Noise.Turbulence(point, p.Fractal))
+}
+
+func (p *TurbulencePattern) SetTransform(m datatypes.Matrix) {
+	p.Transform = m
+}
+
+func (p *TurbulencePattern) GetTransform() datatypes.Matrix {
+	return p.Transform
+}
+
+// Marble has veins of B in A running across x, Frequency veins per unit,
+// pushed around by Strength times the turbulence
+type Marble struct {
+	A, B  Pattern
+	Noise *Noise
+	Fractal
+	Frequency, Strength float64
+	Transform           datatypes.Matrix
+}
+
+func GetMarble(a, b Pattern, seed int64) *Marble {
+	return &Marble{A: a, B: b, Noise: GetNoise(seed), Fractal: GetFractal(), Frequency: 1, Strength: 5, Transform: datatypes.GetIdentity()}
+}
+
+func (p *Marble) At(point datatypes.Tuple) RGB {
+	phase := point.X*p.Frequency + p.Strength*p.Noise.Turbulence(point, p.Fractal)
+	vein := 1 - math.Abs(math.Sin(phase*math.Pi))
+
+	return blendAt(p.A, p.B, point, vein)
+}
+
+func (p *Marble) SetTransform(m datatypes.Matrix) {
+	p.Transform = m
+}
+
+func (p *Marble) GetTransform() datatypes.Matrix {
+	return p.Transform
+}
+
+// Wood has rings around the y axis, Frequency rings per unit of radius, each
+// fading from A to B and wobbled by Strength times the fractal noise
+type Wood struct {
+	A, B  Pattern
+	Noise *Noise
+	Fractal
+	Frequency, Strength float64
+	Transform           datatypes.Matrix
+}
+
+func GetWood(a, b Pattern, seed int64) *Wood {
+	return &Wood{A: a, B: b, Noise: GetNoise(seed), Fractal: GetFractal(), Frequency: 1, Strength: 0.1, Transform: datatypes.GetIdentity()}
+}
+
+func (p *Wood) At(point datatypes.Tuple) RGB {
+	radius := math.Sqrt(point.X*point.X+point.Z*point.Z) + p.Strength*p.Noise.FBm(point, p.Fractal)
+	ring := radius * p.Frequency
+
+	return blendAt(p.A, p.B, point, ring-math.Floor(ring))
+}
+
+func (p *Wood) SetTransform(m datatypes.Matrix) {
+	p.Transform = m
+}
+
+func (p *Wood) GetTransform() datatypes.Matrix {
+	return p.Transform
+}
+
+// Perturb jitters the point it looks Pattern up at by up to about Amount in
+// each axis, using fractal noise so nearby points move together
+type Perturb struct {
+	Pattern Pattern
+	Noise   *Noise
+	Fractal
+	Amount    float64
+	Transform datatypes.Matrix
+}
+
+func GetPerturb(p Pattern, seed int64) *Perturb {
+	return &Perturb{Pattern: p, Noise: GetNoise(seed), Fractal: GetFractal(), Amount: 0.2, Transform: datatypes.GetIdentity()}
+}
+
+func (p *Perturb) At(point datatypes.Tuple) RGB {
+	// offset lookups so each axis moves independently
+	y := datatypes.Point(point.X+31.4, point.Y+15.9, point.Z+26.5)
+	z := datatypes.Point(point.X-35.8, point.Y-97.9, point.Z-32.3)
+
+	moved := datatypes.Point(
+		point.X+p.Amount*p.Noise.FBm(point, p.Fractal),
+		point.Y+p.Amount*p.Noise.FBm(y, p.Fractal),
+		point.Z+p.Amount*p.Noise.FBm(z, p.Fractal))
+
+	return nestedAt(p.Pattern, moved)
+}
+
+func (p *Perturb) SetTransform(m datatypes.Matrix) {
+	p.Transform = m
+}
+
+func (p *Perturb) GetTransform() datatypes.Matrix {
+	return p.Transform
+}
@@ -0,0 +1,165 @@
+package raytracing
+
+import (
+	"github.com/seantur/ray_tracer_challenge/datatypes"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestNoise(t *testing.T) {
+	black := RGB{Red: 0, Green: 0, Blue: 0}
+	white := RGB{Red: 1, Green: 1, Blue: 1}
+
+	// randomPoint is somewhere in a 20 unit cube around the origin
+	randomPoint := func(r *rand.Rand) datatypes.Tuple {
+		return datatypes.Point(r.Float64()*20-10, r.Float64()*20-10, r.Float64()*20-10)
+	}
+
+	t.Run("Noise is zero on the lattice", func(t *testing.T) {
+		n := GetNoise(1)
+
+		for _, p := range [][3]float64{{0, 0, 0}, {1, 2, 3}, {-4, 7, -300}} {
+			datatypes.AssertVal(t, n.At(p[0], p[1], p[2]), 0)
+		}
+	})
+
+	t.Run("Noise is seeded", func(t *testing.T) {
+		a, b, c := GetNoise(7), GetNoise(7), GetNoise(8)
+
+		same, different := true, false
+		for i := 0; i < 50; i++ {
+			x, y, z := float64(i)*0.37, float64(i)*0.11, -float64(i)*0.23
+			same = same && a.At(x, y, z) == b.At(x, y, z)
+			different = different || a.At(x, y, z) != c.At(x, y, z)
+		}
+
+		if !same {
+			t.Error("expected noise with the same seed to match")
+		}
+		if !different {
+			t.Error("expected noise with another seed to differ")
+		}
+	})
+
+	t.Run("Noise is bounded and continuous", func(t *testing.T) {
+		n := GetNoise(3)
+		r := rand.New(rand.NewSource(1))
+
+		for i := 0; i < 1000; i++ {
+			p := randomPoint(r)
+			v := n.At(p.X, p.Y, p.Z)
+
+			if v < -1 || v > 1 {
+				t.Fatalf("noise at %v is %f", p, v)
+			}
+			if step := math.Abs(n.At(p.X+1e-4, p.Y, p.Z) - v); step > 1e-3 {
+				t.Fatalf("noise jumps by %f near %v", step, p)
+			}
+		}
+	})
+
+	t.Run("One octave of fBm is the noise itself", func(t *testing.T) {
+		n := GetNoise(4)
+		p := datatypes.Point(0.3, 1.7, -2.2)
+
+		f := GetFractal()
+		f.Octaves = 1
+		datatypes.AssertVal(t, n.FBm(p, f), n.At(p.X, p.Y, p.Z))
+
+		// with no gain the later octaves add nothing
+		f.Octaves, f.Gain = 6, 0
+		datatypes.AssertVal(t, n.FBm(p, f), n.At(p.X, p.Y, p.Z))
+	})
+
+	t.Run("Octaves add finer detail", func(t *testing.T) {
+		n := GetNoise(4)
+		p := datatypes.Point(0.3, 1.7, -2.2)
+
+		f := GetFractal()
+		f.Octaves = 2
+		want := (n.At(p.X, p.Y, p.Z) + 0.5*n.At(2*p.X, 2*p.Y, 2*p.Z)) / 1.5
+
+		if got := n.FBm(p, f); !datatypes.IsClose(got, want) {
+			t.Errorf("got %f want %f", got, want)
+		}
+
+		f.Lacunarity, f.Gain = 3, 0.25
+		want = (n.At(p.X, p.Y, p.Z) + 0.25*n.At(3*p.X, 3*p.Y, 3*p.Z)) / 1.25
+
+		if got := n.FBm(p, f); !datatypes.IsClose(got, want) {
+			t.Errorf("got %f want %f", got, want)
+		}
+	})
+
+	t.Run("fBm and turbulence stay in range", func(t *testing.T) {
+		n := GetNoise(5)
+		f := GetFractal()
+		r := rand.New(rand.NewSource(2))
+
+		for i := 0; i < 500; i++ {
+			p := randomPoint(r)
+
+			if v := n.FBm(p, f); v < -1 || v > 1 {
+				t.Fatalf("fBm at %v is %f", p, v)
+			}
+			if v := n.Turbulence(p, f); v < 0 || v > 1 {
+				t.Fatalf("turbulence at %v is %f", p, v)
+			}
+		}
+	})
+
+	t.Run("Noise patterns blend their colors", func(t *testing.T) {
+		fbm := GetFBmPattern(GetSolid(black), GetSolid(white), 1)
+		fbm.Octaves = 1
+		AssertColorsEqual(t, fbm.At(datatypes.Point(1, 2, 3)), RGB{Red: 0.5, Green: 0.5, Blue: 0.5})
+
+		turbulence := GetTurbulencePattern(GetSolid(black), GetSolid(white), 1)
+		turbulence.Octaves = 1
+		AssertColorsEqual(t, turbulence.At(datatypes.Point(1, 2, 3)), black)
+	})
+
+	t.Run("Marble without turbulence has straight veins", func(t *testing.T) {
+		marble := GetMarble(GetSolid(black), GetSolid(white), 1)
+		marble.Strength = 0
+
+		AssertColorsEqual(t, marble.At(datatypes.Point(0, 0.3, 0.7)), white)
+		AssertColorsEqual(t, marble.At(datatypes.Point(0.5, 0.3, 0.7)), black)
+
+		marble.Frequency = 2
+		AssertColorsEqual(t, marble.At(datatypes.Point(0.5, 0.3, 0.7)), white)
+	})
+
+	t.Run("Wood without noise has round rings", func(t *testing.T) {
+		wood := GetWood(GetSolid(black), GetSolid(white), 1)
+		wood.Strength = 0
+
+		AssertColorsEqual(t, wood.At(datatypes.Point(0.25, 5, 0)), RGB{Red: 0.25, Green: 0.25, Blue: 0.25})
+		AssertColorsEqual(t, wood.At(datatypes.Point(0, -5, 1.25)), RGB{Red: 0.25, Green: 0.25, Blue: 0.25})
+
+		wood.Frequency = 2
+		AssertColorsEqual(t, wood.At(datatypes.Point(0.3, 0, 0.4)), black)
+	})
+
+	t.Run("Perturb moves the point a wrapped pattern is looked up at", func(t *testing.T) {
+		stripes := GetStripe(white, black)
+		stripes.SetTransform(datatypes.GetScaling(0.5, 1, 1))
+
+		perturb := GetPerturb(stripes, 1)
+		perturb.Amount = 0
+
+		// the stripes' own transform still applies
+		AssertColorsEqual(t, perturb.At(datatypes.Point(0.75, 0, 0)), black)
+
+		perturb.Amount = 1
+		r := rand.New(rand.NewSource(3))
+		moved := false
+		for i := 0; i < 100 && !moved; i++ {
+			p := randomPoint(r)
+			moved = perturb.At(p) != nestedAt(stripes, p)
+		}
+		if !moved {
+			t.Error("expected perturb to change some lookups")
+		}
+	})
+}
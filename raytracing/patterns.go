@@ -11,12 +11,66 @@ type Pattern interface {
 	GetTransform() datatypes.Matrix
 }
 
+// blendAt looks up a and b at point and blends them, a at t = 0 and b at t = 1
+func blendAt(a, b Pattern, point datatypes.Tuple, t float64) RGB {
+	return blend(nestedAt(a, point), nestedAt(b, point), t)
+}
+
+// nestedAt looks up a pattern held by another pattern, point is in the outer
+// pattern's space and goes through the inner pattern's own transform
+func nestedAt(p Pattern, point datatypes.Tuple) RGB {
+	if s, ok := p.(*Solid); ok {
+		return s.Color
+	}
+
+	m := p.GetTransform()
+	inv, _ := m.Inverse()
+	return p.At(datatypes.TupleMultiply(inv, point))
+}
+
+// blend is a at t = 0 and b at t = 1
+func blend(a, b RGB, t float64) RGB {
+	t = math.Max(0, math.Min(1, t))
+	return Add(a.Multiply(1-t), b.Multiply(t))
+}
+
+// Solid is the same color everywhere, it fills a slot of another pattern
+// with a plain color
+type Solid struct {
+	Color     RGB
+	Transform datatypes.Matrix
+}
+
+func GetSolid(c RGB) Pattern {
+	s := Solid{c, datatypes.GetIdentity()}
+	return &s
+}
+
+func (s *Solid) At(point datatypes.Tuple) RGB {
+	return s.Color
+}
+
+func (s *Solid) SetTransform(m datatypes.Matrix) {
+	s.Transform = m
+}
+
+func (s *Solid) GetTransform() datatypes.Matrix {
+	return s.Transform
+}
+
+// Stripe, Gradient, Ring and Checkers alternate between the patterns A and B,
+// which are looked up through their own transforms. The GetX constructors
+// fill them with solid colors, GetXOf takes any patterns.
 type Stripe struct {
-	A, B      RGB
+	A, B      Pattern
 	Transform datatypes.Matrix
 }
 
 func GetStripe(a, b RGB) Pattern {
+	return GetStripeOf(GetSolid(a), GetSolid(b))
+}
+
+func GetStripeOf(a, b Pattern) Pattern {
 	s := Stripe{a, b, datatypes.GetIdentity()}
 	return &s
 }
@@ -24,10 +78,10 @@ func GetStripe(a, b RGB) Pattern {
 func (s *Stripe) At(point datatypes.Tuple) RGB {
 
 	if math.Mod(math.Floor(point.X), 2) == 0 {
-		return s.A
+		return nestedAt(s.A, point)
 	}
 
-	return s.B
+	return nestedAt(s.B, point)
 }
 
 func (s *Stripe) SetTransform(m datatypes.Matrix) {
@@ -39,20 +93,23 @@ func (s *Stripe) GetTransform() datatypes.Matrix {
 }
 
 type Gradient struct {
-	A, B      RGB
+	A, B      Pattern
 	Transform datatypes.Matrix
 }
 
 func GetGradient(a, b RGB) Pattern {
+	return GetGradientOf(GetSolid(a), GetSolid(b))
+}
+
+func GetGradientOf(a, b Pattern) Pattern {
 	g := Gradient{a, b, datatypes.GetIdentity()}
 	return &g
 }
 
 func (g *Gradient) At(point datatypes.Tuple) RGB {
-	distance := Subtract(g.B, g.A)
 	frac := point.X - math.Floor(point.X)
 
-	return Add(g.A, distance.Multiply(frac))
+	return blendAt(g.A, g.B, point, frac)
 }
 
 func (g *Gradient) SetTransform(m datatypes.Matrix) {
@@ -64,20 +121,24 @@ func (g *Gradient) GetTransform() datatypes.Matrix {
 }
 
 type Ring struct {
-	A, B      RGB
+	A, B      Pattern
 	Transform datatypes.Matrix
 }
 
 func GetRing(a, b RGB) Pattern {
+	return GetRingOf(GetSolid(a), GetSolid(b))
+}
+
+func GetRingOf(a, b Pattern) Pattern {
 	r := Ring{a, b, datatypes.GetIdentity()}
 	return &r
 }
 
 func (r *Ring) At(point datatypes.Tuple) RGB {
 	if math.Mod(math.Floor(math.Sqrt(math.Pow(point.X, 2)+math.Pow(point.Z, 2))), 2) == 0 {
-		return r.A
+		return nestedAt(r.A, point)
 	}
-	return r.B
+	return nestedAt(r.B, point)
 }
 
 func (r *Ring) SetTransform(m datatypes.Matrix) {
@@ -89,11 +150,15 @@ func (r *Ring) GetTransform() datatypes.Matrix {
 }
 
 type Checkers struct {
-	A, B      RGB
+	A, B      Pattern
 	Transform datatypes.Matrix
 }
 
 func GetCheckers(a, b RGB) Pattern {
+	return GetCheckersOf(GetSolid(a), GetSolid(b))
+}
+
+func GetCheckersOf(a, b Pattern) Pattern {
 	r := Checkers{a, b, datatypes.GetIdentity()}
 	return &r
 }
@@ -101,9 +166,9 @@ func GetCheckers(a, b RGB) Pattern {
 func (c *Checkers) At(point datatypes.Tuple) RGB {
 
 	if math.Mod(math.Floor(point.X)+math.Floor(point.Y)+math.Floor(point.Z), 2) == 0 {
-		return c.A
+		return nestedAt(c.A, point)
 	}
-	return c.B
+	return nestedAt(c.B, point)
 }
 
 func (c *Checkers) SetTransform(m datatypes.Matrix) {
@@ -114,6 +179,85 @@ func (c *Checkers) GetTransform() datatypes.Matrix {
 	return c.Transform
 }
 
+// Blend mixes Patterns at each point in proportion to Weights, a pattern
+// without a weight, or every pattern when there are none, counts as 1
+type Blend struct {
+	Patterns  []Pattern
+	Weights   []float64
+	Transform datatypes.Matrix
+}
+
+// GetBlend averages patterns
+func GetBlend(patterns ...Pattern) *Blend {
+	return &Blend{Patterns: patterns, Transform: datatypes.GetIdentity()}
+}
+
+// GetLerp is a at t = 0 and b at t = 1
+func GetLerp(a, b Pattern, t float64) *Blend {
+	return &Blend{Patterns: []Pattern{a, b}, Weights: []float64{1 - t, t}, Transform: datatypes.GetIdentity()}
+}
+
+func (b *Blend) At(point datatypes.Tuple) RGB {
+	sum, total := RGB{}, 0.0
+
+	for i, p := range b.Patterns {
+		weight := 1.0
+		if i < len(b.Weights) {
+			weight = b.Weights[i]
+		}
+
+		c := nestedAt(p, point)
+		sum = Add(sum, c.Multiply(weight))
+		total += weight
+	}
+
+	if total == 0 {
+		return RGB{}
+	}
+
+	return sum.Multiply(1 / total)
+}
+
+func (b *Blend) SetTransform(m datatypes.Matrix) {
+	b.Transform = m
+}
+
+func (b *Blend) GetTransform() datatypes.Matrix {
+	return b.Transform
+}
+
+// Mask shows A where Mask is black and B where it is white, grey mixes them
+// by its luminance
+type Mask struct {
+	A, B, Mask Pattern
+	Transform  datatypes.Matrix
+}
+
+func GetMask(a, b, mask Pattern) *Mask {
+	return &Mask{A: a, B: b, Mask: mask, Transform: datatypes.GetIdentity()}
+}
+
+func (m *Mask) At(point datatypes.Tuple) RGB {
+	t := nestedAt(m.Mask, point).Luminance()
+
+	// skip the pattern that doesn't show
+	if t <= 0 {
+		return nestedAt(m.A, point)
+	} else if t >= 1 {
+		return nestedAt(m.B, point)
+	}
+
+	return blendAt(m.A, m.B, point, t)
+}
+
+func (m *Mask) SetTransform(mat datatypes.Matrix) {
+	m.Transform = mat
+}
+
+func (m *Mask) GetTransform() datatypes.Matrix {
+	return m.Transform
+}
+
 type TestPat struct {
 	Transform datatypes.Matrix
 }
@@ -10,10 +10,10 @@ func TestPatterns(t *testing.T) {
 	white := RGB{Red: 1, Green: 1, Blue: 1}
 
 	t.Run("Creating a striped pattern", func(t *testing.T) {
-		stripe := Stripe{A: white, B: black}
+		stripe := GetStripe(white, black).(*Stripe)
 
-		AssertColorsEqual(t, stripe.A, white)
-		AssertColorsEqual(t, stripe.B, black)
+		AssertColorsEqual(t, stripe.A.At(datatypes.Point(0, 0, 0)), white)
+		AssertColorsEqual(t, stripe.B.At(datatypes.Point(0, 0, 0)), black)
 
 	})
 
@@ -86,4 +86,70 @@ func TestPatterns(t *testing.T) {
 		datatypes.AssertMatrixEqual(t, pat.GetTransform(), datatypes.GetIdentity())
 	})
 
+	t.Run("Nested patterns are looked up through their own transforms", func(t *testing.T) {
+		inner := GetTestPat()
+		inner.SetTransform(datatypes.GetScaling(2, 2, 2))
+
+		pattern := GetStripeOf(inner, GetSolid(black))
+
+		AssertColorsEqual(t, pattern.At(datatypes.Point(0.5, 1, 0)), RGB{Red: 0.25, Green: 0.5, Blue: 0})
+		AssertColorsEqual(t, pattern.At(datatypes.Point(1.5, 1, 0)), black)
+	})
+
+	t.Run("Checkered stripes from existing patterns", func(t *testing.T) {
+		red := RGB{Red: 1, Green: 0, Blue: 0}
+		checkers := GetCheckers(white, black)
+		checkers.SetTransform(datatypes.GetScaling(0.25, 0.25, 0.25))
+
+		pattern := GetStripeOf(checkers, GetSolid(red))
+
+		AssertColorsEqual(t, pattern.At(datatypes.Point(0.1, 0, 0)), white)
+		AssertColorsEqual(t, pattern.At(datatypes.Point(0.3, 0, 0)), black)
+		AssertColorsEqual(t, pattern.At(datatypes.Point(1.1, 0, 0)), red)
+		AssertColorsEqual(t, pattern.At(datatypes.Point(1.3, 0, 0)), red)
+	})
+
+	t.Run("A gradient between patterns", func(t *testing.T) {
+		pattern := GetGradientOf(GetStripe(white, black), GetSolid(black))
+
+		AssertColorsEqual(t, pattern.At(datatypes.Point(0.25, 0, 0)), RGB{Red: 0.75, Green: 0.75, Blue: 0.75})
+		AssertColorsEqual(t, pattern.At(datatypes.Point(1.25, 0, 0)), black)
+	})
+
+	t.Run("A blend averages its patterns", func(t *testing.T) {
+		red := RGB{Red: 1, Green: 0, Blue: 0}
+		blue := RGB{Red: 0, Green: 0, Blue: 1}
+		pattern := GetBlend(GetSolid(red), GetSolid(blue), GetStripe(white, black))
+
+		AssertColorsEqual(t, pattern.At(datatypes.Point(0, 0, 0)), RGB{Red: 2.0 / 3, Green: 1.0 / 3, Blue: 2.0 / 3})
+		AssertColorsEqual(t, pattern.At(datatypes.Point(1, 0, 0)), RGB{Red: 1.0 / 3, Green: 0, Blue: 1.0 / 3})
+	})
+
+	t.Run("A lerp weights its two patterns", func(t *testing.T) {
+		pattern := GetLerp(GetSolid(white), GetSolid(black), 0.25)
+		AssertColorsEqual(t, pattern.At(datatypes.Point(0, 0, 0)), RGB{Red: 0.75, Green: 0.75, Blue: 0.75})
+
+		empty := GetBlend()
+		AssertColorsEqual(t, empty.At(datatypes.Point(0, 0, 0)), black)
+	})
+
+	t.Run("Patterns missing a weight count as 1", func(t *testing.T) {
+		pattern := GetBlend(GetSolid(white), GetSolid(black), GetSolid(black))
+		pattern.Weights = []float64{2}
+
+		AssertColorsEqual(t, pattern.At(datatypes.Point(0, 0, 0)), RGB{Red: 0.5, Green: 0.5, Blue: 0.5})
+	})
+
+	t.Run("A mask chooses between patterns by luminance", func(t *testing.T) {
+		red := RGB{Red: 1, Green: 0, Blue: 0}
+		blue := RGB{Red: 0, Green: 0, Blue: 1}
+		pattern := GetMask(GetSolid(red), GetSolid(blue), GetGradient(black, white))
+
+		AssertColorsEqual(t, pattern.At(datatypes.Point(0, 0, 0)), red)
+		AssertColorsEqual(t, pattern.At(datatypes.Point(0.5, 0, 0)), RGB{Red: 0.5, Green: 0, Blue: 0.5})
+
+		pattern.Mask = GetSolid(white)
+		AssertColorsEqual(t, pattern.At(datatypes.Point(0.5, 0, 0)), blue)
+	})
+
 }
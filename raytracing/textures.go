@@ -0,0 +1,235 @@
+package raytracing
+
+import (
+	"fmt"
+	"github.com/seantur/ray_tracer_challenge/datatypes"
+	"image"
+	"math"
+)
+
+// UVPattern is a pattern that can also be looked up by texture coordinates,
+// shapes with coordinates of their own use AtUV instead of At
+type UVPattern interface {
+	Pattern
+	AtUV(u, v float64) RGB
+}
+
+// UVMapping turns a point on a shape into texture coordinates, u across and v
+// up the texture, both from 0 to 1
+type UVMapping int
+
+const (
+	// PlanarMapping repeats the texture every unit along x and z
+	PlanarMapping UVMapping = iota
+	// SphericalMapping wraps the texture around the unit sphere like a globe
+	SphericalMapping
+	// CylindricalMapping wraps the texture around the y axis, repeating every
+	// unit up it
+	CylindricalMapping
+	// CubeMapping reads each face of the unit cube from a horizontal cross,
+	// with the up face above the front and the down face below it
+	//
+	//	   [up]
+	//	[left][front][right][back]
+	//	   [down]
+	CubeMapping
+)
+
+var uvMappingNames = map[string]UVMapping{
+	"planar":      PlanarMapping,
+	"spherical":   SphericalMapping,
+	"cylindrical": CylindricalMapping,
+	"cube":        CubeMapping,
+}
+
+// GetUVMapping looks up a mapping by the name used in scene files
+func GetUVMapping(name string) (UVMapping, error) {
+	m, ok := uvMappingNames[name]
+	if !ok {
+		return PlanarMapping, fmt.Errorf("unknown uv mapping %q", name)
+	}
+	return m, nil
+}
+
+// Map returns the texture coordinates of point
+func (m UVMapping) Map(point datatypes.Tuple) (u, v float64) {
+	switch m {
+	case SphericalMapping:
+		theta := math.Atan2(point.X, point.Z)
+		radius := math.Sqrt(point.X*point.X + point.Y*point.Y + point.Z*point.Z)
+		if radius == 0 {
+			return 0.5, 0.5
+		}
+		phi := math.Acos(point.Y / radius)
+
+		return 1 - (theta/(2*math.Pi) + 0.5), 1 - phi/math.Pi
+	case CylindricalMapping:
+		theta := math.Atan2(point.X, point.Z)
+		return 1 - (theta/(2*math.Pi) + 0.5), fract(point.Y)
+	case CubeMapping:
+		return cubeMap(point)
+	}
+
+	return fract(point.X), fract(point.Z)
+}
+
+// fract is the fractional part of x, always from 0 to 1
+func fract(x float64) float64 {
+	return x - math.Floor(x)
+}
+
+// cubeMap finds the face the point is on by its largest coordinate, maps the
+// point on that face then places the face in the cross
+func cubeMap(p datatypes.Tuple) (u, v float64) {
+	half := func(x float64) float64 {
+		return math.Mod(x+1, 2) / 2
+	}
+
+	var col, row float64
+	x, y, z := math.Abs(p.X), math.Abs(p.Y), math.Abs(p.Z)
+
+	switch {
+	case x >= y && x >= z && p.X > 0:
+		col, row, u, v = 2, 1, half(-p.Z), half(p.Y)
+	case x >= y && x >= z:
+		col, row, u, v = 0, 1, half(p.Z), half(p.Y)
+	case y >= z && p.Y > 0:
+		col, row, u, v = 1, 2, half(p.X), half(-p.Z)
+	case y >= z:
+		col, row, u, v = 1, 0, half(p.X), half(p.Z)
+	case p.Z > 0:
+		col, row, u, v = 1, 1, half(p.X), half(p.Y)
+	default:
+		col, row, u, v = 3, 1, half(-p.X), half(p.Y)
+	}
+
+	return (col + u) / 4, (row + v) / 3
+}
+
+// TextureWrap is how texture coordinates outside 0 to 1 are read
+type TextureWrap int
+
+const (
+	// Repeat tiles the texture
+	Repeat TextureWrap = iota
+	// Clamp stretches the edge pixels outward
+	Clamp
+	// Mirror tiles the texture, flipping every other copy so edges meet
+	Mirror
+)
+
+var textureWrapNames = map[string]TextureWrap{
+	"repeat": Repeat,
+	"clamp":  Clamp,
+	"mirror": Mirror,
+}
+
+// GetTextureWrap looks up a wrap mode by the name used in scene files
+func GetTextureWrap(name string) (TextureWrap, error) {
+	w, ok := textureWrapNames[name]
+	if !ok {
+		return Repeat, fmt.Errorf("unknown texture wrap %q", name)
+	}
+	return w, nil
+}
+
+// address maps pixel index i into [0, n)
+func (w TextureWrap) address(i, n int) int {
+	switch w {
+	case Clamp:
+		if i < 0 {
+			return 0
+		} else if i >= n {
+			return n - 1
+		}
+		return i
+	case Mirror:
+		i = ((i % (2 * n)) + 2*n) % (2 * n)
+		if i >= n {
+			return 2*n - 1 - i
+		}
+		return i
+	}
+
+	return ((i % n) + n) % n
+}
+
+// ImageTexture colors a shape from an image, using Mapping to find the point
+// in the image unless the shape has texture coordinates of its own. Pixels
+// are blended bilinearly unless Nearest is set.
+type ImageTexture struct {
+	Width, Height int
+	Pixels        []RGB // linear colors, row by row from the top
+	Mapping       UVMapping
+	Wrap          TextureWrap
+	Nearest       bool
+	Transform     datatypes.Matrix
+}
+
+// GetImageTexture copies the pixels of im. Colors that are already RGBs, as
+// decoded from an HDR file, are kept as they are, anything else is taken to
+// be sRGB and converted to linear.
+func GetImageTexture(im image.Image) *ImageTexture {
+	b := im.Bounds()
+	t := ImageTexture{Width: b.Dx(), Height: b.Dy(), Transform: datatypes.GetIdentity()}
+	t.Pixels = make([]RGB, 0, t.Width*t.Height)
+
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := im.At(x, y)
+
+			if rgb, ok := c.(RGB); ok {
+				t.Pixels = append(t.Pixels, rgb)
+				continue
+			}
+
+			r, g, b, _ := c.RGBA()
+			t.Pixels = append(t.Pixels, RGB{
+				SRGBToLinear(float64(r) / 0xFFFF),
+				SRGBToLinear(float64(g) / 0xFFFF),
+				SRGBToLinear(float64(b) / 0xFFFF)})
+		}
+	}
+
+	return &t
+}
+
+func (t *ImageTexture) At(point datatypes.Tuple) RGB {
+	return t.AtUV(t.Mapping.Map(point))
+}
+
+// AtUV reads the texture at (u, v), v = 0 is the bottom of the image
+func (t *ImageTexture) AtUV(u, v float64) RGB {
+	if t.Width == 0 || t.Height == 0 {
+		return RGB{}
+	}
+
+	if t.Nearest {
+		return t.pixel(int(math.Floor(u*float64(t.Width))), int(math.Floor((1-v)*float64(t.Height))))
+	}
+
+	// pixel centers are at half coordinates
+	x := u*float64(t.Width) - 0.5
+	y := (1-v)*float64(t.Height) - 0.5
+
+	x0, y0 := math.Floor(x), math.Floor(y)
+	fx, fy := x-x0, y-y0
+	ix, iy := int(x0), int(y0)
+
+	top := blend(t.pixel(ix, iy), t.pixel(ix+1, iy), fx)
+	bottom := blend(t.pixel(ix, iy+1), t.pixel(ix+1, iy+1), fx)
+
+	return blend(top, bottom, fy)
+}
+
+func (t *ImageTexture) pixel(x, y int) RGB {
+	return t.Pixels[t.Wrap.address(y, t.Height)*t.Width+t.Wrap.address(x, t.Width)]
+}
+
+func (t *ImageTexture) SetTransform(m datatypes.Matrix) {
+	t.Transform = m
+}
+
+func (t *ImageTexture) GetTransform() datatypes.Matrix {
+	return t.Transform
+}
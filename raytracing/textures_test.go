@@ -0,0 +1,132 @@
+package raytracing
+
+import (
+	"github.com/seantur/ray_tracer_challenge/datatypes"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestTextures(t *testing.T) {
+	black := RGB{Red: 0, Green: 0, Blue: 0}
+	white := RGB{Red: 1, Green: 1, Blue: 1}
+	red := RGB{Red: 1, Green: 0, Blue: 0}
+	green := RGB{Red: 0, Green: 1, Blue: 0}
+
+	// checker2 is a 2x2 texture, red and green on the top row
+	checker2 := func() *ImageTexture {
+		return &ImageTexture{Width: 2, Height: 2, Pixels: []RGB{red, green, black, white}, Transform: datatypes.GetIdentity()}
+	}
+
+	assertUV := func(t *testing.T, m UVMapping, p datatypes.Tuple, u, v float64) {
+		t.Helper()
+		gotU, gotV := m.Map(p)
+		if !datatypes.IsClose(gotU, u) || !datatypes.IsClose(gotV, v) {
+			t.Errorf("got (%f, %f) want (%f, %f)", gotU, gotV, u, v)
+		}
+	}
+
+	t.Run("Mappings are looked up by name", func(t *testing.T) {
+		m, err := GetUVMapping("cylindrical")
+		if err != nil || m != CylindricalMapping {
+			t.Errorf("got %v, %v", m, err)
+		}
+
+		w, err := GetTextureWrap("mirror")
+		if err != nil || w != Mirror {
+			t.Errorf("got %v, %v", w, err)
+		}
+
+		if _, err := GetUVMapping("toroidal"); err == nil {
+			t.Error("expected an error for an unknown mapping")
+		}
+	})
+
+	t.Run("A planar mapping repeats every unit in x and z", func(t *testing.T) {
+		assertUV(t, PlanarMapping, datatypes.Point(0.25, 0, 0.5), 0.25, 0.5)
+		assertUV(t, PlanarMapping, datatypes.Point(1.25, 7, -0.25), 0.25, 0.75)
+	})
+
+	t.Run("A spherical mapping wraps the texture around the sphere", func(t *testing.T) {
+		assertUV(t, SphericalMapping, datatypes.Point(0, 0, -1), 0, 0.5)
+		assertUV(t, SphericalMapping, datatypes.Point(1, 0, 0), 0.25, 0.5)
+		assertUV(t, SphericalMapping, datatypes.Point(0, 0, 1), 0.5, 0.5)
+		assertUV(t, SphericalMapping, datatypes.Point(0, 1, 0), 0.5, 1)
+		assertUV(t, SphericalMapping, datatypes.Point(math.Sqrt2/2, math.Sqrt2/2, 0), 0.25, 0.75)
+	})
+
+	t.Run("A cylindrical mapping repeats every unit in y", func(t *testing.T) {
+		assertUV(t, CylindricalMapping, datatypes.Point(0, 0, -1), 0, 0)
+		assertUV(t, CylindricalMapping, datatypes.Point(1, 0.5, 0), 0.25, 0.5)
+		assertUV(t, CylindricalMapping, datatypes.Point(0, 1.25, 1), 0.5, 0.25)
+	})
+
+	t.Run("A cube mapping places each face in the cross", func(t *testing.T) {
+		assertUV(t, CubeMapping, datatypes.Point(0, 0, 1), 1.5/4, 1.5/3)
+		assertUV(t, CubeMapping, datatypes.Point(1, 0, 0), 2.5/4, 1.5/3)
+		assertUV(t, CubeMapping, datatypes.Point(-1, 0, 0), 0.5/4, 1.5/3)
+		assertUV(t, CubeMapping, datatypes.Point(0, 0, -1), 3.5/4, 1.5/3)
+		assertUV(t, CubeMapping, datatypes.Point(0, 1, 0), 1.5/4, 2.5/3)
+		assertUV(t, CubeMapping, datatypes.Point(0, -1, 0), 1.5/4, 0.5/3)
+		assertUV(t, CubeMapping, datatypes.Point(-0.5, 0.5, 1), 1.25/4, 1.75/3)
+	})
+
+	t.Run("Nearest filtering reads whole pixels with v up the image", func(t *testing.T) {
+		tex := checker2()
+		tex.Nearest = true
+
+		AssertColorsEqual(t, tex.AtUV(0.1, 0.9), red)
+		AssertColorsEqual(t, tex.AtUV(0.9, 0.9), green)
+		AssertColorsEqual(t, tex.AtUV(0.1, 0.1), black)
+		AssertColorsEqual(t, tex.AtUV(0.9, 0.1), white)
+	})
+
+	t.Run("Bilinear filtering blends the four nearest pixels", func(t *testing.T) {
+		tex := checker2()
+		tex.Wrap = Clamp
+
+		AssertColorsEqual(t, tex.AtUV(0.25, 0.75), red)
+		AssertColorsEqual(t, tex.AtUV(0.5, 0.75), RGB{Red: 0.5, Green: 0.5, Blue: 0})
+		AssertColorsEqual(t, tex.AtUV(0.5, 0.5), RGB{Red: 0.5, Green: 0.5, Blue: 0.25})
+	})
+
+	t.Run("Wrap modes address pixels outside the texture", func(t *testing.T) {
+		for _, c := range []struct {
+			wrap TextureWrap
+			want []int
+		}{
+			{Repeat, []int{0, 1, 2, 0, 1, 2, 0}},
+			{Clamp, []int{0, 0, 0, 0, 1, 2, 2}},
+			{Mirror, []int{2, 1, 0, 0, 1, 2, 2}},
+		} {
+			for i, want := range c.want {
+				if got := c.wrap.address(i-3, 3); got != want {
+					t.Errorf("wrap %v: address(%d) got %d want %d", c.wrap, i-3, got, want)
+				}
+			}
+		}
+	})
+
+	t.Run("Textures are read through their mapping and transform", func(t *testing.T) {
+		tex := checker2()
+		tex.Nearest = true
+
+		AssertColorsEqual(t, tex.At(datatypes.Point(0.25, 0, 0.75)), red)
+		AssertColorsEqual(t, tex.At(datatypes.Point(1.75, 0, 0.25)), white)
+	})
+
+	t.Run("8-bit images are converted from sRGB to linear", func(t *testing.T) {
+		im := image.NewRGBA(image.Rect(0, 0, 2, 1))
+		im.Set(0, 0, color.RGBA{R: 255, G: 188, B: 0, A: 255})
+		im.Set(1, 0, color.RGBA{A: 255})
+
+		tex := GetImageTexture(im)
+
+		if tex.Width != 2 || tex.Height != 1 {
+			t.Fatalf("got size %dx%d", tex.Width, tex.Height)
+		}
+		AssertColorsEqual(t, tex.Pixels[0], RGB{Red: 1, Green: SRGBToLinear(188.0 / 255), Blue: 0})
+		AssertColorsEqual(t, tex.Pixels[1], black)
+	})
+}
@@ -0,0 +1,107 @@
+package scene
+
+import (
+	"context"
+	"github.com/seantur/ray_tracer_challenge/raytracing"
+	"image"
+	"math"
+	"sync"
+)
+
+// cornerGrid holds the colors seen through every pixel corner of a block of
+// pixels, each corner is shared by up to four pixels so it is only traced once
+type cornerGrid struct {
+	origin image.Point
+	width  int
+	colors []raytracing.RGB
+}
+
+func (g *cornerGrid) at(x, y int) raytracing.RGB {
+	return g.colors[(y-g.origin.Y)*g.width+x-g.origin.X]
+}
+
+// traceCorners traces the corners of the pixels in bounds, a row at a time
+func (c *camera) traceCorners(ctx context.Context, w World, workers int, bounds image.Rectangle) (*cornerGrid, error) {
+	g := cornerGrid{
+		origin: bounds.Min,
+		width:  bounds.Dx() + 1,
+		colors: make([]raytracing.RGB, (bounds.Dx()+1)*(bounds.Dy()+1))}
+
+	rows := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for y := range rows {
+				for x := bounds.Min.X; x <= bounds.Max.X; x++ {
+					g.colors[(y-bounds.Min.Y)*g.width+x-bounds.Min.X] = c.colorThrough(w, x, y, 0, 0)
+				}
+			}
+		}()
+	}
+
+	var err error
+
+rows:
+	for y := bounds.Min.Y; y <= bounds.Max.Y; y++ {
+		select {
+		case rows <- y:
+		case <-ctx.Done():
+			err = ctx.Err()
+			break rows
+		}
+	}
+
+	close(rows)
+	wg.Wait()
+
+	return &g, err
+}
+
+// contrast is the largest difference between the colors in any one channel
+func contrast(colors ...raytracing.RGB) float64 {
+	lo := raytracing.RGB{Red: math.Inf(1), Green: math.Inf(1), Blue: math.Inf(1)}
+	hi := raytracing.RGB{Red: math.Inf(-1), Green: math.Inf(-1), Blue: math.Inf(-1)}
+
+	for _, c := range colors {
+		lo = raytracing.RGB{Red: math.Min(lo.Red, c.Red), Green: math.Min(lo.Green, c.Green), Blue: math.Min(lo.Blue, c.Blue)}
+		hi = raytracing.RGB{Red: math.Max(hi.Red, c.Red), Green: math.Max(hi.Green, c.Green), Blue: math.Max(hi.Blue, c.Blue)}
+	}
+
+	return math.Max(math.Max(hi.Red-lo.Red, hi.Green-lo.Green), hi.Blue-lo.Blue)
+}
+
+// adaptivePixel colors pixel (x, y) from its corners, subdividing where they
+// disagree, and returns the number of rays the pixel used
+func (c *camera) adaptivePixel(w World, g *cornerGrid, x, y int) (raytracing.RGB, int) {
+	color, rays := c.subdivide(w, x, y, 0, 0, 1, g.at(x, y), g.at(x+1, y), g.at(x, y+1), g.at(x+1, y+1), 0)
+	return color, rays + 4
+}
+
+// subdivide averages the square at offset (dx, dy) with the given side inside
+// pixel (x, y), tl/tr/bl/br are the colors at its corners
+func (c *camera) subdivide(w World, x, y int, dx, dy, size float64, tl, tr, bl, br raytracing.RGB, depth int) (raytracing.RGB, int) {
+	if depth >= c.AdaptiveDepth || contrast(tl, tr, bl, br) <= c.AdaptiveThreshold {
+		sum := raytracing.Add(raytracing.Add(tl, tr), raytracing.Add(bl, br))
+		return sum.Multiply(0.25), 0
+	}
+
+	half := size / 2
+	sample := func(ox, oy float64) raytracing.RGB {
+		return c.colorThrough(w, x, y, dx+ox, dy+oy)
+	}
+
+	top, left, center := sample(half, 0), sample(0, half), sample(half, half)
+	right, bottom := sample(size, half), sample(half, size)
+
+	c1, n1 := c.subdivide(w, x, y, dx, dy, half, tl, top, left, center, depth+1)
+	c2, n2 := c.subdivide(w, x, y, dx+half, dy, half, top, tr, center, right, depth+1)
+	c3, n3 := c.subdivide(w, x, y, dx, dy+half, half, left, center, bl, bottom, depth+1)
+	c4, n4 := c.subdivide(w, x, y, dx+half, dy+half, half, center, right, bottom, br, depth+1)
+
+	sum := raytracing.Add(raytracing.Add(c1, c2), raytracing.Add(c3, c4))
+
+	return sum.Multiply(0.25), 5 + n1 + n2 + n3 + n4
+}
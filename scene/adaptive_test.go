@@ -0,0 +1,95 @@
+package scene
+
+import (
+	"context"
+	"github.com/seantur/ray_tracer_challenge/datatypes"
+	"github.com/seantur/ray_tracer_challenge/raytracing"
+	"image"
+	"math"
+	"testing"
+)
+
+// pixelColorAt reads back a rendered pixel as an RGB
+func pixelColorAt(im image.Image, x, y int) raytracing.RGB {
+	r, g, b, a := im.At(x, y).RGBA()
+	return raytracing.RGB{Red: float64(r) / float64(a), Green: float64(g) / float64(a), Blue: float64(b) / float64(a)}
+}
+
+func TestAdaptive(t *testing.T) {
+
+	t.Run("Contrast is the largest difference in any channel", func(t *testing.T) {
+		datatypes.AssertVal(t, contrast(raytracing.RGB{Red: 0.5}, raytracing.RGB{Red: 0.5}), 0)
+		datatypes.AssertVal(t, contrast(
+			raytracing.RGB{Red: 0.1, Green: 0.2, Blue: 0.9},
+			raytracing.RGB{Red: 0.3, Green: 0.9, Blue: 0.8},
+			raytracing.RGB{Red: 0.2, Green: 0.5, Blue: 0.85}), 0.7)
+	})
+
+	t.Run("Flat regions only trace the pixel corners", func(t *testing.T) {
+		c := GetCamera(4, 4, math.Pi/4)
+		c.Adaptive = true
+
+		_, counts, _ := c.RenderContext(context.Background(), World{})
+
+		for _, v := range counts.Pix {
+			datatypes.AssertVal(t, float64(v), 255)
+		}
+	})
+
+	t.Run("Pixels on an edge are subdivided up to the maximum depth", func(t *testing.T) {
+		w := GetWorld()
+		c := GetCamera(11, 11, math.Pi/2)
+		c.Transform = datatypes.ViewTransform(datatypes.Point(0, 0, -5), datatypes.Point(0, 0, 0), datatypes.Vector(0, 1, 0))
+		c.Adaptive = true
+
+		g, _ := c.traceCorners(context.Background(), w, 2, image.Rect(0, 0, 11, 11))
+
+		// the sphere's silhouette crosses pixel (4, 4) of this view
+		c.AdaptiveDepth = 0
+		_, none := c.adaptivePixel(w, g, 4, 4)
+		datatypes.AssertVal(t, float64(none), 4)
+
+		c.AdaptiveDepth = 1
+		_, once := c.adaptivePixel(w, g, 4, 4)
+		datatypes.AssertVal(t, float64(once), 4+5)
+
+		c.AdaptiveDepth = 2
+		_, twice := c.adaptivePixel(w, g, 4, 4)
+		if twice <= once || twice > 4+5+4*5 {
+			t.Errorf("expected between %d and %d rays, got %d", once+1, 4+5+4*5, twice)
+		}
+
+		_, flat := c.adaptivePixel(w, g, 0, 0)
+		datatypes.AssertVal(t, float64(flat), 4)
+	})
+
+	t.Run("Adaptive rendering is the same sequentially and concurrently", func(t *testing.T) {
+		w := GetWorld()
+		c := GetCamera(11, 11, math.Pi/2)
+		c.Transform = datatypes.ViewTransform(datatypes.Point(0, 0, -5), datatypes.Point(0, 0, 0), datatypes.Vector(0, 1, 0))
+		c.Adaptive = true
+		c.Workers = 3
+
+		a, b := c.Render(w), c.RenderConcurrent(w)
+
+		for y := 0; y < c.Vsize; y++ {
+			for x := 0; x < c.Hsize; x++ {
+				raytracing.AssertColorsEqual(t, pixelColorAt(a, x, y), pixelColorAt(b, x, y))
+			}
+		}
+	})
+
+	t.Run("The center of a flat pixel is the average of its corners", func(t *testing.T) {
+		w := GetWorld()
+		c := GetCamera(11, 11, math.Pi/2)
+		c.Transform = datatypes.ViewTransform(datatypes.Point(0, 0, -5), datatypes.Point(0, 0, 0), datatypes.Vector(0, 1, 0))
+		c.Adaptive = true
+		c.AdaptiveDepth = 0
+
+		g, _ := c.traceCorners(context.Background(), w, 1, image.Rect(2, 3, 9, 8))
+		color, _ := c.adaptivePixel(w, g, 5, 5)
+
+		want := raytracing.Add(raytracing.Add(g.at(5, 5), g.at(6, 5)), raytracing.Add(g.at(5, 6), g.at(6, 6)))
+		raytracing.AssertColorsEqual(t, color, want.Multiply(0.25))
+	})
+}
@@ -0,0 +1,97 @@
+package scene
+
+import (
+	"github.com/seantur/ray_tracer_challenge/datatypes"
+	"github.com/seantur/ray_tracer_challenge/raytracing"
+	"math"
+)
+
+// Background is what a ray sees when it misses every shape, looked up by the
+// ray's unit direction. A World without one is black.
+type Background interface {
+	ColorAt(direction datatypes.Tuple) raytracing.RGB
+}
+
+// ConstantBackground is the same color in every direction
+type ConstantBackground struct {
+	Color raytracing.RGB
+}
+
+func (b ConstantBackground) ColorAt(direction datatypes.Tuple) raytracing.RGB {
+	return b.Color
+}
+
+// GradientBackground blends from Bottom straight down to Top straight up
+type GradientBackground struct {
+	Bottom, Top raytracing.RGB
+}
+
+func (b GradientBackground) ColorAt(direction datatypes.Tuple) raytracing.RGB {
+	t := (math.Max(-1, math.Min(1, direction.Y)) + 1) / 2
+
+	bottom, top := b.Bottom, b.Top
+	return raytracing.Add(bottom.Multiply(1-t), top.Multiply(t))
+}
+
+// EquirectBackground wraps a latitude-longitude image around the scene, the
+// center of the image is straight along -z and its top is straight up
+type EquirectBackground struct {
+	Texture *raytracing.ImageTexture
+}
+
+func (b EquirectBackground) ColorAt(direction datatypes.Tuple) raytracing.RGB {
+	u := 0.5 + math.Atan2(direction.X, -direction.Z)/(2*math.Pi)
+	v := 0.5 + math.Asin(math.Max(-1, math.Min(1, direction.Y)))/math.Pi
+
+	// keep bilinear filtering from blending one pole into the other
+	half := 0.5 / float64(b.Texture.Height)
+	v = math.Max(half, math.Min(1-half, v))
+
+	return b.Texture.AtUV(u, v)
+}
+
+// Cube map faces, in the usual +x, -x, +y, -y, +z, -z order
+const (
+	CubePositiveX = iota
+	CubeNegativeX
+	CubePositiveY
+	CubeNegativeY
+	CubePositiveZ
+	CubeNegativeZ
+)
+
+// CubeMapBackground surrounds the scene with six images, each seen from the
+// inside of the cube. Looking along +z, +x is to the right and +y is up.
+type CubeMapBackground struct {
+	Faces [6]*raytracing.ImageTexture
+}
+
+func (b CubeMapBackground) ColorAt(direction datatypes.Tuple) raytracing.RGB {
+	x, y, z := direction.X, direction.Y, direction.Z
+	ax, ay, az := math.Abs(x), math.Abs(y), math.Abs(z)
+
+	// sc and tc run across and down the face, major is the axis it faces
+	var face int
+	var sc, tc, major float64
+
+	switch {
+	case ax >= ay && ax >= az && x > 0:
+		face, sc, tc, major = CubePositiveX, -z, -y, ax
+	case ax >= ay && ax >= az:
+		face, sc, tc, major = CubeNegativeX, z, -y, ax
+	case ay >= az && y > 0:
+		face, sc, tc, major = CubePositiveY, x, z, ay
+	case ay >= az:
+		face, sc, tc, major = CubeNegativeY, x, -z, ay
+	case z > 0:
+		face, sc, tc, major = CubePositiveZ, x, -y, az
+	default:
+		face, sc, tc, major = CubeNegativeZ, -x, -y, az
+	}
+
+	if major == 0 || b.Faces[face] == nil {
+		return raytracing.RGB{}
+	}
+
+	return b.Faces[face].AtUV((sc/major+1)/2, 1-(tc/major+1)/2)
+}
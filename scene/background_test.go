@@ -0,0 +1,103 @@
+package scene
+
+import (
+	"github.com/seantur/ray_tracer_challenge/datatypes"
+	"github.com/seantur/ray_tracer_challenge/raytracing"
+	"math"
+	"testing"
+)
+
+func TestBackground(t *testing.T) {
+
+	red := raytracing.RGB{Red: 1, Green: 0, Blue: 0}
+	blue := raytracing.RGB{Red: 0, Green: 0, Blue: 1}
+
+	// solid is a one pixel texture of color c
+	solid := func(c raytracing.RGB) *raytracing.ImageTexture {
+		return &raytracing.ImageTexture{Width: 1, Height: 1, Pixels: []raytracing.RGB{c}, Transform: datatypes.GetIdentity()}
+	}
+
+	t.Run("A constant background is the same everywhere", func(t *testing.T) {
+		b := ConstantBackground{Color: red}
+
+		raytracing.AssertColorsEqual(t, b.ColorAt(datatypes.Vector(0, 1, 0)), red)
+		raytracing.AssertColorsEqual(t, b.ColorAt(datatypes.Vector(1, 0, 0)), red)
+	})
+
+	t.Run("A gradient blends from the bottom to the top", func(t *testing.T) {
+		b := GradientBackground{Bottom: red, Top: blue}
+
+		raytracing.AssertColorsEqual(t, b.ColorAt(datatypes.Vector(0, -1, 0)), red)
+		raytracing.AssertColorsEqual(t, b.ColorAt(datatypes.Vector(0, 1, 0)), blue)
+		raytracing.AssertColorsEqual(t, b.ColorAt(datatypes.Vector(0, 0, 1)), raytracing.RGB{Red: 0.5, Green: 0, Blue: 0.5})
+	})
+
+	t.Run("An equirectangular map is centered on -z with the top up", func(t *testing.T) {
+		// four columns, top half red and bottom half blue in the second column
+		tex := &raytracing.ImageTexture{Width: 4, Height: 2, Nearest: true, Transform: datatypes.GetIdentity(),
+			Pixels: []raytracing.RGB{{}, red, {}, {}, {}, blue, {}, {}}}
+		b := EquirectBackground{Texture: tex}
+
+		// just left of -z is the second column
+		up := datatypes.Vector(-0.1, 0.5, -1)
+		up = up.Normalize()
+		down := datatypes.Vector(-0.1, -0.5, -1)
+		down = down.Normalize()
+
+		raytracing.AssertColorsEqual(t, b.ColorAt(up), red)
+		raytracing.AssertColorsEqual(t, b.ColorAt(down), blue)
+		raytracing.AssertColorsEqual(t, b.ColorAt(datatypes.Vector(1, 0, 0)), raytracing.RGB{})
+	})
+
+	t.Run("Poles don't bleed into each other", func(t *testing.T) {
+		tex := &raytracing.ImageTexture{Width: 1, Height: 2, Transform: datatypes.GetIdentity(), Pixels: []raytracing.RGB{blue, red}}
+		b := EquirectBackground{Texture: tex}
+
+		raytracing.AssertColorsEqual(t, b.ColorAt(datatypes.Vector(0, 1, 0)), blue)
+		raytracing.AssertColorsEqual(t, b.ColorAt(datatypes.Vector(0, -1, 0)), red)
+	})
+
+	t.Run("A cube map reads the face a direction points at", func(t *testing.T) {
+		var b CubeMapBackground
+		colors := []raytracing.RGB{
+			{Red: 1}, {Red: 0.5}, {Green: 1}, {Green: 0.5}, {Blue: 1}, {Blue: 0.5},
+		}
+		for i, c := range colors {
+			b.Faces[i] = solid(c)
+		}
+
+		for i, d := range []datatypes.Tuple{
+			datatypes.Vector(1, 0.2, -0.3),
+			datatypes.Vector(-1, 0.2, 0.3),
+			datatypes.Vector(0.3, 1, 0.2),
+			datatypes.Vector(0.3, -1, 0.2),
+			datatypes.Vector(0.2, -0.3, 1),
+			datatypes.Vector(0.2, 0.3, -1),
+		} {
+			raytracing.AssertColorsEqual(t, b.ColorAt(d), colors[i])
+		}
+	})
+
+	t.Run("Cube map faces are seen from the inside", func(t *testing.T) {
+		// the +z face is red on the left and blue on the right, red on the top row
+		face := &raytracing.ImageTexture{Width: 2, Height: 2, Nearest: true, Wrap: raytracing.Clamp, Transform: datatypes.GetIdentity(),
+			Pixels: []raytracing.RGB{red, blue, red, red}}
+		b := CubeMapBackground{}
+		b.Faces[CubePositiveZ] = face
+
+		raytracing.AssertColorsEqual(t, b.ColorAt(datatypes.Vector(-0.5, -0.5, 1)), red)
+		raytracing.AssertColorsEqual(t, b.ColorAt(datatypes.Vector(0.5, 0.5, 1)), blue)
+		raytracing.AssertColorsEqual(t, b.ColorAt(datatypes.Vector(0.5, -0.5, 1)), red)
+
+		// faces that aren't set are black
+		raytracing.AssertColorsEqual(t, b.ColorAt(datatypes.Vector(0, 0, -1)), raytracing.RGB{})
+	})
+
+	t.Run("Directions are normalized before lookup", func(t *testing.T) {
+		w := World{Background: GradientBackground{Bottom: red, Top: blue}}
+
+		raytracing.AssertColorsEqual(t, w.BackgroundAt(datatypes.Vector(0, 0, 7)), raytracing.RGB{Red: 0.5, Green: 0, Blue: 0.5})
+		raytracing.AssertColorsEqual(t, w.BackgroundAt(datatypes.Vector(0, 0.5*math.Sqrt(2), 0.5*math.Sqrt(2))),
+			raytracing.RGB{Red: 0.5 - math.Sqrt(2)/4, Green: 0, Blue: 0.5 + math.Sqrt(2)/4})
+	})
+}
@@ -1,22 +1,80 @@
 package scene
 
 import (
-	"fmt"
+	"context"
 	"github.com/seantur/ray_tracer_challenge/datatypes"
+	"github.com/seantur/ray_tracer_challenge/raytracing"
 	"image"
 	"math"
+	"math/rand"
 	"runtime"
-	"sync"
 )
 
+// DefaultDepth is how many reflected/refracted bounces a ray may take
+const DefaultDepth = 5
+
 type camera struct {
 	Hsize, Vsize                          int
 	Fov, PixelSize, HalfWidth, HalfHeight float64
 	Transform                             datatypes.Matrix
+	Depth                                 int // recursion limit passed to ColorAt
+	Workers                               int // goroutines used by RenderConcurrent
+
+	// SamplesPerPixel is rounded up to a square number so the samples can be
+	// stratified over a grid covering the filter, Jitter randomizes each
+	// sample within its cell instead of using the cell's center
+	SamplesPerPixel int
+	Jitter          bool
+	Filter          Filter // nil is a BoxFilter
+
+	// Adaptive replaces uniform supersampling: rays are traced through pixel
+	// corners and a pixel is split into quarters while its corners differ by
+	// more than AdaptiveThreshold in any channel, at most AdaptiveDepth times
+	Adaptive          bool
+	AdaptiveThreshold float64
+	AdaptiveDepth     int
+
+	// A non-zero Aperture (the lens radius) turns the pinhole into a thin
+	// lens focused FocalDistance in front of the camera. Blades of 3 or more
+	// makes the lens a regular polygon instead of a disk, shaping the bokeh.
+	// Defocus is noisy so raise SamplesPerPixel along with the aperture.
+	Aperture      float64
+	FocalDistance float64
+	Blades        int
+
+	// Projection defaults to Perspective, the thin lens only applies to it.
+	// OrthoWidth is the width in world units of an Orthographic view.
+	Projection Projection
+	OrthoWidth float64
+
+	// Images are rendered in TileSize square tiles visited in TileOrder.
+	// Progress, if set, is called from the render goroutines, one at a time,
+	// as each tile completes.
+	TileSize  int
+	TileOrder TileOrder
+	Progress  func(RenderProgress)
 }
 
 func GetCamera(hsize, vsize int, fov float64) camera {
-	c := camera{Hsize: hsize, Vsize: vsize, Fov: fov, Transform: datatypes.GetIdentity()}
+	c := camera{
+		Transform:         datatypes.GetIdentity(),
+		Depth:             DefaultDepth,
+		Workers:           runtime.NumCPU() * 4,
+		SamplesPerPixel:   1,
+		AdaptiveThreshold: 0.1,
+		AdaptiveDepth:     2,
+		FocalDistance:     1,
+		OrthoWidth:        2,
+		TileSize:          DefaultTileSize}
+
+	c.SetView(hsize, vsize, fov)
+
+	return c
+}
+
+// SetView changes the image size and field of view, keeping every other setting
+func (c *camera) SetView(hsize, vsize int, fov float64) {
+	c.Hsize, c.Vsize, c.Fov = hsize, vsize, fov
 
 	half_view := math.Tan(fov / 2)
 	aspect_ratio := float64(hsize) / float64(vsize)
@@ -30,79 +88,210 @@ func GetCamera(hsize, vsize int, fov float64) camera {
 	}
 
 	c.PixelSize = (c.HalfWidth * 2) / float64(c.Hsize)
-
-	return c
 }
 
 func (c *camera) RayForPixel(px, py int) datatypes.Ray {
-	xoffset := (float64(px) + 0.5) * c.PixelSize
-	yoffset := (float64(py) + 0.5) * c.PixelSize
+	return c.RayForPixelOffset(px, py, 0.5, 0.5)
+}
 
-	world_x := c.HalfWidth - xoffset
-	world_y := c.HalfHeight - yoffset
+// RayForPixelOffset shoots a ray through (dx, dy) measured in pixels from the
+// top left corner of pixel (px, py). Outside a fisheye's image circle there is
+// no ray and the zero Ray is returned.
+func (c *camera) RayForPixelOffset(px, py int, dx, dy float64) datatypes.Ray {
+	r, _ := c.cameraRay(px, py, dx, dy)
+	return r
+}
 
-	transform_inv, _ := c.Transform.Inverse()
+// cameraRay is RayForPixelOffset, reporting whether the camera sees anything
+// through that point of the image
+func (c *camera) cameraRay(px, py int, dx, dy float64) (datatypes.Ray, bool) {
+	u, v := float64(px)+dx, float64(py)+dy
 
-	pixel := datatypes.TupleMultiply(transform_inv, datatypes.Point(world_x, world_y, -1))
-	origin := datatypes.TupleMultiply(transform_inv, datatypes.Point(0, 0, 0))
+	world_x := c.HalfWidth - u*c.PixelSize
+	world_y := c.HalfHeight - v*c.PixelSize
 
-	direction := datatypes.Subtract(pixel, origin)
+	var origin, direction datatypes.Tuple
+	origin = datatypes.Point(0, 0, 0)
+
+	switch c.Projection {
+	case Orthographic:
+		origin, direction = c.orthographicRay(world_x, world_y)
+	case Fisheye:
+		var ok bool
+		if direction, ok = c.fisheyeDirection(u, v); !ok {
+			return datatypes.Ray{}, false
+		}
+	case Equirectangular:
+		direction = c.equirectangularDirection(u, v)
+	default:
+		pixel := datatypes.Point(world_x, world_y, -1)
+
+		if c.Aperture > 0 {
+			// every ray through the lens converges where the pinhole ray
+			// meets the focal plane
+			pixel = datatypes.Point(world_x*c.FocalDistance, world_y*c.FocalDistance, -c.FocalDistance)
+
+			lx, ly := c.lensSample(rand.Float64(), rand.Float64(), rand.Float64())
+			origin = datatypes.Point(lx*c.Aperture, ly*c.Aperture, 0)
+		}
+
+		direction = datatypes.Subtract(pixel, origin)
+	}
+
+	transform_inv, _ := c.Transform.Inverse()
+
+	origin = datatypes.TupleMultiply(transform_inv, origin)
+	direction = datatypes.TupleMultiply(transform_inv, direction)
 	direction = direction.Normalize()
 
-	return datatypes.Ray{Origin: origin, Direction: direction}
+	return datatypes.Ray{Origin: origin, Direction: direction}, true
 }
 
-func (c *camera) Render(w World) image.Image {
-	im := InitCanvas(c.Hsize, c.Vsize)
+// colorThrough is the color seen through (dx, dy) of pixel (px, py)
+func (c *camera) colorThrough(w World, px, py int, dx, dy float64) raytracing.RGB {
+	r, ok := c.cameraRay(px, py, dx, dy)
+	if !ok {
+		return raytracing.RGB{}
+	}
+	return w.ColorAt(r, c.Depth)
+}
+
+// sampleGrid is the number of samples along each side of a pixel
+func (c *camera) sampleGrid() int {
+	grid := int(math.Ceil(math.Sqrt(float64(c.SamplesPerPixel))))
+	if grid < 1 {
+		grid = 1
+	}
+	return grid
+}
 
-	for y := 0; y < c.Vsize; y++ {
-		for x := 0; x < c.Hsize; x++ {
-			r := c.RayForPixel(x, y)
-			color := w.ColorAt(r, 5)
-			im.Set(x, y, color)
+// PixelColor reconstructs pixel (x, y) from the filtered average of its samples
+func (c *camera) PixelColor(w World, x, y int) raytracing.RGB {
+	filter := c.Filter
+	if filter == nil {
+		filter = BoxFilter{}
+	}
+
+	grid := c.sampleGrid()
+	radius := filter.Radius()
+	sum, plain := raytracing.RGB{}, raytracing.RGB{}
+	totalWeight := 0.0
+
+	for j := 0; j < grid; j++ {
+		for i := 0; i < grid; i++ {
+			du, dv := 0.5, 0.5
+			if c.Jitter {
+				du, dv = rand.Float64(), rand.Float64()
+			}
+
+			// offsets from the pixel center, spanning the filter's support
+			fx := (2*(float64(i)+du)/float64(grid) - 1) * radius
+			fy := (2*(float64(j)+dv)/float64(grid) - 1) * radius
+
+			color := c.colorThrough(w, x, y, 0.5+fx, 0.5+fy)
+			weight := filter.Weight(fx, fy)
+
+			sum = raytracing.Add(sum, color.Multiply(weight))
+			plain = raytracing.Add(plain, color)
+			totalWeight += weight
 		}
 	}
 
-	return im
+	// filters with negative lobes can cancel out entirely
+	if totalWeight <= 0 {
+		return plain.Multiply(1 / float64(grid*grid))
+	}
+
+	return sum.Multiply(1 / totalWeight)
 }
 
-type pnt struct {
-	x, y int
+// frame is the state shared by every pixel of one render
+type frame struct {
+	corners *cornerGrid // only traced for adaptive rendering
+	counts  []int       // rays traced for each pixel
 }
 
-func worker(channel chan pnt, w World, c *camera, im *image.RGBA64, wg *sync.WaitGroup) {
-	defer wg.Done()
+func (c *camera) newFrame(ctx context.Context, w World, workers int) (*frame, error) {
+	f := frame{counts: make([]int, c.Hsize*c.Vsize)}
 
-	for pnt := range channel {
-		r := c.RayForPixel(pnt.x, pnt.y)
-		color := w.ColorAt(r, 5)
-		im.Set(pnt.x, pnt.y, color)
+	if c.Adaptive {
+		var err error
+		if f.corners, err = c.traceCorners(ctx, w, workers, image.Rect(0, 0, c.Hsize, c.Vsize)); err != nil {
+			return nil, err
+		}
 	}
+
+	return &f, nil
 }
 
-func (c *camera) RenderConcurrent(w World) image.Image {
+// shadePixel colors pixel (x, y) with whichever sampling the camera uses
+func (c *camera) shadePixel(w World, f *frame, x, y int) raytracing.RGB {
+	if f.corners != nil {
+		color, count := c.adaptivePixel(w, f.corners, x, y)
+		f.counts[y*c.Hsize+x] = count
+		return color
+	}
+
+	grid := c.sampleGrid()
+	f.counts[y*c.Hsize+x] = grid * grid
+
+	return c.PixelColor(w, x, y)
+}
 
-	numWorkers := runtime.NumCPU() * 4
+func (c *camera) Render(w World) image.Image {
+	im, _, _ := c.render(context.Background(), w, 1)
+	return im
+}
+
+func (c *camera) workers() int {
+	if c.Workers < 1 {
+		return 1
+	}
+	return c.Workers
+}
+
+func (c *camera) RenderConcurrent(w World) image.Image {
+	im, _, _ := c.render(context.Background(), w, c.workers())
+	return im
+}
 
-	im := InitCanvas(c.Hsize, c.Vsize)
+// RenderContext renders the image a tile at a time on c.Workers goroutines.
+// It stops early with ctx's error, returning what was rendered so far, and
+// also returns a debug image of how many rays each pixel took, brighter
+// pixels took more.
+func (c *camera) RenderContext(ctx context.Context, w World) (image.Image, *image.Gray, error) {
+	return c.render(ctx, w, c.workers())
+}
 
-	channel := make(chan pnt)
-	var wg sync.WaitGroup
-	wg.Add(numWorkers)
+func (c *camera) render(ctx context.Context, w World, workers int) (image.Image, *image.Gray, error) {
+	im := InitCanvas(c.Vsize, c.Hsize)
 
-	fmt.Printf("Starting %d goroutines\n", numWorkers)
-	for i := 0; i < numWorkers; i++ {
-		go worker(channel, w, c, im, &wg)
+	f, err := c.newFrame(ctx, w, workers)
+	if err != nil {
+		return im, nil, err
 	}
 
-	for y := 0; y < c.Vsize; y++ {
-		for x := 0; x < c.Hsize; x++ {
-			channel <- pnt{x, y}
+	err = c.renderTiles(ctx, workers, func(x, y int) {
+		im.Set(x, y, c.shadePixel(w, f, x, y))
+	})
+
+	return im, f.countImage(c.Hsize, c.Vsize), err
+}
+
+// countImage scales the sample counts so the busiest pixel is white
+func (f *frame) countImage(width, height int) *image.Gray {
+	im := image.NewGray(image.Rect(0, 0, width, height))
+
+	most := 1
+	for _, count := range f.counts {
+		if count > most {
+			most = count
 		}
 	}
 
-	close(channel)
-	wg.Wait()
+	for i, count := range f.counts {
+		im.Pix[(i/width)*im.Stride+i%width] = uint8(255 * count / most)
+	}
 
 	return im
 }
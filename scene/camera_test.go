@@ -3,6 +3,8 @@ package scene
 import (
 	"github.com/seantur/ray_tracer_challenge/datatypes"
 	"github.com/seantur/ray_tracer_challenge/raytracing"
+	"github.com/seantur/ray_tracer_challenge/shapes"
+	"image"
 	"math"
 	"testing"
 )
@@ -72,4 +74,91 @@ func TestCamera(t *testing.T) {
 		raytracing.AssertColorsEqual(t, output, desired)
 	})
 
+	t.Run("Rendering a non-square image", func(t *testing.T) {
+		c := GetCamera(8, 4, math.Pi/2)
+
+		for _, im := range []image.Image{c.Render(GetWorld()), c.RenderConcurrent(GetWorld())} {
+			max := im.Bounds().Max
+			datatypes.AssertVal(t, float64(max.X), 8)
+			datatypes.AssertVal(t, float64(max.Y), 4)
+		}
+	})
+
+	t.Run("Rendering reports progress until every tile is done", func(t *testing.T) {
+		c := GetCamera(5, 3, math.Pi/2)
+		c.Workers = 2
+		c.TileSize = 2
+
+		calls, last := 0, 0
+		c.Progress = func(p RenderProgress) {
+			calls++
+			last = p.TilesDone
+			datatypes.AssertVal(t, float64(p.Tiles), 6)
+		}
+
+		c.RenderConcurrent(GetWorld())
+
+		datatypes.AssertVal(t, float64(calls), 6)
+		datatypes.AssertVal(t, float64(last), 6)
+	})
+
+	t.Run("A single sample per pixel shoots through the pixel center", func(t *testing.T) {
+		c := GetCamera(201, 101, math.Pi/2)
+
+		datatypes.AssertTupleEqual(t, c.RayForPixelOffset(100, 50, 0.5, 0.5).Direction, c.RayForPixel(100, 50).Direction)
+		datatypes.AssertTupleEqual(t, c.RayForPixelOffset(100, 50, 0.5, 0.5).Direction, datatypes.Vector(0, 0, -1))
+	})
+
+	t.Run("Supersampling averages a stratified grid of samples", func(t *testing.T) {
+		w := GetWorld()
+		c := GetCamera(11, 11, math.Pi/2)
+		c.Transform = datatypes.ViewTransform(datatypes.Point(0, 0, -5), datatypes.Point(0, 0, 0), datatypes.Vector(0, 1, 0))
+
+		c.SamplesPerPixel = 4
+
+		want := raytracing.RGB{}
+		for _, dy := range []float64{0.25, 0.75} {
+			for _, dx := range []float64{0.25, 0.75} {
+				want = raytracing.Add(want, w.ColorAt(c.RayForPixelOffset(3, 2, dx, dy), c.Depth))
+			}
+		}
+		want = want.Multiply(0.25)
+
+		raytracing.AssertColorsEqual(t, c.PixelColor(w, 3, 2), want)
+	})
+
+	t.Run("Samples per pixel are rounded up to a square grid", func(t *testing.T) {
+		w := GetWorld()
+		c := GetCamera(11, 11, math.Pi/2)
+		c.Transform = datatypes.ViewTransform(datatypes.Point(0, 0, -5), datatypes.Point(0, 0, 0), datatypes.Vector(0, 1, 0))
+
+		c.SamplesPerPixel = 3
+		three := c.PixelColor(w, 3, 2)
+		c.SamplesPerPixel = 4
+
+		raytracing.AssertColorsEqual(t, three, c.PixelColor(w, 3, 2))
+	})
+
+	t.Run("Filtered jittered samples of a flat color stay that color", func(t *testing.T) {
+		wall := shapes.GetPlane()
+		wall.SetTransform(datatypes.GetTransform(datatypes.GetRotationX(math.Pi/2), datatypes.GetTranslation(0, 0, -5)))
+		m := wall.GetMaterial()
+		m.RGB = raytracing.RGB{Red: 0.2, Green: 0.4, Blue: 0.6}
+		m.Ambient, m.Diffuse, m.Specular = 1, 0, 0
+		wall.SetMaterial(m)
+
+		w := World{
+			Lights: []Light{PointLight{Position: datatypes.Point(0, 0, 0), Intensity: raytracing.RGB{Red: 1, Green: 1, Blue: 1}}},
+			Shapes: []shapes.Shape{wall}}
+
+		c := GetCamera(5, 5, math.Pi/2)
+		c.SamplesPerPixel = 9
+		c.Jitter = true
+
+		for _, name := range []string{"box", "tent", "mitchell", "gaussian"} {
+			c.Filter, _ = GetFilter(name)
+			raytracing.AssertColorsEqual(t, c.PixelColor(w, 2, 2), m.RGB)
+		}
+	})
+
 }
@@ -1,45 +1,87 @@
 package scene
 
 import (
+	"fmt"
+	"github.com/seantur/ray_tracer_challenge/raytracing"
 	"image"
 	"image/jpeg"
 	"image/png"
-	"log"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
-func InitCanvas(height, width int) *image.RGBA64 {
-	return image.NewRGBA64(image.Rectangle{Min: image.Point{0, 0}, Max: image.Point{width, height}})
+// LoadTexture reads a PNG, JPEG or Radiance HDR image as an image texture
+func LoadTexture(path string) (*raytracing.ImageTexture, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	im, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	return raytracing.GetImageTexture(im), nil
+}
+
+func InitCanvas(height, width int) *Framebuffer {
+	return GetFramebuffer(width, height)
+}
+
+// imageWriters maps lower case file extensions to the function saving them
+var imageWriters = map[string]func(image.Image, string) error{
+	".png":  SavePng,
+	".jpg":  SaveJpg,
+	".jpeg": SaveJpg,
+	".hdr":  SaveHdr,
+	".exr":  SaveExr,
 }
 
-func SavePng(c image.Image, path string) {
+// SupportedFormat reports whether SaveImage can write to path
+func SupportedFormat(path string) bool {
+	_, ok := imageWriters[strings.ToLower(filepath.Ext(path))]
+	return ok
+}
+
+// SaveImage writes c to path in the format named by its extension
+func SaveImage(c image.Image, path string) error {
+	ext := strings.ToLower(filepath.Ext(path))
+
+	save, ok := imageWriters[ext]
+	if !ok {
+		return fmt.Errorf("unsupported output format %q", ext)
+	}
+
+	return save(c, path)
+}
+
+func SavePng(c image.Image, path string) error {
 	f, err := os.Create(path)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	if err := png.Encode(f, c); err != nil {
 		f.Close()
-		log.Fatal(err)
+		return err
 	}
 
-	if err := f.Close(); err != nil {
-		log.Fatal(err)
-	}
+	return f.Close()
 }
 
-func SaveJpg(c image.Image, path string) {
+func SaveJpg(c image.Image, path string) error {
 	f, err := os.Create(path)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	if err := jpeg.Encode(f, c, nil); err != nil {
 		f.Close()
-		log.Fatal(err)
+		return err
 	}
 
-	if err := f.Close(); err != nil {
-		log.Fatal(err)
-	}
+	return f.Close()
 }
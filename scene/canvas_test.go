@@ -2,6 +2,9 @@ package scene
 
 import (
 	"github.com/seantur/ray_tracer_challenge/raytracing"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -50,4 +53,28 @@ func TestCanvas(t *testing.T) {
 		raytracing.AssertColorsEqual(t, output, Red)
 	})
 
+	t.Run("Saving an image picks the format from the extension", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "canvas")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		c := InitCanvas(4, 6)
+
+		for _, name := range []string{"out.png", "out.jpg", "OUT.JPEG", "out.hdr", "out.exr"} {
+			if err := SaveImage(c, filepath.Join(dir, name)); err != nil {
+				t.Errorf("saving %s: %v", name, err)
+			}
+		}
+
+		if err := SaveImage(c, filepath.Join(dir, "out.bmp")); err == nil {
+			t.Error("expected an error for an unsupported format")
+		}
+
+		if err := SavePng(c, filepath.Join(dir, "missing", "out.png")); err == nil {
+			t.Error("expected an error for a missing directory")
+		}
+	})
+
 }
@@ -0,0 +1,506 @@
+package scene
+
+import (
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"github.com/seantur/ray_tracer_challenge/raytracing"
+	"image"
+	"net"
+	"sync"
+	"time"
+)
+
+// Distributed rendering runs over gob encoded messages on a TCP connection
+// that each worker opens to the coordinator:
+//
+//	worker      -> coordinator  workerHello, how many tiles it renders at once
+//	coordinator -> worker       jobMessage with the scene, then one per tile
+//	worker      -> coordinator  tileResult for every tile, in any order
+//	coordinator -> worker       jobMessage with Done set once the image is done
+//
+// Workers load the same scene file as the coordinator, so relative paths in
+// it (such as OBJ files) must resolve on every machine.
+
+func init() {
+	// the camera's Filter is an interface, gob needs its concrete types
+	gob.Register(BoxFilter{})
+	gob.Register(TentFilter{})
+	gob.Register(MitchellFilter{})
+	gob.Register(GaussianFilter{})
+}
+
+type workerHello struct {
+	Workers int
+}
+
+type jobMessage struct {
+	// sent once, first
+	Scene  []byte
+	Dir    string
+	Camera *camera
+
+	Tile image.Rectangle
+	Done bool
+}
+
+type tileResult struct {
+	Tile   image.Rectangle
+	Pixels []raytracing.RGB // row by row
+	Err    string
+}
+
+// renderTile colors every pixel of tile, row by row
+func (c *camera) renderTile(w World, tile image.Rectangle) []raytracing.RGB {
+	pixels := make([]raytracing.RGB, 0, tile.Dx()*tile.Dy())
+
+	var corners *cornerGrid
+	if c.Adaptive {
+		corners, _ = c.traceCorners(context.Background(), w, 1, tile)
+	}
+
+	for y := tile.Min.Y; y < tile.Max.Y; y++ {
+		for x := tile.Min.X; x < tile.Max.X; x++ {
+			if corners != nil {
+				color, _ := c.adaptivePixel(w, corners, x, y)
+				pixels = append(pixels, color)
+			} else {
+				pixels = append(pixels, c.PixelColor(w, x, y))
+			}
+		}
+	}
+
+	return pixels
+}
+
+// RenderWorker renders tiles for the coordinator on the other end of conn, up
+// to workers at a time, until the coordinator finishes or ctx is done
+func RenderWorker(ctx context.Context, conn net.Conn, workers int) error {
+	defer conn.Close()
+
+	if workers < 1 {
+		workers = 1
+	}
+
+	// unblock reads and writes when the context ends
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-stop:
+		}
+	}()
+
+	enc, dec := gob.NewEncoder(conn), gob.NewDecoder(conn)
+
+	if err := enc.Encode(workerHello{Workers: workers}); err != nil {
+		return err
+	}
+
+	var setup jobMessage
+	if err := dec.Decode(&setup); err != nil {
+		return err
+	}
+
+	if setup.Camera == nil {
+		return errors.New("coordinator did not send a camera")
+	}
+
+	w, _, err := LoadScene(setup.Scene, setup.Dir)
+	if err != nil {
+		enc.Encode(tileResult{Err: err.Error()})
+		return err
+	}
+
+	c := setup.Camera
+
+	var encMu sync.Mutex
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		var job jobMessage
+		if err := dec.Decode(&job); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+
+		if job.Done {
+			return nil
+		}
+
+		wg.Add(1)
+		go func(tile image.Rectangle) {
+			defer wg.Done()
+
+			result := tileResult{Tile: tile, Pixels: c.renderTile(w, tile)}
+
+			encMu.Lock()
+			defer encMu.Unlock()
+			enc.Encode(result)
+		}(job.Tile)
+	}
+}
+
+// DialWorker connects to the coordinator at addr and runs RenderWorker
+func DialWorker(ctx context.Context, addr string, workers int) error {
+	var d net.Dialer
+
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	return RenderWorker(ctx, conn, workers)
+}
+
+// Coordinator splits a render into the camera's tiles and hands them to the
+// workers that connect to it. Tiles held by a worker that disconnects go back
+// in the queue, and a tile that takes longer than TileTimeout is also given
+// to the next idle worker, the first copy to finish is kept.
+type Coordinator struct {
+	Scene       []byte // the YAML scene file
+	Dir         string // where workers resolve the scene's relative paths
+	Camera      camera
+	TileTimeout time.Duration // zero never reassigns slow tiles
+}
+
+func GetCoordinator(scene []byte, dir string, c camera) *Coordinator {
+	return &Coordinator{Scene: scene, Dir: dir, Camera: c, TileTimeout: time.Minute}
+}
+
+// dispatch tracks which tiles still need rendering
+type dispatch struct {
+	sync.Mutex
+	pending  []image.Rectangle
+	assigned map[image.Rectangle]time.Time
+	finished map[image.Rectangle]bool
+	total    int
+	lastErr  error
+
+	changed  chan struct{} // closed and replaced whenever anything changes
+	complete chan struct{} // closed once every tile is finished
+}
+
+// notify wakes everyone waiting for a change, the caller holds the lock
+func (d *dispatch) notify() {
+	close(d.changed)
+	d.changed = make(chan struct{})
+}
+
+// next returns a tile to render and a channel that is closed when the state
+// changes, for when there is nothing to hand out yet
+func (d *dispatch) next(timeout time.Duration, held map[image.Rectangle]bool) (image.Rectangle, bool, chan struct{}) {
+	d.Lock()
+	defer d.Unlock()
+
+	for len(d.pending) > 0 {
+		tile := d.pending[0]
+		d.pending = d.pending[1:]
+
+		if !d.finished[tile] {
+			d.assigned[tile] = time.Now()
+			return tile, true, d.changed
+		}
+	}
+
+	if timeout > 0 {
+		for tile, since := range d.assigned {
+			if !held[tile] && time.Since(since) > timeout {
+				d.assigned[tile] = time.Now()
+				return tile, true, d.changed
+			}
+		}
+	}
+
+	return image.Rectangle{}, false, d.changed
+}
+
+// finish records a rendered tile, reporting false for duplicates
+func (d *dispatch) finish(tile image.Rectangle) bool {
+	d.Lock()
+	defer d.Unlock()
+
+	if d.finished[tile] {
+		return false
+	}
+
+	d.finished[tile] = true
+	delete(d.assigned, tile)
+	d.notify()
+
+	if len(d.finished) == d.total {
+		close(d.complete)
+	}
+
+	return true
+}
+
+// requeue puts back the unfinished tiles of a worker that went away
+func (d *dispatch) requeue(tiles map[image.Rectangle]bool, err error) {
+	d.Lock()
+	defer d.Unlock()
+
+	for tile := range tiles {
+		if !d.finished[tile] {
+			delete(d.assigned, tile)
+			d.pending = append([]image.Rectangle{tile}, d.pending...)
+		}
+	}
+
+	if err != nil {
+		d.lastErr = err
+	}
+	d.notify()
+}
+
+// ioTimeout bounds how long the coordinator waits on a single message, so an
+// unresponsive worker cannot hold up the render
+const ioTimeout = 30 * time.Second
+
+// Render accepts workers on l until every tile is rendered or ctx is done,
+// and closes l before returning
+func (co *Coordinator) Render(ctx context.Context, l net.Listener) (image.Image, error) {
+	defer l.Close()
+
+	c := co.Camera
+	im := InitCanvas(c.Vsize, c.Hsize)
+	tiles := c.Tiles()
+
+	d := dispatch{
+		pending:  tiles,
+		assigned: map[image.Rectangle]time.Time{},
+		finished: map[image.Rectangle]bool{},
+		total:    len(tiles),
+		changed:  make(chan struct{}),
+		complete: make(chan struct{})}
+
+	if len(tiles) == 0 {
+		return im, nil
+	}
+
+	// the deferred close of over runs first, then the wait for workers to be
+	// told the render is done
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	over := make(chan struct{})
+	defer close(over)
+
+	conns := make(chan net.Conn)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			select {
+			case conns <- conn:
+			case <-over:
+				conn.Close()
+				return
+			}
+		}
+	}()
+
+	start := time.Now()
+	var imMu sync.Mutex
+
+	store := func(result tileResult) error {
+		if result.Tile.Intersect(im.Bounds()) != result.Tile || len(result.Pixels) != result.Tile.Dx()*result.Tile.Dy() {
+			return fmt.Errorf("worker sent %d pixels for tile %v", len(result.Pixels), result.Tile)
+		}
+
+		imMu.Lock()
+		defer imMu.Unlock()
+
+		if !d.finish(result.Tile) {
+			return nil
+		}
+
+		i := 0
+		for y := result.Tile.Min.Y; y < result.Tile.Max.Y; y++ {
+			for x := result.Tile.Min.X; x < result.Tile.Max.X; x++ {
+				im.Set(x, y, result.Pixels[i])
+				i++
+			}
+		}
+
+		if c.Progress != nil {
+			d.Lock()
+			done := len(d.finished)
+			d.Unlock()
+
+			elapsed := time.Since(start)
+			c.Progress(RenderProgress{
+				TilesDone: done,
+				Tiles:     len(tiles),
+				Elapsed:   elapsed,
+				Remaining: elapsed / time.Duration(done) * time.Duration(len(tiles)-done)})
+		}
+
+		return nil
+	}
+
+	for {
+		select {
+		case conn := <-conns:
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				co.serve(ctx, conn, &d, store)
+			}()
+		case <-d.complete:
+			return im, nil
+		case <-ctx.Done():
+			d.Lock()
+			lastErr := d.lastErr
+			d.Unlock()
+
+			if lastErr != nil {
+				return im, fmt.Errorf("%v (last worker error: %v)", ctx.Err(), lastErr)
+			}
+			return im, ctx.Err()
+		}
+	}
+}
+
+// serve feeds tiles to one worker until the render is complete
+func (co *Coordinator) serve(ctx context.Context, conn net.Conn, d *dispatch, store func(tileResult) error) {
+	defer conn.Close()
+
+	served := make(chan struct{})
+	defer close(served)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-served:
+		}
+	}()
+
+	enc, dec := gob.NewEncoder(conn), gob.NewDecoder(conn)
+	send := func(job jobMessage) error {
+		conn.SetWriteDeadline(time.Now().Add(ioTimeout))
+		return enc.Encode(job)
+	}
+
+	var hello workerHello
+	conn.SetReadDeadline(time.Now().Add(ioTimeout))
+	if err := dec.Decode(&hello); err != nil {
+		return
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	if hello.Workers < 1 {
+		hello.Workers = 1
+	}
+
+	c := co.Camera
+	if err := send(jobMessage{Scene: co.Scene, Dir: co.Dir, Camera: &c}); err != nil {
+		return
+	}
+
+	var mu sync.Mutex
+	held := map[image.Rectangle]bool{}
+	failed := make(chan error, 1)
+
+	// results arrive independently of the tiles being sent
+	go func() {
+		for {
+			var result tileResult
+			err := dec.Decode(&result)
+			if err == nil && result.Err != "" {
+				err = errors.New(result.Err)
+			}
+			if err == nil {
+				err = store(result)
+			}
+
+			if err != nil {
+				failed <- err
+				return
+			}
+
+			mu.Lock()
+			delete(held, result.Tile)
+			mu.Unlock()
+			d.Lock()
+			d.notify()
+			d.Unlock()
+		}
+	}()
+
+	ticker := time.NewTicker(co.tickInterval())
+	defer ticker.Stop()
+
+	for {
+		mu.Lock()
+		busy := len(held) >= hello.Workers
+		var snapshot map[image.Rectangle]bool
+		if !busy {
+			snapshot = make(map[image.Rectangle]bool, len(held))
+			for tile := range held {
+				snapshot[tile] = true
+			}
+		}
+		mu.Unlock()
+
+		var changed chan struct{}
+
+		if !busy {
+			tile, ok, ch := d.next(co.TileTimeout, snapshot)
+			changed = ch
+
+			if ok {
+				mu.Lock()
+				held[tile] = true
+				mu.Unlock()
+
+				if err := send(jobMessage{Tile: tile}); err != nil {
+					co.drop(d, &mu, held, err)
+					return
+				}
+				continue
+			}
+		} else {
+			d.Lock()
+			changed = d.changed
+			d.Unlock()
+		}
+
+		select {
+		case <-changed:
+		case <-ticker.C:
+		case err := <-failed:
+			co.drop(d, &mu, held, err)
+			return
+		case <-d.complete:
+			send(jobMessage{Done: true})
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// drop hands a failed worker's tiles back to the queue
+func (co *Coordinator) drop(d *dispatch, mu *sync.Mutex, held map[image.Rectangle]bool, err error) {
+	mu.Lock()
+	defer mu.Unlock()
+	d.requeue(held, err)
+}
+
+// tickInterval is how often idle connections look for slow tiles to take over
+func (co *Coordinator) tickInterval() time.Duration {
+	if co.TileTimeout > 0 && co.TileTimeout < time.Second {
+		return co.TileTimeout / 2
+	}
+	return 500 * time.Millisecond
+}
@@ -0,0 +1,196 @@
+package scene
+
+import (
+	"context"
+	"encoding/gob"
+	"image"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+const distributedScene = `
+- add: camera
+  width: 24
+  height: 16
+  field-of-view: 1.0471975511965976
+  from: [0, 1.5, -5]
+  to: [0, 1, 0]
+  up: [0, 1, 0]
+- add: light
+  at: [-10, 10, -10]
+- add: plane
+  material:
+    pattern:
+      type: checkers
+      colors: [[1, 1, 1], [0, 0, 0]]
+- add: sphere
+  transform:
+    - [translate, 0, 1, 0]
+  material:
+    color: [1, 0.2, 0.2]
+    reflective: 0.3
+`
+
+func TestDistributed(t *testing.T) {
+
+	setup := func(t *testing.T) (*Coordinator, World, net.Listener) {
+		t.Helper()
+
+		w, c, err := LoadScene([]byte(distributedScene), ".")
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.TileSize = 8
+
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return GetCoordinator([]byte(distributedScene), ".", c), w, l
+	}
+
+	assertSameImage := func(t *testing.T, got, want image.Image) {
+		t.Helper()
+
+		b := want.Bounds()
+		if got.Bounds() != b {
+			t.Fatalf("got bounds %v, want %v", got.Bounds(), b)
+		}
+
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				if got.At(x, y) != want.At(x, y) {
+					t.Fatalf("pixel (%d, %d) is %v, want %v", x, y, got.At(x, y), want.At(x, y))
+				}
+			}
+		}
+	}
+
+	ctxTimeout := func() (context.Context, context.CancelFunc) {
+		return context.WithTimeout(context.Background(), 20*time.Second)
+	}
+
+	t.Run("Workers on localhost render the same image as a single machine", func(t *testing.T) {
+		co, w, l := setup(t)
+		ctx, cancel := ctxTimeout()
+		defer cancel()
+
+		errs := make(chan error, 3)
+		for i := 0; i < 3; i++ {
+			go func() { errs <- DialWorker(ctx, l.Addr().String(), 2) }()
+		}
+
+		im, err := co.Render(ctx, l)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i := 0; i < 3; i++ {
+			if err := <-errs; err != nil {
+				t.Errorf("worker failed: %v", err)
+			}
+		}
+
+		assertSameImage(t, im, co.Camera.Render(w))
+	})
+
+	t.Run("Tiles of a worker that disconnects are reassigned", func(t *testing.T) {
+		co, w, l := setup(t)
+		ctx, cancel := ctxTimeout()
+		defer cancel()
+
+		// takes a few tiles then hangs up without answering
+		flaky := make(chan struct{})
+		go func() {
+			defer close(flaky)
+
+			conn, err := net.Dial("tcp", l.Addr().String())
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+
+			enc, dec := gob.NewEncoder(conn), gob.NewDecoder(conn)
+			enc.Encode(workerHello{Workers: 4})
+
+			for i := 0; i < 5; i++ {
+				var job jobMessage
+				if dec.Decode(&job) != nil {
+					return
+				}
+			}
+		}()
+
+		go func() {
+			<-flaky
+			DialWorker(ctx, l.Addr().String(), 2)
+		}()
+
+		im, err := co.Render(ctx, l)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assertSameImage(t, im, co.Camera.Render(w))
+	})
+
+	t.Run("Tiles of a worker that is too slow are given to another", func(t *testing.T) {
+		co, w, l := setup(t)
+		co.TileTimeout = 50 * time.Millisecond
+		ctx, cancel := ctxTimeout()
+		defer cancel()
+
+		// accepts tiles but never renders them
+		stalled := make(chan struct{})
+		go func() {
+			conn, err := net.Dial("tcp", l.Addr().String())
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+
+			gob.NewEncoder(conn).Encode(workerHello{Workers: 2})
+			dec := gob.NewDecoder(conn)
+
+			for i := 0; i < 3; i++ {
+				var job jobMessage
+				if dec.Decode(&job) != nil {
+					return
+				}
+			}
+			close(stalled)
+
+			<-ctx.Done()
+		}()
+
+		go func() {
+			<-stalled
+			DialWorker(ctx, l.Addr().String(), 1)
+		}()
+
+		im, err := co.Render(ctx, l)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assertSameImage(t, im, co.Camera.Render(w))
+	})
+
+	t.Run("A render with only failing workers times out with their error", func(t *testing.T) {
+		co, _, l := setup(t)
+		co.Scene = []byte("- add: teapot\n")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		defer cancel()
+
+		go DialWorker(ctx, l.Addr().String(), 1)
+
+		_, err := co.Render(ctx, l)
+		if err == nil || !strings.Contains(err.Error(), "unknown item") {
+			t.Errorf("expected the worker's scene error, got %v", err)
+		}
+	})
+}
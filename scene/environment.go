@@ -0,0 +1,100 @@
+package scene
+
+import (
+	"github.com/seantur/ray_tracer_challenge/datatypes"
+	"github.com/seantur/ray_tracer_challenge/raytracing"
+	"math"
+	"sort"
+)
+
+// EnvironmentLight lights the scene from every direction with an
+// equirectangular image, laid out as for EquirectBackground. Each hit is lit
+// by Samples directions picked in proportion to the image's luminance, so a
+// bright sun gets most of the shadow rays instead of the dim sky around it.
+type EnvironmentLight struct {
+	Texture   *raytracing.ImageTexture
+	Intensity float64 // scales the radiance of the image
+	Samples   int
+
+	rows    []float64   // running total of the weight of each row
+	columns [][]float64 // running total of the weight along each row
+}
+
+// GetEnvironmentLight builds the tables for sampling t. A pixel's weight is
+// its luminance times the solid angle it covers, which shrinks toward the
+// poles.
+func GetEnvironmentLight(t *raytracing.ImageTexture, samples int) *EnvironmentLight {
+	e := EnvironmentLight{Texture: t, Intensity: 1, Samples: samples}
+	e.rows = make([]float64, t.Height)
+	e.columns = make([][]float64, t.Height)
+
+	total := 0.0
+
+	for y := 0; y < t.Height; y++ {
+		top, bottom := e.rowBounds(y)
+		solidAngle := (top - bottom) * 2 * math.Pi / float64(t.Width)
+		e.columns[y] = make([]float64, t.Width)
+
+		row := 0.0
+		for x := 0; x < t.Width; x++ {
+			row += math.Max(t.Pixels[y*t.Width+x].Luminance(), 0) * solidAngle
+			e.columns[y][x] = row
+		}
+
+		total += row
+		e.rows[y] = total
+	}
+
+	return &e
+}
+
+// rowBounds returns the cosines of the polar angles at the top and bottom
+// edges of row y
+func (e *EnvironmentLight) rowBounds(y int) (top, bottom float64) {
+	height := float64(e.Texture.Height)
+	return math.Cos(math.Pi * float64(y) / height), math.Cos(math.Pi * float64(y+1) / height)
+}
+
+// pick returns the first index whose running total is above x
+func pick(totals []float64, x float64) int {
+	i := sort.Search(len(totals), func(i int) bool { return totals[i] > x })
+	if i == len(totals) {
+		i--
+	}
+	return i
+}
+
+// Sample turns four uniform numbers into a direction toward the environment,
+// the radiance arriving from it and the probability density of picking it
+// per unit solid angle. The pdf is 0 for an image that is black everywhere.
+func (e *EnvironmentLight) Sample(u1, u2, u3, u4 float64) (datatypes.Tuple, raytracing.RGB, float64) {
+	if len(e.rows) == 0 || e.rows[len(e.rows)-1] <= 0 {
+		return datatypes.Vector(0, 1, 0), raytracing.RGB{}, 0
+	}
+
+	total := e.rows[len(e.rows)-1]
+	y := pick(e.rows, u1*total)
+
+	columns := e.columns[y]
+	x := pick(columns, u2*columns[len(columns)-1])
+
+	weight := columns[x]
+	if x > 0 {
+		weight -= columns[x-1]
+	}
+
+	// a point spread evenly over the pixel's solid angle, turned back into a
+	// direction the way EquirectBackground maps directions to the image
+	top, bottom := e.rowBounds(y)
+	cosTheta := top - u4*(top-bottom)
+	sinTheta := math.Sqrt(math.Max(0, 1-cosTheta*cosTheta))
+	phi := ((float64(x)+u3)/float64(e.Texture.Width) - 0.5) * 2 * math.Pi
+
+	direction := datatypes.Vector(sinTheta*math.Sin(phi), cosTheta, -sinTheta*math.Cos(phi))
+
+	solidAngle := (top - bottom) * 2 * math.Pi / float64(e.Texture.Width)
+	pdf := weight / total / solidAngle
+
+	radiance := e.Texture.Pixels[y*e.Texture.Width+x]
+	return direction, radiance.Multiply(e.Intensity), pdf
+}
@@ -0,0 +1,116 @@
+package scene
+
+import (
+	"github.com/seantur/ray_tracer_challenge/datatypes"
+	"github.com/seantur/ray_tracer_challenge/raytracing"
+	"github.com/seantur/ray_tracer_challenge/shapes"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestEnvironmentLight(t *testing.T) {
+
+	// uniform is a w x h environment that is the same color everywhere
+	uniform := func(w, h int, c raytracing.RGB) *raytracing.ImageTexture {
+		tex := raytracing.ImageTexture{Width: w, Height: h, Transform: datatypes.GetIdentity()}
+		for i := 0; i < w*h; i++ {
+			tex.Pixels = append(tex.Pixels, c)
+		}
+		return &tex
+	}
+
+	white := raytracing.RGB{Red: 1, Green: 1, Blue: 1}
+
+	// floor returns the computations for a hit on top of a white plane
+	floor := func(w *World) shapes.Computation {
+		plane := shapes.GetPlane()
+		mat := plane.GetMaterial()
+		mat.Ambient, mat.Diffuse, mat.Specular = 0, 1, 0
+		plane.SetMaterial(mat)
+		w.Shapes = append(w.Shapes, plane)
+
+		r := datatypes.Ray{Origin: datatypes.Point(0, 1, 0), Direction: datatypes.Vector(0, -1, 0)}
+		i := shapes.Intersection{T: 1, Object: plane}
+		return i.PrepareComputations(r, []shapes.Intersection{i})
+	}
+
+	t.Run("A uniform environment is sampled evenly over the sphere", func(t *testing.T) {
+		env := GetEnvironmentLight(uniform(8, 4, white), 1)
+
+		for i := 0; i < 100; i++ {
+			direction, radiance, pdf := env.Sample(rand.Float64(), rand.Float64(), rand.Float64(), rand.Float64())
+
+			datatypes.AssertVal(t, math.Round(direction.Magnitude()*1e9)/1e9, 1)
+			raytracing.AssertColorsEqual(t, radiance, white)
+			if !datatypes.IsClose(pdf, 1/(4*math.Pi)) {
+				t.Fatalf("got pdf %f want %f", pdf, 1/(4*math.Pi))
+			}
+		}
+	})
+
+	t.Run("Samples go to the bright pixels", func(t *testing.T) {
+		tex := uniform(4, 2, raytracing.RGB{})
+		// the top row, second column is just left of -z looking up
+		tex.Pixels[1] = raytracing.RGB{Red: 10, Green: 10, Blue: 10}
+		env := GetEnvironmentLight(tex, 1)
+
+		for i := 0; i < 100; i++ {
+			direction, radiance, _ := env.Sample(rand.Float64(), rand.Float64(), rand.Float64(), rand.Float64())
+
+			if direction.Y < 0 || direction.X > 0 || direction.Z > 0 {
+				t.Fatalf("expected a direction up and toward -x, -z, got %v", direction)
+			}
+			raytracing.AssertColorsEqual(t, radiance, tex.Pixels[1])
+		}
+	})
+
+	t.Run("A black environment gives no light", func(t *testing.T) {
+		env := GetEnvironmentLight(uniform(4, 2, raytracing.RGB{}), 1)
+
+		if _, _, pdf := env.Sample(0.5, 0.5, 0.5, 0.5); pdf != 0 {
+			t.Errorf("expected a pdf of 0, got %f", pdf)
+		}
+	})
+
+	t.Run("A white sky lights a white floor white", func(t *testing.T) {
+		w := World{Environment: GetEnvironmentLight(uniform(16, 8, white), 20000)}
+		comps := floor(&w)
+
+		c := w.ShadeHit(comps, 0)
+		for _, v := range []float64{c.Red, c.Green, c.Blue} {
+			if math.Abs(v-1) > 0.05 {
+				t.Errorf("expected about 1, got %v", c)
+			}
+		}
+	})
+
+	t.Run("Intensity scales the light", func(t *testing.T) {
+		w := World{Environment: GetEnvironmentLight(uniform(16, 8, white), 20000)}
+		w.Environment.Intensity = 0.25
+		comps := floor(&w)
+
+		if c := w.ShadeHit(comps, 0); math.Abs(c.Red-0.25) > 0.02 {
+			t.Errorf("expected about 0.25, got %v", c)
+		}
+	})
+
+	t.Run("Shapes in the way cast shadows", func(t *testing.T) {
+		w := World{Environment: GetEnvironmentLight(uniform(16, 8, white), 500)}
+		comps := floor(&w)
+
+		roof := shapes.GetPlane()
+		roof.SetTransform(datatypes.GetTranslation(0, 2, 0))
+		w.Shapes = append(w.Shapes, roof)
+
+		raytracing.AssertColorsEqual(t, w.ShadeHit(comps, 0), raytracing.RGB{})
+
+		v := comps.Object.GetVisibility()
+		v.NoReceiveShadow = true
+		comps.Object.SetVisibility(v)
+
+		if c := w.ShadeHit(comps, 0); c.Red < 0.5 {
+			t.Errorf("expected a floor that ignores shadows to be lit, got %v", c)
+		}
+	})
+}
@@ -0,0 +1,258 @@
+package scene
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"image"
+	"io"
+	"math"
+	"os"
+)
+
+// ExrPixelType is how each channel of an OpenEXR pixel is stored
+type ExrPixelType int
+
+const (
+	// ExrHalf stores 16 bit floats, enough for most images at half the size
+	ExrHalf ExrPixelType = iota
+	// ExrFloat stores 32 bit floats
+	ExrFloat
+)
+
+// ExrCompression is how OpenEXR scanlines are compressed
+type ExrCompression int
+
+const (
+	// ExrZip deflates blocks of 16 scanlines
+	ExrZip ExrCompression = iota
+	// ExrNone stores the pixels as they are
+	ExrNone
+)
+
+// ExrOptions are the settings for EncodeExr, the zero value writes ZIP
+// compressed half floats
+type ExrOptions struct {
+	PixelType   ExrPixelType
+	Compression ExrCompression
+}
+
+// values used in the file for the pixel types and compressions
+const (
+	exrHalf  = 1
+	exrFloat = 2
+
+	exrNone = 0
+	exrZip  = 3
+)
+
+// EncodeExr writes m as a single part scanline OpenEXR file with B, G and R
+// channels. A nil o uses the default options.
+func EncodeExr(w io.Writer, m image.Image, o *ExrOptions) error {
+	if o == nil {
+		o = &ExrOptions{}
+	}
+
+	pixelType, size := int32(exrHalf), 2
+	if o.PixelType == ExrFloat {
+		pixelType, size = exrFloat, 4
+	}
+
+	compression, linesPerChunk := byte(exrZip), 16
+	if o.Compression == ExrNone {
+		compression, linesPerChunk = exrNone, 1
+	}
+
+	b := m.Bounds()
+	width, height := b.Dx(), b.Dy()
+
+	var header bytes.Buffer
+	le := binary.LittleEndian
+
+	binary.Write(&header, le, [2]int32{20000630, 2})
+
+	attribute := func(name, kind string, value interface{}) {
+		var v bytes.Buffer
+		binary.Write(&v, le, value)
+
+		header.WriteString(name + "\x00" + kind + "\x00")
+		binary.Write(&header, le, int32(v.Len()))
+		header.Write(v.Bytes())
+	}
+
+	// channels have to be in alphabetical order
+	var channels bytes.Buffer
+	for _, name := range []string{"B", "G", "R"} {
+		channels.WriteString(name + "\x00")
+		binary.Write(&channels, le, struct {
+			PixelType            int32
+			Linear               uint8
+			Reserved             [3]uint8
+			XSampling, YSampling int32
+		}{PixelType: pixelType, XSampling: 1, YSampling: 1})
+	}
+	channels.WriteByte(0)
+
+	window := [4]int32{0, 0, int32(width - 1), int32(height - 1)}
+
+	attribute("channels", "chlist", channels.Bytes())
+	attribute("compression", "compression", compression)
+	attribute("dataWindow", "box2i", window)
+	attribute("displayWindow", "box2i", window)
+	attribute("lineOrder", "lineOrder", uint8(0))
+	attribute("pixelAspectRatio", "float", float32(1))
+	attribute("screenWindowCenter", "v2f", [2]float32{0, 0})
+	attribute("screenWindowWidth", "float", float32(1))
+	header.WriteByte(0)
+
+	// each chunk is its first y, its size and its scanlines, and each
+	// scanline has all the blues, then the greens, then the reds
+	var chunks [][]byte
+	row := make([][3]float32, width)
+
+	for y0 := 0; y0 < height; y0 += linesPerChunk {
+		lines := linesPerChunk
+		if y0+lines > height {
+			lines = height - y0
+		}
+
+		raw := make([]byte, 0, lines*width*3*size)
+		for y := y0; y < y0+lines; y++ {
+			for x := range row {
+				c := rgbOf(m.At(b.Min.X+x, b.Min.Y+y))
+				row[x] = [3]float32{float32(c.Red), float32(c.Green), float32(c.Blue)}
+			}
+
+			for channel := 2; channel >= 0; channel-- {
+				for _, pixel := range row {
+					v := pixel[channel]
+
+					if size == 2 {
+						raw = append(raw, 0, 0)
+						le.PutUint16(raw[len(raw)-2:], halfBits(v))
+					} else {
+						raw = append(raw, 0, 0, 0, 0)
+						le.PutUint32(raw[len(raw)-4:], math.Float32bits(v))
+					}
+				}
+			}
+		}
+
+		data := raw
+		if compression == exrZip {
+			var err error
+			if data, err = exrZipChunk(raw); err != nil {
+				return err
+			}
+		}
+
+		chunk := make([]byte, 8, 8+len(data))
+		le.PutUint32(chunk, uint32(y0))
+		le.PutUint32(chunk[4:], uint32(len(data)))
+		chunks = append(chunks, append(chunk, data...))
+	}
+
+	// the offset table points at each chunk from the start of the file
+	offset := uint64(header.Len() + 8*len(chunks))
+	for _, chunk := range chunks {
+		binary.Write(&header, le, offset)
+		offset += uint64(len(chunk))
+	}
+
+	if _, err := w.Write(header.Bytes()); err != nil {
+		return err
+	}
+
+	for _, chunk := range chunks {
+		if _, err := w.Write(chunk); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// exrZipChunk splits the bytes into even and odd halves and stores each as
+// the difference from the one before, which deflates much better, it returns
+// raw unchanged when that doesn't make it smaller
+func exrZipChunk(raw []byte) ([]byte, error) {
+	tmp := make([]byte, len(raw))
+
+	half := (len(raw) + 1) / 2
+	for i, v := range raw {
+		if i%2 == 0 {
+			tmp[i/2] = v
+		} else {
+			tmp[half+i/2] = v
+		}
+	}
+
+	for i := len(tmp) - 1; i > 0; i-- {
+		tmp[i] = tmp[i] - tmp[i-1] + 128
+	}
+
+	var out bytes.Buffer
+	z := zlib.NewWriter(&out)
+	if _, err := z.Write(tmp); err != nil {
+		return nil, err
+	}
+	if err := z.Close(); err != nil {
+		return nil, err
+	}
+
+	if out.Len() >= len(raw) {
+		return raw, nil
+	}
+	return out.Bytes(), nil
+}
+
+// halfBits converts f to an IEEE 754 half precision float, rounding to the
+// nearest even, values too large become infinity
+func halfBits(f float32) uint16 {
+	bits := math.Float32bits(f)
+	sign := uint16(bits>>16) & 0x8000
+	exp := int(bits>>23&0xFF) - 127 + 15
+	mantissa := bits & 0x7FFFFF
+
+	if bits&0x7FFFFFFF > 0x7F800000 {
+		return sign | 0x7E00 // NaN
+	}
+
+	// a mantissa shifted right by shift bits, rounded to nearest even
+	round := func(m uint32, shift uint) uint16 {
+		h := uint16(m >> shift)
+		rest, halfway := m&(1<<shift-1), uint32(1)<<(shift-1)
+		if rest > halfway || (rest == halfway && h&1 == 1) {
+			h++
+		}
+		return h
+	}
+
+	switch {
+	case exp >= 0x1F:
+		return sign | 0x7C00
+	case exp <= 0:
+		// subnormal, or too small even for that
+		if exp < -10 {
+			return sign
+		}
+		return sign | round(mantissa|0x800000, uint(14-exp))
+	}
+
+	// a rounding carry out of the mantissa correctly bumps the exponent
+	return sign | round(uint32(exp)<<23|mantissa, 13)
+}
+
+func SaveExr(c image.Image, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := EncodeExr(f, c, nil); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,184 @@
+package scene
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"github.com/seantur/ray_tracer_challenge/raytracing"
+	"io/ioutil"
+	"math"
+	"testing"
+)
+
+// readExr decodes the files EncodeExr writes, returning the pixels as rows of
+// red, green and blue
+func readExr(t *testing.T, data []byte) [][][3]float32 {
+	t.Helper()
+	le := binary.LittleEndian
+
+	if le.Uint32(data) != 20000630 || le.Uint32(data[4:]) != 2 {
+		t.Fatalf("bad magic number or version % x", data[:8])
+	}
+
+	attributes := map[string][]byte{}
+	pos := 8
+	cstring := func() string {
+		end := bytes.IndexByte(data[pos:], 0)
+		s := string(data[pos : pos+end])
+		pos += end + 1
+		return s
+	}
+
+	for {
+		name := cstring()
+		if name == "" {
+			break
+		}
+		cstring()
+		size := int(le.Uint32(data[pos:]))
+		attributes[name] = data[pos+4 : pos+4+size]
+		pos += 4 + size
+	}
+
+	window := attributes["dataWindow"]
+	width, height := int(le.Uint32(window[8:]))+1, int(le.Uint32(window[12:]))+1
+	pixelSize := 2
+	if le.Uint32(attributes["channels"][2:]) == exrFloat {
+		pixelSize = 4
+	}
+	lines := 1
+	if attributes["compression"][0] == exrZip {
+		lines = 16
+	}
+
+	rows := make([][][3]float32, height)
+	for chunk := 0; chunk < (height+lines-1)/lines; chunk++ {
+		offset := le.Uint64(data[pos+8*chunk:])
+		y0 := int(le.Uint32(data[offset:]))
+		size := le.Uint32(data[offset+4:])
+		raw := data[offset+8 : offset+8+uint64(size)]
+
+		count := lines
+		if y0+count > height {
+			count = height - y0
+		}
+
+		if expected := count * width * 3 * pixelSize; len(raw) < expected {
+			z, err := zlib.NewReader(bytes.NewReader(raw))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tmp, err := ioutil.ReadAll(z)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i := 1; i < len(tmp); i++ {
+				tmp[i] = tmp[i-1] + tmp[i] - 128
+			}
+
+			raw = make([]byte, len(tmp))
+			half := (len(tmp) + 1) / 2
+			for i := range raw {
+				if i%2 == 0 {
+					raw[i] = tmp[i/2]
+				} else {
+					raw[i] = tmp[half+i/2]
+				}
+			}
+		}
+
+		for y := y0; y < y0+count; y++ {
+			rows[y] = make([][3]float32, width)
+			for channel := 2; channel >= 0; channel-- {
+				for x := 0; x < width; x++ {
+					if pixelSize == 4 {
+						rows[y][x][channel] = math.Float32frombits(le.Uint32(raw))
+					} else {
+						rows[y][x][channel] = halfFloat(le.Uint16(raw))
+					}
+					raw = raw[pixelSize:]
+				}
+			}
+		}
+	}
+
+	return rows
+}
+
+func halfFloat(h uint16) float32 {
+	sign := float32(1)
+	if h&0x8000 != 0 {
+		sign = -1
+	}
+	exp, mantissa := int(h>>10&0x1F), float64(h&0x3FF)
+
+	switch exp {
+	case 0:
+		return sign * float32(math.Ldexp(mantissa, -24))
+	case 0x1F:
+		return sign * float32(math.Inf(1))
+	}
+	return sign * float32(math.Ldexp(1024+mantissa, exp-25))
+}
+
+func TestExr(t *testing.T) {
+
+	gradient := func(width, height int) *Framebuffer {
+		f := GetFramebuffer(width, height)
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				f.SetRGB(x, y, raytracing.RGB{Red: float64(x) / 4, Green: float64(y) * 8, Blue: 0.5})
+			}
+		}
+		return f
+	}
+
+	t.Run("Half floats round to the nearest even", func(t *testing.T) {
+		for f, want := range map[float32]uint16{
+			1: 0x3C00, -2: 0xC000, 0.1: 0x2E66, 65504: 0x7BFF, 1e6: 0x7C00,
+			float32(math.Ldexp(1, -24)): 0x0001, 1e-10: 0, 1 + 1.0/2048: 0x3C00, 1 + 3.0/2048: 0x3C02,
+		} {
+			if got := halfBits(f); got != want {
+				t.Errorf("halfBits(%v) = %#04x, want %#04x", f, got, want)
+			}
+		}
+	})
+
+	t.Run("Every pixel type and compression round trips", func(t *testing.T) {
+		f := gradient(20, 37)
+
+		for _, o := range []*ExrOptions{nil, {PixelType: ExrFloat}, {Compression: ExrNone}, {PixelType: ExrFloat, Compression: ExrNone}} {
+			var buf bytes.Buffer
+			if err := EncodeExr(&buf, f, o); err != nil {
+				t.Fatal(err)
+			}
+
+			rows := readExr(t, buf.Bytes())
+			if len(rows) != 37 || len(rows[0]) != 20 {
+				t.Fatalf("options %v: got %dx%d pixels", o, len(rows[0]), len(rows))
+			}
+
+			for y, row := range rows {
+				for x, pixel := range row {
+					want := f.RGBAt(x, y)
+					got := raytracing.RGB{Red: float64(pixel[0]), Green: float64(pixel[1]), Blue: float64(pixel[2])}
+					raytracing.AssertColorsEqual(t, got, want)
+				}
+			}
+		}
+	})
+
+	t.Run("ZIP compression makes smooth images smaller", func(t *testing.T) {
+		f := gradient(64, 64)
+
+		var zipped, plain bytes.Buffer
+		EncodeExr(&zipped, f, nil)
+		EncodeExr(&plain, f, &ExrOptions{Compression: ExrNone})
+
+		if zipped.Len() >= plain.Len() {
+			t.Errorf("zipped file is %d bytes, uncompressed is %d", zipped.Len(), plain.Len())
+		}
+	})
+
+}
@@ -0,0 +1,102 @@
+package scene
+
+import (
+	"fmt"
+	"math"
+)
+
+// Filter weights a sample by its offset (x, y) in pixels from the center of
+// the pixel being reconstructed. Samples are only taken within Radius.
+type Filter interface {
+	Radius() float64
+	Weight(x, y float64) float64
+}
+
+// BoxFilter weighs every sample inside the pixel equally
+type BoxFilter struct{}
+
+func (f BoxFilter) Radius() float64 {
+	return 0.5
+}
+
+func (f BoxFilter) Weight(x, y float64) float64 {
+	return 1
+}
+
+// TentFilter falls off linearly to zero at Width pixels from the center
+type TentFilter struct {
+	Width float64
+}
+
+func (f TentFilter) Radius() float64 {
+	return f.Width
+}
+
+func (f TentFilter) Weight(x, y float64) float64 {
+	tent := func(v float64) float64 {
+		return math.Max(0, 1-math.Abs(v)/f.Width)
+	}
+	return tent(x) * tent(y)
+}
+
+// MitchellFilter is the Mitchell-Netravali cubic, B = C = 1/3 is the pair
+// recommended in their paper
+type MitchellFilter struct {
+	Width, B, C float64
+}
+
+func (f MitchellFilter) Radius() float64 {
+	return f.Width
+}
+
+func (f MitchellFilter) mitchell1D(v float64) float64 {
+	// the cubic is defined over [-2, 2]
+	x := math.Abs(2 * v / f.Width)
+	b, c := f.B, f.C
+
+	if x < 1 {
+		return ((12-9*b-6*c)*x*x*x + (-18+12*b+6*c)*x*x + (6 - 2*b)) / 6
+	} else if x < 2 {
+		return ((-b-6*c)*x*x*x + (6*b+30*c)*x*x + (-12*b-48*c)*x + (8*b + 24*c)) / 6
+	}
+
+	return 0
+}
+
+func (f MitchellFilter) Weight(x, y float64) float64 {
+	return f.mitchell1D(x) * f.mitchell1D(y)
+}
+
+// GaussianFilter is a gaussian shifted down so it reaches zero at Width
+type GaussianFilter struct {
+	Width, Alpha float64
+}
+
+func (f GaussianFilter) Radius() float64 {
+	return f.Width
+}
+
+func (f GaussianFilter) Weight(x, y float64) float64 {
+	edge := math.Exp(-f.Alpha * f.Width * f.Width)
+	gaussian := func(v float64) float64 {
+		return math.Max(0, math.Exp(-f.Alpha*v*v)-edge)
+	}
+	return gaussian(x) * gaussian(y)
+}
+
+// GetFilter returns the named filter (box, tent, mitchell or gaussian) with
+// its usual width
+func GetFilter(name string) (Filter, error) {
+	switch name {
+	case "box":
+		return BoxFilter{}, nil
+	case "tent":
+		return TentFilter{Width: 1}, nil
+	case "mitchell":
+		return MitchellFilter{Width: 2, B: 1.0 / 3, C: 1.0 / 3}, nil
+	case "gaussian":
+		return GaussianFilter{Width: 1.5, Alpha: 2}, nil
+	}
+
+	return nil, fmt.Errorf("unknown filter %q", name)
+}
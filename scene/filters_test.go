@@ -0,0 +1,55 @@
+package scene
+
+import (
+	"github.com/seantur/ray_tracer_challenge/datatypes"
+	"testing"
+)
+
+func TestFilters(t *testing.T) {
+
+	t.Run("A box filter weighs every sample equally", func(t *testing.T) {
+		f, _ := GetFilter("box")
+
+		datatypes.AssertVal(t, f.Radius(), 0.5)
+		datatypes.AssertVal(t, f.Weight(0, 0), 1)
+		datatypes.AssertVal(t, f.Weight(0.4, -0.4), 1)
+	})
+
+	t.Run("A tent filter falls off linearly", func(t *testing.T) {
+		f, _ := GetFilter("tent")
+
+		datatypes.AssertVal(t, f.Weight(0, 0), 1)
+		datatypes.AssertVal(t, f.Weight(0.5, 0), 0.5)
+		datatypes.AssertVal(t, f.Weight(0.5, 0.5), 0.25)
+		datatypes.AssertVal(t, f.Weight(1, 0), 0)
+	})
+
+	t.Run("A Mitchell filter peaks at the center and has negative lobes", func(t *testing.T) {
+		f, _ := GetFilter("mitchell")
+
+		// (6 - 2B) / 6 along each axis
+		center := (6 - 2.0/3) / 6
+		datatypes.AssertVal(t, f.Weight(0, 0), center*center)
+		datatypes.AssertVal(t, f.Weight(2, 0), 0)
+
+		if f.Weight(1.5, 0) >= 0 {
+			t.Errorf("expected a negative lobe, got %f", f.Weight(1.5, 0))
+		}
+	})
+
+	t.Run("A gaussian filter reaches zero at its radius", func(t *testing.T) {
+		f, _ := GetFilter("gaussian")
+
+		datatypes.AssertVal(t, f.Weight(f.Radius(), 0), 0)
+
+		if f.Weight(0, 0) <= f.Weight(0.5, 0.5) {
+			t.Error("expected the center to weigh the most")
+		}
+	})
+
+	t.Run("Unknown filters are an error", func(t *testing.T) {
+		if _, err := GetFilter("lanczos"); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
@@ -0,0 +1,85 @@
+package scene
+
+import (
+	"github.com/seantur/ray_tracer_challenge/raytracing"
+	"image"
+	"image/color"
+)
+
+// Framebuffer is an image of float32 RGB pixels. Unlike image.RGBA64 it keeps
+// values outside [0, 1], so highlights survive until the image is written to
+// an HDR format, and are only clipped when read through At().RGBA().
+type Framebuffer struct {
+	// Pix holds the red, green and blue of each pixel, row by row
+	Pix    []float32
+	Stride int // distance in Pix between vertically adjacent pixels
+	Rect   image.Rectangle
+}
+
+func GetFramebuffer(width, height int) *Framebuffer {
+	return &Framebuffer{
+		Pix:    make([]float32, 3*width*height),
+		Stride: 3 * width,
+		Rect:   image.Rect(0, 0, width, height),
+	}
+}
+
+func (f *Framebuffer) ColorModel() color.Model {
+	return color.RGBA64Model
+}
+
+func (f *Framebuffer) Bounds() image.Rectangle {
+	return f.Rect
+}
+
+func (f *Framebuffer) offset(x, y int) int {
+	return (y-f.Rect.Min.Y)*f.Stride + (x-f.Rect.Min.X)*3
+}
+
+// At returns the pixel as a raytracing.RGB, so its full range is available to
+// anything that type checks the color
+func (f *Framebuffer) At(x, y int) color.Color {
+	return f.RGBAt(x, y)
+}
+
+func (f *Framebuffer) RGBAt(x, y int) raytracing.RGB {
+	if !(image.Point{x, y}.In(f.Rect)) {
+		return raytracing.RGB{}
+	}
+
+	i := f.offset(x, y)
+	return raytracing.RGB{Red: float64(f.Pix[i]), Green: float64(f.Pix[i+1]), Blue: float64(f.Pix[i+2])}
+}
+
+func (f *Framebuffer) RGBA64At(x, y int) color.RGBA64 {
+	return f.RGBAt(x, y).Cvt()
+}
+
+func (f *Framebuffer) SetRGB(x, y int, c raytracing.RGB) {
+	if !(image.Point{x, y}.In(f.Rect)) {
+		return
+	}
+
+	i := f.offset(x, y)
+	f.Pix[i], f.Pix[i+1], f.Pix[i+2] = float32(c.Red), float32(c.Green), float32(c.Blue)
+}
+
+// Set stores a raytracing.RGB unchanged, other colors are converted to [0, 1]
+func (f *Framebuffer) Set(x, y int, c color.Color) {
+	f.SetRGB(x, y, rgbOf(c))
+}
+
+func (f *Framebuffer) SetRGBA64(x, y int, c color.RGBA64) {
+	f.Set(x, y, c)
+}
+
+// rgbOf converts any color to a raytracing.RGB without losing the range of
+// one that already is
+func rgbOf(c color.Color) raytracing.RGB {
+	if rgb, ok := c.(raytracing.RGB); ok {
+		return rgb
+	}
+
+	r, g, b, _ := c.RGBA()
+	return raytracing.RGB{Red: float64(r) / 0xFFFF, Green: float64(g) / 0xFFFF, Blue: float64(b) / 0xFFFF}
+}
@@ -0,0 +1,53 @@
+package scene
+
+import (
+	"github.com/seantur/ray_tracer_challenge/datatypes"
+	"github.com/seantur/ray_tracer_challenge/raytracing"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestFramebuffer(t *testing.T) {
+
+	t.Run("A framebuffer keeps colors brighter than white", func(t *testing.T) {
+		f := GetFramebuffer(4, 3)
+		f.Set(1, 2, raytracing.RGB{Red: 12.5, Green: 1, Blue: -0.25})
+
+		raytracing.AssertColorsEqual(t, f.RGBAt(1, 2), raytracing.RGB{Red: 12.5, Green: 1, Blue: -0.25})
+
+		r, g, b, a := f.At(1, 2).RGBA()
+		if r != 0xFFFF || g != 0xFFFF || b != 0 || a != 0xFFFF {
+			t.Errorf("reading through RGBA should clip, got %v %v %v %v", r, g, b, a)
+		}
+	})
+
+	t.Run("Other colors are stored in the unit range", func(t *testing.T) {
+		f := GetFramebuffer(2, 2)
+		f.Set(0, 1, color.Gray{Y: 0xFF})
+
+		raytracing.AssertColorsEqual(t, f.RGBAt(0, 1), raytracing.RGB{Red: 1, Green: 1, Blue: 1})
+	})
+
+	t.Run("Pixels outside the bounds are ignored", func(t *testing.T) {
+		f := GetFramebuffer(2, 2)
+		f.SetRGB(2, 0, raytracing.RGB{Red: 1})
+
+		raytracing.AssertColorsEqual(t, f.RGBAt(2, 0), raytracing.RGB{})
+	})
+
+	t.Run("Renders keep the full range of the shading", func(t *testing.T) {
+		w := GetWorld()
+		w.Lights[0] = PointLight{Position: datatypes.Point(-10, 10, -10), Intensity: raytracing.RGB{Red: 10, Green: 10, Blue: 10}}
+
+		c := GetCamera(11, 11, math.Pi/2)
+		c.Transform = datatypes.ViewTransform(datatypes.Point(0, 0, -5), datatypes.Point(0, 0, 0), datatypes.Vector(0, 1, 0))
+
+		im := c.Render(w).(*Framebuffer)
+
+		if im.RGBAt(5, 5).Red <= 1 {
+			t.Errorf("expected the center pixel to be brighter than white, got %v", im.RGBAt(5, 5))
+		}
+	})
+
+}
@@ -0,0 +1,254 @@
+package scene
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/seantur/ray_tracer_challenge/raytracing"
+	"image"
+	"image/color"
+	"io"
+	"os"
+	"strings"
+)
+
+func init() {
+	image.RegisterFormat("hdr", "#?RADIANCE", decodeHdrImage, DecodeHdrConfig)
+	image.RegisterFormat("hdr", "#?RGBE", decodeHdrImage, DecodeHdrConfig)
+}
+
+// hdrMinRun is the shortest run of equal bytes worth run length encoding
+const hdrMinRun = 4
+
+// The largest image decoded, enough for a 16k x 8k environment map
+const (
+	hdrMaxSide   = 1 << 16
+	hdrMaxPixels = 1 << 27
+)
+
+// EncodeHdr writes m as a Radiance .hdr (RGBE) file, which keeps colors
+// brighter than white. Scanlines are run length encoded when the width allows.
+func EncodeHdr(w io.Writer, m image.Image) error {
+	b := m.Bounds()
+	out := bufio.NewWriter(w)
+
+	fmt.Fprintf(out, "#?RADIANCE\nFORMAT=32-bit_rle_rgbe\n\n-Y %d +X %d\n", b.Dy(), b.Dx())
+
+	width := b.Dx()
+	line := make([][4]byte, width)
+	channel := make([]byte, width)
+
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := range line {
+			line[x] = rgbOf(m.At(b.Min.X+x, y)).RGBE()
+		}
+
+		// the run length format can only describe these widths
+		if width < 8 || width > 0x7FFF {
+			for _, pixel := range line {
+				out.Write(pixel[:])
+			}
+			continue
+		}
+
+		out.Write([]byte{2, 2, byte(width >> 8), byte(width)})
+
+		for i := 0; i < 4; i++ {
+			for x, pixel := range line {
+				channel[x] = pixel[i]
+			}
+			writeHdrRuns(out, channel)
+		}
+	}
+
+	return out.Flush()
+}
+
+// writeHdrRuns encodes one channel of a scanline as runs, a count above 128
+// repeats the next byte count-128 times, otherwise count literal bytes follow
+func writeHdrRuns(out *bufio.Writer, data []byte) {
+	runAt := func(i int) int {
+		n := 1
+		for i+n < len(data) && n < 127 && data[i+n] == data[i] {
+			n++
+		}
+		return n
+	}
+
+	for i := 0; i < len(data); {
+		if run := runAt(i); run >= hdrMinRun {
+			out.Write([]byte{byte(128 + run), data[i]})
+			i += run
+			continue
+		}
+
+		j := i + 1
+		for j < len(data) && j-i < 128 && runAt(j) < hdrMinRun {
+			j++
+		}
+
+		out.WriteByte(byte(j - i))
+		out.Write(data[i:j])
+		i = j
+	}
+}
+
+func SaveHdr(c image.Image, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := EncodeHdr(f, c); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// DecodeHdr reads a Radiance .hdr file into a Framebuffer, keeping colors
+// brighter than white. Scanlines may be flat or run length encoded, only the
+// usual -Y h +X w orientation is supported. The framebuffer grows as scanlines
+// are read, so a file that claims to be bigger than it is fails before much
+// is allocated.
+func DecodeHdr(r io.Reader) (*Framebuffer, error) {
+	in := bufio.NewReader(r)
+
+	width, height, err := readHdrHeader(in)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &Framebuffer{Stride: 3 * width, Rect: image.Rect(0, 0, width, height)}
+	line := make([][4]byte, width)
+
+	for y := 0; y < height; y++ {
+		if err := readHdrScanline(in, line); err != nil {
+			return nil, fmt.Errorf("hdr: scanline %d: %v", y, err)
+		}
+
+		f.Pix = append(f.Pix, make([]float32, f.Stride)...)
+		for x, pixel := range line {
+			f.SetRGB(x, y, raytracing.RGBEColor(pixel))
+		}
+	}
+
+	return f, nil
+}
+
+// DecodeHdrConfig reads only the header of a Radiance .hdr file
+func DecodeHdrConfig(r io.Reader) (image.Config, error) {
+	width, height, err := readHdrHeader(bufio.NewReader(r))
+	if err != nil {
+		return image.Config{}, err
+	}
+
+	return image.Config{ColorModel: color.RGBA64Model, Width: width, Height: height}, nil
+}
+
+func decodeHdrImage(r io.Reader) (image.Image, error) {
+	return DecodeHdr(r)
+}
+
+// readHdrHeader skips the header lines up to the blank line, then reads the
+// resolution line after it
+func readHdrHeader(in *bufio.Reader) (width, height int, err error) {
+	magic, err := in.ReadString('\n')
+	if err != nil || !strings.HasPrefix(magic, "#?") {
+		return 0, 0, errors.New("hdr: not a Radiance file")
+	}
+
+	for {
+		line, err := in.ReadString('\n')
+		if err != nil {
+			return 0, 0, fmt.Errorf("hdr: reading header: %v", err)
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+
+		if strings.HasPrefix(line, "FORMAT=") && line != "FORMAT=32-bit_rle_rgbe" {
+			return 0, 0, fmt.Errorf("hdr: unsupported %s", line)
+		}
+	}
+
+	resolution, err := in.ReadString('\n')
+	if err != nil {
+		return 0, 0, fmt.Errorf("hdr: reading resolution: %v", err)
+	}
+
+	if _, err := fmt.Sscanf(resolution, "-Y %d +X %d", &height, &width); err != nil {
+		return 0, 0, fmt.Errorf("hdr: unsupported resolution %q", strings.TrimSpace(resolution))
+	}
+
+	if width <= 0 || height <= 0 {
+		return 0, 0, fmt.Errorf("hdr: invalid size %dx%d", width, height)
+	}
+
+	if width > hdrMaxSide || height > hdrMaxSide || width*height > hdrMaxPixels {
+		return 0, 0, fmt.Errorf("hdr: image too large at %dx%d", width, height)
+	}
+
+	return width, height, nil
+}
+
+// readHdrScanline reads one scanline into line, which is run length encoded
+// when it starts with 2, 2 and the width
+func readHdrScanline(in *bufio.Reader, line [][4]byte) error {
+	var start [4]byte
+	if _, err := io.ReadFull(in, start[:]); err != nil {
+		return err
+	}
+
+	width := len(line)
+
+	if start[0] != 2 || start[1] != 2 || int(start[2])<<8|int(start[3]) != width || width < 8 || width > 0x7FFF {
+		line[0] = start
+		for x := 1; x < width; x++ {
+			if _, err := io.ReadFull(in, line[x][:]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for i := 0; i < 4; i++ {
+		for x := 0; x < width; {
+			count, err := in.ReadByte()
+			if err != nil {
+				return err
+			}
+
+			if count > 128 {
+				run := int(count) - 128
+				value, err := in.ReadByte()
+				if err != nil {
+					return err
+				}
+				if x+run > width {
+					return errors.New("run overflows the scanline")
+				}
+				for ; run > 0; run-- {
+					line[x][i] = value
+					x++
+				}
+				continue
+			}
+
+			if count == 0 || x+int(count) > width {
+				return errors.New("invalid run length")
+			}
+			for n := 0; n < int(count); n++ {
+				if line[x][i], err = in.ReadByte(); err != nil {
+					return err
+				}
+				x++
+			}
+		}
+	}
+
+	return nil
+}
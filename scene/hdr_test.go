@@ -0,0 +1,135 @@
+package scene
+
+import (
+	"bufio"
+	"bytes"
+	"github.com/seantur/ray_tracer_challenge/raytracing"
+	"image"
+	"strings"
+	"testing"
+)
+
+func TestHdr(t *testing.T) {
+
+	header := "#?RADIANCE\nFORMAT=32-bit_rle_rgbe\n\n"
+
+	t.Run("Narrow images are written as flat RGBE pixels", func(t *testing.T) {
+		f := GetFramebuffer(2, 1)
+		f.SetRGB(0, 0, raytracing.RGB{Red: 1, Green: 0.5, Blue: 0.25})
+		f.SetRGB(1, 0, raytracing.RGB{Red: 4})
+
+		var buf bytes.Buffer
+		if err := EncodeHdr(&buf, f); err != nil {
+			t.Fatal(err)
+		}
+
+		want := header + "-Y 1 +X 2\n" + string([]byte{128, 64, 32, 129, 128, 0, 0, 131})
+		if buf.String() != want {
+			t.Errorf("got %q want %q", buf.String(), want)
+		}
+	})
+
+	t.Run("Wide scanlines are run length encoded a channel at a time", func(t *testing.T) {
+		f := GetFramebuffer(10, 1)
+		for x := 0; x < 10; x++ {
+			f.SetRGB(x, 0, raytracing.RGB{Red: 1, Green: 0.5, Blue: 0.25})
+		}
+
+		var buf bytes.Buffer
+		if err := EncodeHdr(&buf, f); err != nil {
+			t.Fatal(err)
+		}
+
+		body := strings.TrimPrefix(buf.String(), header+"-Y 1 +X 10\n")
+		want := string([]byte{2, 2, 0, 10, 138, 128, 138, 64, 138, 32, 138, 129})
+		if body != want {
+			t.Errorf("got %v want %v", []byte(body), []byte(want))
+		}
+	})
+
+	t.Run("Short runs are written as literal bytes", func(t *testing.T) {
+		var buf bytes.Buffer
+		out := bufio.NewWriter(&buf)
+		writeHdrRuns(out, []byte{1, 2, 3, 5, 5, 5, 5, 5, 9, 9})
+		out.Flush()
+
+		want := []byte{3, 1, 2, 3, 133, 5, 2, 9, 9}
+		if !bytes.Equal(buf.Bytes(), want) {
+			t.Errorf("got %v want %v", buf.Bytes(), want)
+		}
+	})
+	t.Run("Decoding reads back what was encoded", func(t *testing.T) {
+		for _, width := range []int{3, 12} {
+			f := GetFramebuffer(width, 2)
+			for x := 0; x < width; x++ {
+				f.SetRGB(x, 0, raytracing.RGB{Red: 1, Green: 0.5, Blue: 0.25})
+				f.SetRGB(x, 1, raytracing.RGB{Red: float64(x), Green: 2, Blue: 0})
+			}
+
+			var buf bytes.Buffer
+			if err := EncodeHdr(&buf, f); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := DecodeHdr(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got.Bounds() != f.Bounds() {
+				t.Fatalf("got bounds %v want %v", got.Bounds(), f.Bounds())
+			}
+
+			for y := 0; y < 2; y++ {
+				for x := 0; x < width; x++ {
+					raytracing.AssertColorsEqual(t, got.RGBAt(x, y), raytracing.RGBEColor(f.RGBAt(x, y).RGBE()))
+				}
+			}
+		}
+	})
+
+	t.Run("HDR files can be decoded as images", func(t *testing.T) {
+		f := GetFramebuffer(9, 4)
+		f.SetRGB(8, 3, raytracing.RGB{Red: 8, Green: 4, Blue: 2})
+
+		var buf bytes.Buffer
+		if err := EncodeHdr(&buf, f); err != nil {
+			t.Fatal(err)
+		}
+
+		config, format, err := image.DecodeConfig(bytes.NewReader(buf.Bytes()))
+		if err != nil || format != "hdr" || config.Width != 9 || config.Height != 4 {
+			t.Fatalf("got %v %q %v", config, format, err)
+		}
+
+		im, _, err := image.Decode(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := raytracing.RGB{Red: 8, Green: 4, Blue: 2}
+		raytracing.AssertColorsEqual(t, im.(*Framebuffer).RGBAt(8, 3), raytracing.RGBEColor(want.RGBE()))
+	})
+
+	t.Run("Decoding rejects other files", func(t *testing.T) {
+		for _, data := range []string{
+			"P6\n1 1\n255\n",
+			header + "+X 1 -Y 1\n",
+			header + "-Y 1 +X 2\n" + string([]byte{1, 2, 3, 4}),
+			header + "-Y 0 +X 2\n",
+			header + "-Y 100000 +X 100000\n",
+			header + "-Y 1 +X 200000\n",
+			// within the limits, but with one pixel of data
+			header + "-Y 8192 +X 16384\n" + string([]byte{1, 2, 3, 4}),
+		} {
+			if _, err := DecodeHdr(strings.NewReader(data)); err == nil {
+				t.Errorf("expected an error decoding %q", data)
+			}
+		}
+
+		if _, err := DecodeHdrConfig(strings.NewReader(header + "-Y 100000 +X 100000\n")); err == nil {
+			t.Error("expected an error for the size of a huge image")
+		}
+	})
+
+}
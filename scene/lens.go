@@ -0,0 +1,55 @@
+package scene
+
+import (
+	"math"
+)
+
+// lensSample maps uniform random numbers to a point on the unit lens, a disk
+// or a regular polygon with c.Blades sides
+func (c *camera) lensSample(u, v, w float64) (x, y float64) {
+	if c.Blades >= 3 {
+		return samplePolygon(c.Blades, u, v, w)
+	}
+	return sampleDisk(u, v)
+}
+
+// sampleDisk is Shirley's concentric mapping of the unit square onto the unit
+// disk, which keeps stratified samples evenly spread
+func sampleDisk(u, v float64) (x, y float64) {
+	a, b := 2*u-1, 2*v-1
+
+	if a == 0 && b == 0 {
+		return 0, 0
+	}
+
+	var r, theta float64
+
+	if math.Abs(a) > math.Abs(b) {
+		r, theta = a, math.Pi/4*(b/a)
+	} else {
+		r, theta = b, math.Pi/2-math.Pi/4*(a/b)
+	}
+
+	return r * math.Cos(theta), r * math.Sin(theta)
+}
+
+// samplePolygon picks one of the triangles fanning out from the center of a
+// regular polygon inscribed in the unit circle using w, then a uniform point
+// inside it using u and v
+func samplePolygon(sides int, u, v, w float64) (x, y float64) {
+	i := int(w * float64(sides))
+	if i == sides {
+		i--
+	}
+
+	step := 2 * math.Pi / float64(sides)
+	x1, y1 := math.Cos(float64(i)*step), math.Sin(float64(i)*step)
+	x2, y2 := math.Cos(float64(i+1)*step), math.Sin(float64(i+1)*step)
+
+	// fold the unit square onto the triangle's barycentric coordinates
+	if u+v > 1 {
+		u, v = 1-u, 1-v
+	}
+
+	return u*x1 + v*x2, u*y1 + v*y2
+}
@@ -0,0 +1,80 @@
+package scene
+
+import (
+	"github.com/seantur/ray_tracer_challenge/datatypes"
+	"math"
+	"testing"
+)
+
+func TestLens(t *testing.T) {
+
+	t.Run("Disk samples stay inside the unit disk", func(t *testing.T) {
+		for _, uv := range [][2]float64{{0, 0}, {1, 1}, {0.5, 0.5}, {0, 1}, {0.9, 0.2}, {0.25, 0.75}} {
+			x, y := sampleDisk(uv[0], uv[1])
+			if math.Hypot(x, y) > 1+datatypes.EPSILON {
+				t.Errorf("sample (%f, %f) is outside the disk", x, y)
+			}
+		}
+
+		x, y := sampleDisk(0.5, 0.5)
+		datatypes.AssertVal(t, x, 0)
+		datatypes.AssertVal(t, y, 0)
+
+		x, y = sampleDisk(1, 0.5)
+		datatypes.AssertVal(t, x, 1)
+		datatypes.AssertVal(t, y, 0)
+	})
+
+	t.Run("Polygon samples stay inside the polygon", func(t *testing.T) {
+		// a square with corners on the axes
+		for _, uvw := range [][3]float64{{0, 0, 0}, {1, 0, 0.3}, {0.7, 0.9, 0.6}, {0.5, 0.5, 0.99}, {0.2, 0.1, 1}} {
+			x, y := samplePolygon(4, uvw[0], uvw[1], uvw[2])
+			if math.Abs(x)+math.Abs(y) > 1+datatypes.EPSILON {
+				t.Errorf("sample (%f, %f) is outside the square", x, y)
+			}
+		}
+	})
+
+	t.Run("A camera with no aperture is a pinhole", func(t *testing.T) {
+		c := GetCamera(201, 101, math.Pi/2)
+		c.Transform = datatypes.GetTransform(datatypes.GetTranslation(0, -2, 5), datatypes.GetRotationY(math.Pi/4))
+
+		for i := 0; i < 5; i++ {
+			r := c.RayForPixel(0, 0)
+			datatypes.AssertTupleEqual(t, r.Origin, datatypes.Point(0, 2, -5))
+		}
+	})
+
+	t.Run("Lens rays start on the lens and converge on the focal plane", func(t *testing.T) {
+		c := GetCamera(201, 101, math.Pi/2)
+		c.Aperture = 0.5
+		c.FocalDistance = 3
+
+		for i := 0; i < 20; i++ {
+			r := c.RayForPixel(100, 50)
+
+			datatypes.AssertVal(t, r.Origin.Z, 0)
+			if math.Hypot(r.Origin.X, r.Origin.Y) > c.Aperture+datatypes.EPSILON {
+				t.Errorf("ray starts off the lens at %v", r.Origin)
+			}
+
+			focus := r.Position(-3 / r.Direction.Z)
+			datatypes.AssertTupleEqual(t, focus, datatypes.Point(0, 0, -3))
+		}
+	})
+
+	t.Run("Lens rays through an off center pixel meet the pinhole ray", func(t *testing.T) {
+		c := GetCamera(201, 101, math.Pi/2)
+		pinhole := c.RayForPixel(20, 70)
+		want := pinhole.Position(4 / -pinhole.Direction.Z)
+
+		c.Aperture = 1
+		c.FocalDistance = 4
+		c.Blades = 6
+
+		for i := 0; i < 20; i++ {
+			r := c.RayForPixel(20, 70)
+			datatypes.AssertTupleEqual(t, r.Position((-4-r.Origin.Z)/r.Direction.Z), want)
+		}
+	})
+}
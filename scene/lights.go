@@ -5,62 +5,296 @@ import (
 	"github.com/seantur/ray_tracer_challenge/raytracing"
 	"github.com/seantur/ray_tracer_challenge/shapes"
 	"math"
+	"math/rand"
 )
 
+// LightSample is a single point on a light as seen from a surface point
+type LightSample struct {
+	Direction datatypes.Tuple // unit vector from the surface point toward the light
+	Distance  float64         // how far shadow rays travel, +Inf for lights at infinity
+	Intensity raytracing.RGB  // the light arriving at the surface point
+}
+
+// Light is anything that can illuminate a World. Sample returns the samples
+// that shading and shadow rays are averaged over for the given point.
+type Light interface {
+	GetIntensity() raytracing.RGB
+	Sample(point datatypes.Tuple) []LightSample
+}
+
+// sampleTowards builds the sample for a light located at position
+func sampleTowards(position, point datatypes.Tuple, intensity raytracing.RGB) LightSample {
+	v := datatypes.Subtract(position, point)
+	return LightSample{Direction: v.Normalize(), Distance: v.Magnitude(), Intensity: intensity}
+}
+
+// PointLight shines from Position in every direction. With a Radius it is a
+// sphere instead, casting soft shadows from Samples shadow rays spread over
+// the sphere, the samples are rounded up to a square grid so each one can be
+// stratified.
 type PointLight struct {
 	Intensity raytracing.RGB
 	Position  datatypes.Tuple
+	Radius    float64
+	Samples   int
+
+	// Random jitters the samples within their cells, rand.Float64 when nil.
+	// It's called from every render goroutine.
+	Random func() float64
+}
+
+func (l PointLight) GetIntensity() raytracing.RGB {
+	return l.Intensity
 }
 
-func Lighting(material raytracing.Material, shape shapes.Shape, light PointLight, point datatypes.Tuple, eyev datatypes.Tuple, normalv datatypes.Tuple, is_shadow bool) raytracing.RGB {
+func (l PointLight) Sample(point datatypes.Tuple) []LightSample {
+	toLight := datatypes.Subtract(l.Position, point)
+
+	if l.Radius <= 0 || toLight.Magnitude() <= l.Radius {
+		return []LightSample{sampleTowards(l.Position, point, l.Intensity)}
+	}
 
-	var materialColor raytracing.RGB
+	grid := int(math.Ceil(math.Sqrt(float64(l.Samples))))
+	if grid < 1 {
+		grid = 1
+	}
+
+	// from point the sphere looks like a disk facing it, a jittered sample in
+	// each cell of a grid over the disk keeps the shadow's penumbra smooth
+	u, v := perpendicularAxes(toLight.Normalize())
+	samples := make([]LightSample, 0, grid*grid)
+
+	random := l.Random
+	if random == nil {
+		random = rand.Float64
+	}
+
+	for j := 0; j < grid; j++ {
+		for i := 0; i < grid; i++ {
+			x, y := sampleDisk((float64(i)+random())/float64(grid), (float64(j)+random())/float64(grid))
+
+			position := datatypes.Add(l.Position, datatypes.Add(u.Multiply(x*l.Radius), v.Multiply(y*l.Radius)))
+			samples = append(samples, sampleTowards(position, point, l.Intensity))
+		}
+	}
+
+	return samples
+}
 
+// perpendicularAxes returns two unit vectors at right angles to n and each
+// other
+func perpendicularAxes(n datatypes.Tuple) (u, v datatypes.Tuple) {
+	helper := datatypes.Vector(1, 0, 0)
+	if math.Abs(n.X) > 0.9 {
+		helper = datatypes.Vector(0, 1, 0)
+	}
+
+	u = datatypes.Cross(n, helper)
+	u = u.Normalize()
+
+	return u, datatypes.Cross(n, u)
+}
+
+// SpotLight is a point light restricted to a cone around Direction. The light
+// is at full strength within InnerAngle and falls off smoothly to nothing at
+// OuterAngle, both measured in radians from Direction.
+type SpotLight struct {
+	Intensity              raytracing.RGB
+	Position, Direction    datatypes.Tuple
+	InnerAngle, OuterAngle float64
+}
+
+func GetSpotLight(position, direction datatypes.Tuple, inner, outer float64, intensity raytracing.RGB) SpotLight {
+	return SpotLight{
+		Intensity:  intensity,
+		Position:   position,
+		Direction:  direction.Normalize(),
+		InnerAngle: inner,
+		OuterAngle: outer}
+}
+
+func (l SpotLight) GetIntensity() raytracing.RGB {
+	return l.Intensity
+}
+
+// Falloff returns how much of the light reaches point, from 0 to 1
+func (l SpotLight) Falloff(point datatypes.Tuple) float64 {
+	toPoint := datatypes.Subtract(point, l.Position)
+	toPoint = toPoint.Normalize()
+
+	cosAngle := datatypes.Dot(toPoint, l.Direction.Normalize())
+	cosInner := math.Cos(l.InnerAngle)
+	cosOuter := math.Cos(l.OuterAngle)
+
+	if cosAngle >= cosInner {
+		return 1
+	} else if cosAngle <= cosOuter {
+		return 0
+	}
+
+	// smoothstep between the edges of the cone
+	x := (cosAngle - cosOuter) / (cosInner - cosOuter)
+	return x * x * (3 - 2*x)
+}
+
+func (l SpotLight) Sample(point datatypes.Tuple) []LightSample {
+	return []LightSample{sampleTowards(l.Position, point, l.Intensity.Multiply(l.Falloff(point)))}
+}
+
+// DirectionalLight is a light at infinity, such as the sun, shining along
+// Direction
+type DirectionalLight struct {
+	Intensity raytracing.RGB
+	Direction datatypes.Tuple
+}
+
+func (l DirectionalLight) GetIntensity() raytracing.RGB {
+	return l.Intensity
+}
+
+func (l DirectionalLight) Sample(point datatypes.Tuple) []LightSample {
+	towards := l.Direction.Negate()
+
+	return []LightSample{LightSample{
+		Direction: towards.Normalize(),
+		Distance:  math.Inf(1),
+		Intensity: l.Intensity}}
+}
+
+// AreaLight is a rectangle split into USteps x VSteps cells, with one sample
+// taken in each cell
+type AreaLight struct {
+	Intensity      raytracing.RGB
+	Corner         datatypes.Tuple
+	UVec, VVec     datatypes.Tuple // the edge of a single cell
+	USteps, VSteps int
+	Jitter         bool // sample a random point in each cell instead of its center
+
+	// Random picks the jittered points, rand.Float64 when nil. It's called
+	// from every render goroutine.
+	Random func() float64
+}
+
+func GetAreaLight(corner, fullUVec datatypes.Tuple, uSteps int, fullVVec datatypes.Tuple, vSteps int, intensity raytracing.RGB) AreaLight {
+	return AreaLight{
+		Intensity: intensity,
+		Corner:    corner,
+		UVec:      fullUVec.Divide(float64(uSteps)),
+		VVec:      fullVVec.Divide(float64(vSteps)),
+		USteps:    uSteps,
+		VSteps:    vSteps,
+		Jitter:    true}
+}
+
+func (l AreaLight) GetIntensity() raytracing.RGB {
+	return l.Intensity
+}
+
+// Position is the center of the light
+func (l AreaLight) Position() datatypes.Tuple {
+	half := datatypes.Add(l.UVec.Multiply(float64(l.USteps)/2), l.VVec.Multiply(float64(l.VSteps)/2))
+	return datatypes.Add(l.Corner, half)
+}
+
+func (l AreaLight) PointOn(u, v int) datatypes.Tuple {
+	du, dv := 0.5, 0.5
+	if l.Jitter {
+		random := l.Random
+		if random == nil {
+			random = rand.Float64
+		}
+		du, dv = random(), random()
+	}
+
+	return datatypes.Add(l.Corner, datatypes.Add(
+		l.UVec.Multiply(float64(u)+du),
+		l.VVec.Multiply(float64(v)+dv)))
+}
+
+func (l AreaLight) Sample(point datatypes.Tuple) []LightSample {
+	samples := make([]LightSample, 0, l.USteps*l.VSteps)
+
+	for v := 0; v < l.VSteps; v++ {
+		for u := 0; u < l.USteps; u++ {
+			samples = append(samples, sampleTowards(l.PointOn(u, v), point, l.Intensity))
+		}
+	}
+
+	return samples
+}
+
+// Lighting shades point using the Phong model, intensity is the fraction of
+// the light that reaches the point (0 is fully shadowed, 1 is fully lit)
+func Lighting(material raytracing.Material, shape shapes.Shape, light Light, point datatypes.Tuple, eyev datatypes.Tuple, normalv datatypes.Tuple, intensity float64) raytracing.RGB {
+	return LightingFiltered(material, shape, light, point, eyev, normalv, raytracing.RGB{Red: intensity, Green: intensity, Blue: intensity})
+}
+
+// surfaceColor is the material's color at point, from its pattern if it has one
+func surfaceColor(material raytracing.Material, shape shapes.Shape, point datatypes.Tuple) raytracing.RGB {
 	if material.Pattern != nil {
-		materialColor = shapes.AtObj(material.Pattern, shape, point)
-	} else {
-		materialColor = material.RGB
+		return shapes.AtObj(material.Pattern, shape, point)
 	}
+	return material.RGB
+}
 
-	diffuse := raytracing.RGB{}
-	specular := raytracing.RGB{}
+// hitColor is surfaceColor at a hit, so UV patterns can use the shape's own
+// texture coordinates
+func hitColor(material raytracing.Material, c shapes.Computation) raytracing.RGB {
+	if material.Pattern != nil {
+		return shapes.AtHit(material.Pattern, c.Object, c.OverPoint, c.U, c.V)
+	}
+	return material.RGB
+}
 
-	effective_color := raytracing.Hadamard(materialColor, light.Intensity)
+// LightingFiltered is Lighting with the fraction of the light reaching the
+// point given per channel, as when it shines through colored glass
+func LightingFiltered(material raytracing.Material, shape shapes.Shape, light Light, point datatypes.Tuple, eyev datatypes.Tuple, normalv datatypes.Tuple, filter raytracing.RGB) raytracing.RGB {
+	return lighting(material, surfaceColor(material, shape, point), light, point, eyev, normalv, filter)
+}
 
-	lightv := datatypes.Subtract(light.Position, point)
-	lightv = lightv.Normalize()
+// lighting is LightingFiltered with the surface color already looked up
+func lighting(material raytracing.Material, materialColor raytracing.RGB, light Light, point datatypes.Tuple, eyev datatypes.Tuple, normalv datatypes.Tuple, filter raytracing.RGB) raytracing.RGB {
+	effective_color := raytracing.Hadamard(materialColor, light.GetIntensity())
 
 	ambient := effective_color.Multiply(material.Ambient)
 
-	if is_shadow {
+	if filter == (raytracing.RGB{}) {
 		return ambient
 	}
 
-	light_dot_normal := datatypes.Dot(lightv, normalv)
+	samples := light.Sample(point)
+	sum := raytracing.RGB{}
+
+	for _, sample := range samples {
+		lightv := sample.Direction
 
-	if light_dot_normal >= 0 {
-		diffuse = effective_color.Multiply(material.Diffuse)
+		light_dot_normal := datatypes.Dot(lightv, normalv)
+
+		if light_dot_normal < 0 {
+			continue
+		}
+
+		diffuse := raytracing.Hadamard(materialColor, sample.Intensity)
+		diffuse = diffuse.Multiply(material.Diffuse)
 		diffuse = diffuse.Multiply(light_dot_normal)
+		sum = raytracing.Add(sum, diffuse)
 
 		reflectv := lightv.Negate()
 		reflectv = reflectv.Reflect(normalv)
 
 		reflect_dot_eye := datatypes.Dot(reflectv, eyev)
 
-		if reflect_dot_eye <= 0 {
-			specular = raytracing.RGB{}
-		} else {
-
+		if reflect_dot_eye > 0 {
 			factor := math.Pow(reflect_dot_eye, material.Shininess)
 
-			specular = light.Intensity.Multiply(material.Specular)
+			specular := sample.Intensity.Multiply(material.Specular)
 			specular = specular.Multiply(factor)
+			sum = raytracing.Add(sum, specular)
 		}
 	}
 
-	output := raytracing.Add(ambient, diffuse)
-	output = raytracing.Add(output, specular)
-
-	return output
+	sum = raytracing.Hadamard(sum, filter)
+	sum = sum.Multiply(1 / float64(len(samples)))
 
+	return raytracing.Add(ambient, sum)
 }
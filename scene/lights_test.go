@@ -5,6 +5,7 @@ import (
 	"github.com/seantur/ray_tracer_challenge/raytracing"
 	"github.com/seantur/ray_tracer_challenge/shapes"
 	"math"
+	"math/rand"
 	"reflect"
 	"testing"
 )
@@ -35,9 +36,9 @@ func TestLight(t *testing.T) {
 		eyev := datatypes.Vector(0, 0, -1)
 		normalv := datatypes.Vector(0, 0, -1)
 		light := PointLight{Position: datatypes.Point(0, 0, -10), Intensity: raytracing.RGB{Red: 1, Green: 1, Blue: 1}}
-		in_shadow := false
+		intensity := 1.0
 
-		result := Lighting(m, sphere, light, p, eyev, normalv, in_shadow)
+		result := Lighting(m, sphere, light, p, eyev, normalv, intensity)
 
 		raytracing.AssertColorsEqual(t, result, raytracing.RGB{Red: 1.9, Green: 1.9, Blue: 1.9})
 	})
@@ -49,9 +50,9 @@ func TestLight(t *testing.T) {
 		eyev := datatypes.Vector(0, math.Sqrt(2)/2, -math.Sqrt(2)/2)
 		normalv := datatypes.Vector(0, 0, -1)
 		light := PointLight{Position: datatypes.Point(0, 0, -10), Intensity: raytracing.RGB{Red: 1, Green: 1, Blue: 1}}
-		in_shadow := false
+		intensity := 1.0
 
-		result := Lighting(m, sphere, light, p, eyev, normalv, in_shadow)
+		result := Lighting(m, sphere, light, p, eyev, normalv, intensity)
 
 		raytracing.AssertColorsEqual(t, result, raytracing.RGB{Red: 1.0, Green: 1.0, Blue: 1.0})
 	})
@@ -63,9 +64,9 @@ func TestLight(t *testing.T) {
 		eyev := datatypes.Vector(0, 0, -1)
 		normalv := datatypes.Vector(0, 0, -1)
 		light := PointLight{Position: datatypes.Point(0, 10, -10), Intensity: raytracing.RGB{Red: 1, Green: 1, Blue: 1}}
-		in_shadow := false
+		intensity := 1.0
 
-		result := Lighting(m, sphere, light, p, eyev, normalv, in_shadow)
+		result := Lighting(m, sphere, light, p, eyev, normalv, intensity)
 
 		raytracing.AssertColorsEqual(t, result, raytracing.RGB{Red: 0.7364, Green: 0.7364, Blue: 0.7364})
 	})
@@ -77,9 +78,9 @@ func TestLight(t *testing.T) {
 		eyev := datatypes.Vector(0, -math.Sqrt(2)/2, -math.Sqrt(2)/2)
 		normalv := datatypes.Vector(0, 0, -1)
 		light := PointLight{Position: datatypes.Point(0, 10, -10), Intensity: raytracing.RGB{Red: 1, Green: 1, Blue: 1}}
-		in_shadow := false
+		intensity := 1.0
 
-		result := Lighting(m, sphere, light, p, eyev, normalv, in_shadow)
+		result := Lighting(m, sphere, light, p, eyev, normalv, intensity)
 
 		raytracing.AssertColorsEqual(t, result, raytracing.RGB{Red: 1.6364, Green: 1.6364, Blue: 1.6364})
 	})
@@ -91,9 +92,9 @@ func TestLight(t *testing.T) {
 		eyev := datatypes.Vector(0, 0, -1)
 		normalv := datatypes.Vector(0, 0, -1)
 		light := PointLight{Position: datatypes.Point(0, 0, 10), Intensity: raytracing.RGB{Red: 1, Green: 1, Blue: 1}}
-		in_shadow := false
+		intensity := 1.0
 
-		result := Lighting(m, sphere, light, p, eyev, normalv, in_shadow)
+		result := Lighting(m, sphere, light, p, eyev, normalv, intensity)
 
 		raytracing.AssertColorsEqual(t, result, raytracing.RGB{Red: 0.1, Green: 0.1, Blue: 0.1})
 	})
@@ -105,9 +106,9 @@ func TestLight(t *testing.T) {
 		eyev := datatypes.Vector(0, 0, -1)
 		normalv := datatypes.Vector(0, 0, -1)
 		light := PointLight{Position: datatypes.Point(0, 0, -1), Intensity: raytracing.RGB{Red: 1, Green: 1, Blue: 1}}
-		in_shadow := true
+		intensity := 0.0
 
-		result := Lighting(m, sphere, light, p, eyev, normalv, in_shadow)
+		result := Lighting(m, sphere, light, p, eyev, normalv, intensity)
 		raytracing.AssertColorsEqual(t, result, raytracing.RGB{Red: 0.1, Green: 0.1, Blue: 0.1})
 
 	})
@@ -123,11 +124,235 @@ func TestLight(t *testing.T) {
 		normalv := datatypes.Vector(0, 0, -1)
 		light := PointLight{Position: datatypes.Point(0, 0, -10), Intensity: raytracing.RGB{Red: 1, Green: 1, Blue: 1}}
 
-		c1 := Lighting(m, sphere, light, datatypes.Point(0.9, 0, 0), eyev, normalv, false)
-		c2 := Lighting(m, sphere, light, datatypes.Point(1.1, 0, 0), eyev, normalv, false)
+		c1 := Lighting(m, sphere, light, datatypes.Point(0.9, 0, 0), eyev, normalv, 1.0)
+		c2 := Lighting(m, sphere, light, datatypes.Point(1.1, 0, 0), eyev, normalv, 1.0)
 
 		raytracing.AssertColorsEqual(t, c1, raytracing.RGB{Red: 1, Green: 1, Blue: 1})
 		raytracing.AssertColorsEqual(t, c2, raytracing.RGB{Red: 0, Green: 0, Blue: 0})
 	})
 
+	t.Run("Lighting uses light intensity to attenuate color", func(t *testing.T) {
+		m := raytracing.GetMaterial()
+		m.Ambient = 0.1
+		m.Diffuse = 0.9
+		m.Specular = 0
+
+		p := datatypes.Point(0, 0, -1)
+		eyev := datatypes.Vector(0, 0, -1)
+		normalv := datatypes.Vector(0, 0, -1)
+		light := PointLight{Position: datatypes.Point(0, 0, -10), Intensity: raytracing.RGB{Red: 1, Green: 1, Blue: 1}}
+
+		intensities := []float64{1.0, 0.5, 0.0}
+		want := []float64{1.0, 0.55, 0.1}
+
+		for i, intensity := range intensities {
+			result := Lighting(m, sphere, light, p, eyev, normalv, intensity)
+			raytracing.AssertColorsEqual(t, result, raytracing.RGB{Red: want[i], Green: want[i], Blue: want[i]})
+		}
+	})
+
+	t.Run("Creating an area light", func(t *testing.T) {
+		light := GetAreaLight(datatypes.Point(0, 0, 0), datatypes.Vector(2, 0, 0), 4, datatypes.Vector(0, 0, 1), 2, raytracing.RGB{Red: 1, Green: 1, Blue: 1})
+
+		datatypes.AssertTupleEqual(t, light.Corner, datatypes.Point(0, 0, 0))
+		datatypes.AssertTupleEqual(t, light.UVec, datatypes.Vector(0.5, 0, 0))
+		datatypes.AssertVal(t, float64(light.USteps), 4)
+		datatypes.AssertTupleEqual(t, light.VVec, datatypes.Vector(0, 0, 0.5))
+		datatypes.AssertVal(t, float64(light.VSteps), 2)
+		datatypes.AssertVal(t, float64(len(light.Sample(datatypes.Point(0, 0, 0)))), 8)
+		datatypes.AssertTupleEqual(t, light.Position(), datatypes.Point(1, 0, 0.5))
+	})
+
+	t.Run("Finding a single point on an area light", func(t *testing.T) {
+		light := GetAreaLight(datatypes.Point(0, 0, 0), datatypes.Vector(2, 0, 0), 4, datatypes.Vector(0, 0, 1), 2, raytracing.RGB{Red: 1, Green: 1, Blue: 1})
+		light.Jitter = false
+
+		datatypes.AssertTupleEqual(t, light.PointOn(0, 0), datatypes.Point(0.25, 0, 0.25))
+		datatypes.AssertTupleEqual(t, light.PointOn(1, 0), datatypes.Point(0.75, 0, 0.25))
+		datatypes.AssertTupleEqual(t, light.PointOn(0, 1), datatypes.Point(0.25, 0, 0.75))
+		datatypes.AssertTupleEqual(t, light.PointOn(2, 0), datatypes.Point(1.25, 0, 0.25))
+		datatypes.AssertTupleEqual(t, light.PointOn(3, 1), datatypes.Point(1.75, 0, 0.75))
+	})
+
+	t.Run("Jittered points stay inside their cell", func(t *testing.T) {
+		light := GetAreaLight(datatypes.Point(0, 0, 0), datatypes.Vector(2, 0, 0), 4, datatypes.Vector(0, 0, 1), 2, raytracing.RGB{Red: 1, Green: 1, Blue: 1})
+		light.Random = rand.New(rand.NewSource(1)).Float64
+
+		for i := 0; i < 100; i++ {
+			p := light.PointOn(3, 1)
+			if p.X < 1.5 || p.X > 2 || p.Z < 0.5 || p.Z > 1 || p.Y != 0 {
+				t.Fatalf("jittered point %v is outside its cell", p)
+			}
+		}
+	})
+
+	t.Run("An area light jitters with its own random source", func(t *testing.T) {
+		light := GetAreaLight(datatypes.Point(0, 0, 0), datatypes.Vector(2, 0, 0), 4, datatypes.Vector(0, 0, 1), 2, raytracing.RGB{Red: 1, Green: 1, Blue: 1})
+		point := datatypes.Point(1, 5, 0)
+
+		light.Random = rand.New(rand.NewSource(7)).Float64
+		first := light.Sample(point)
+		light.Random = rand.New(rand.NewSource(7)).Float64
+		second := light.Sample(point)
+
+		if !reflect.DeepEqual(first, second) {
+			t.Error("expected the same seed to give the same samples")
+		}
+	})
+
+	t.Run("Lighting samples the area light", func(t *testing.T) {
+		light := GetAreaLight(datatypes.Point(-0.5, -0.5, -5), datatypes.Vector(1, 0, 0), 2, datatypes.Vector(0, 1, 0), 2, raytracing.RGB{Red: 1, Green: 1, Blue: 1})
+		light.Jitter = false
+
+		s := shapes.GetSphere()
+		m := s.GetMaterial()
+		m.Ambient = 0.1
+		m.Diffuse = 0.9
+		m.Specular = 0
+		s.SetMaterial(m)
+
+		eye := datatypes.Point(0, 0, -5)
+		points := []datatypes.Tuple{datatypes.Point(0, 0, -1), datatypes.Point(0, 0.7071, -0.7071)}
+		want := []float64{0.9965, 0.6232}
+
+		for i, p := range points {
+			eyev := datatypes.Subtract(eye, p)
+			eyev = eyev.Normalize()
+			normalv := datatypes.Vector(p.X, p.Y, p.Z)
+
+			result := Lighting(m, s, light, p, eyev, normalv, 1.0)
+
+			if math.Abs(result.Red-want[i]) > 0.0001 || math.Abs(result.Green-want[i]) > 0.0001 || math.Abs(result.Blue-want[i]) > 0.0001 {
+				t.Errorf("got %v want %v", result, want[i])
+			}
+		}
+	})
+
+	t.Run("A spot light is full strength inside its inner cone", func(t *testing.T) {
+		light := GetSpotLight(datatypes.Point(0, 10, 0), datatypes.Vector(0, -1, 0), math.Pi/8, math.Pi/4, raytracing.RGB{Red: 1, Green: 1, Blue: 1})
+
+		datatypes.AssertVal(t, light.Falloff(datatypes.Point(0, 0, 0)), 1)
+		datatypes.AssertVal(t, light.Falloff(datatypes.Point(1, 0, 0)), 1)
+		datatypes.AssertVal(t, light.Falloff(datatypes.Point(20, 0, 0)), 0)
+		datatypes.AssertVal(t, light.Falloff(datatypes.Point(0, 20, 0)), 0)
+	})
+
+	t.Run("A spot light falls off smoothly between its cones", func(t *testing.T) {
+		light := GetSpotLight(datatypes.Point(0, 0, 0), datatypes.Vector(0, 0, 1), 0.2, 0.4, raytracing.RGB{Red: 1, Green: 1, Blue: 1})
+
+		previous := 1.0
+		for angle := 0.2; angle <= 0.4; angle += 0.02 {
+			falloff := light.Falloff(datatypes.Point(math.Sin(angle), 0, math.Cos(angle)))
+			if falloff > previous || falloff < 0 {
+				t.Fatalf("falloff %f at %f is not decreasing", falloff, angle)
+			}
+			previous = falloff
+		}
+
+		samples := light.Sample(datatypes.Point(math.Sin(0.3), 0, math.Cos(0.3)))
+		datatypes.AssertVal(t, float64(len(samples)), 1)
+		if samples[0].Intensity.Red <= 0 || samples[0].Intensity.Red >= 1 {
+			t.Errorf("expected a partial intensity got %f", samples[0].Intensity.Red)
+		}
+	})
+
+	t.Run("Lighting outside a spot light's cone is only ambient", func(t *testing.T) {
+		m := raytracing.GetMaterial()
+		eyev := datatypes.Vector(0, 0, -1)
+		normalv := datatypes.Vector(0, 0, -1)
+
+		light := GetSpotLight(datatypes.Point(0, 0, -10), datatypes.Vector(0, 0, 1), math.Pi/16, math.Pi/8, raytracing.RGB{Red: 1, Green: 1, Blue: 1})
+
+		raytracing.AssertColorsEqual(t, Lighting(m, sphere, light, datatypes.Point(0, 0, 0), eyev, normalv, 1.0), raytracing.RGB{Red: 1.9, Green: 1.9, Blue: 1.9})
+		raytracing.AssertColorsEqual(t, Lighting(m, sphere, light, datatypes.Point(10, 0, 0), eyev, normalv, 1.0), raytracing.RGB{Red: 0.1, Green: 0.1, Blue: 0.1})
+	})
+
+	t.Run("A directional light shines from infinity", func(t *testing.T) {
+		light := DirectionalLight{Direction: datatypes.Vector(0, -2, 0), Intensity: raytracing.RGB{Red: 1, Green: 1, Blue: 1}}
+
+		samples := light.Sample(datatypes.Point(5, 3, 1))
+
+		datatypes.AssertVal(t, float64(len(samples)), 1)
+		datatypes.AssertTupleEqual(t, samples[0].Direction, datatypes.Vector(0, 1, 0))
+		if !math.IsInf(samples[0].Distance, 1) {
+			t.Errorf("expected an infinite distance got %f", samples[0].Distance)
+		}
+	})
+
+	t.Run("Lighting with a directional light", func(t *testing.T) {
+		m := raytracing.GetMaterial()
+		eyev := datatypes.Vector(0, 0, -1)
+		normalv := datatypes.Vector(0, 0, -1)
+		light := DirectionalLight{Direction: datatypes.Vector(0, 0, 1), Intensity: raytracing.RGB{Red: 1, Green: 1, Blue: 1}}
+
+		result := Lighting(m, sphere, light, datatypes.Point(0, 0, 0), eyev, normalv, 1.0)
+
+		raytracing.AssertColorsEqual(t, result, raytracing.RGB{Red: 1.9, Green: 1.9, Blue: 1.9})
+	})
+
+	t.Run("A point light without a radius has one sample", func(t *testing.T) {
+		light := PointLight{Position: datatypes.Point(0, 5, 0), Intensity: raytracing.RGB{Red: 1, Green: 1, Blue: 1}, Samples: 16}
+
+		samples := light.Sample(datatypes.Point(0, 0, 0))
+
+		datatypes.AssertVal(t, float64(len(samples)), 1)
+		datatypes.AssertTupleEqual(t, samples[0].Direction, datatypes.Vector(0, 1, 0))
+		datatypes.AssertVal(t, samples[0].Distance, 5)
+	})
+
+	t.Run("A sphere light spreads its samples over a disk facing the point", func(t *testing.T) {
+		center := datatypes.Point(1, 5, -2)
+		light := PointLight{Position: center, Intensity: raytracing.RGB{Red: 1, Green: 1, Blue: 1}, Radius: 0.5, Samples: 10}
+		point := datatypes.Point(0, 0, 0)
+		toLight := datatypes.Subtract(center, point)
+
+		samples := light.Sample(point)
+
+		// rounded up to a 4x4 grid
+		datatypes.AssertVal(t, float64(len(samples)), 16)
+
+		for _, sample := range samples {
+			onLight := datatypes.Add(point, sample.Direction.Multiply(sample.Distance))
+			offset := datatypes.Subtract(onLight, center)
+
+			if offset.Magnitude() > 0.5+EPSILON {
+				t.Errorf("sample %v is outside the light", onLight)
+			}
+			if math.Abs(datatypes.Dot(offset, toLight)) > EPSILON {
+				t.Errorf("sample %v is not on the disk facing the point", onLight)
+			}
+		}
+	})
+
+	t.Run("A sphere light jitters with its own random source", func(t *testing.T) {
+		light := PointLight{Position: datatypes.Point(0, 5, 0), Intensity: raytracing.RGB{Red: 1, Green: 1, Blue: 1}, Radius: 1, Samples: 4}
+		point := datatypes.Point(0, 0, 0)
+
+		light.Random = rand.New(rand.NewSource(7)).Float64
+		first := light.Sample(point)
+		light.Random = rand.New(rand.NewSource(7)).Float64
+		second := light.Sample(point)
+
+		if !reflect.DeepEqual(first, second) {
+			t.Error("expected the same seed to give the same samples")
+		}
+	})
+
+	t.Run("A point inside a sphere light sees its center", func(t *testing.T) {
+		light := PointLight{Position: datatypes.Point(0, 0, 0), Intensity: raytracing.RGB{Red: 1, Green: 1, Blue: 1}, Radius: 2, Samples: 4}
+
+		datatypes.AssertVal(t, float64(len(light.Sample(datatypes.Point(1, 0, 0)))), 1)
+	})
+
+	t.Run("Lighting through a colored filter only tints the direct light", func(t *testing.T) {
+		m := raytracing.GetMaterial()
+		eyev := datatypes.Vector(0, 0, -1)
+		normalv := datatypes.Vector(0, 0, -1)
+		light := PointLight{Position: datatypes.Point(0, 0, -10), Intensity: raytracing.RGB{Red: 1, Green: 1, Blue: 1}}
+
+		result := LightingFiltered(m, sphere, light, datatypes.Point(0, 0, 0), eyev, normalv, raytracing.RGB{Red: 1, Green: 0.5, Blue: 0})
+
+		raytracing.AssertColorsEqual(t, result, raytracing.RGB{Red: 1.9, Green: 1, Blue: 0.1})
+	})
+
 }
@@ -0,0 +1,102 @@
+package scene
+
+import (
+	"context"
+	"github.com/seantur/ray_tracer_challenge/raytracing"
+	"image"
+	"math/rand"
+)
+
+// PreviewBlockSize is the side of the blocks traced with a single ray by the
+// first progressive pass
+const PreviewBlockSize = 8
+
+// accumulator sums the samples of every progressive pass
+type accumulator struct {
+	width, height int
+	sum           []raytracing.RGB
+	passes        int
+}
+
+func (a *accumulator) add(x, y int, color raytracing.RGB) {
+	i := y*a.width + x
+	a.sum[i] = raytracing.Add(a.sum[i], color)
+}
+
+// image averages the passes so far
+func (a *accumulator) image() *Framebuffer {
+	im := InitCanvas(a.height, a.width)
+
+	for i, sum := range a.sum {
+		im.SetRGB(i%a.width, i/a.width, sum.Multiply(1/float64(a.passes)))
+	}
+
+	return im
+}
+
+// RenderProgressive refines the image over passes, calling onPass with the
+// image after each one. The first pass is a preview tracing one ray for each
+// PreviewBlockSize square block of pixels, every later pass adds one jittered
+// sample per pixel to the average. Rendering ends after passes passes (or
+// never when passes <= 0), when onPass returns false, or when ctx is done, in
+// which case ctx's error is returned. The filter, SamplesPerPixel and
+// adaptive settings do not apply.
+func (c *camera) RenderProgressive(ctx context.Context, w World, passes int, onPass func(pass int, im image.Image) bool) (image.Image, error) {
+	workers := c.workers()
+
+	preview, err := c.renderPreview(ctx, w, workers)
+	if err != nil {
+		return preview, err
+	}
+
+	if !onPass(1, preview) {
+		return preview, nil
+	}
+
+	acc := accumulator{width: c.Hsize, height: c.Vsize, sum: make([]raytracing.RGB, c.Hsize*c.Vsize)}
+	var current image.Image = preview
+
+	for pass := 2; passes <= 0 || pass <= passes; pass++ {
+		err := c.renderTiles(ctx, workers, func(x, y int) {
+			acc.add(x, y, c.colorThrough(w, x, y, rand.Float64(), rand.Float64()))
+		})
+
+		// a partly accumulated pass would leave some pixels brighter
+		if err != nil {
+			return current, err
+		}
+
+		acc.passes++
+		current = acc.image()
+
+		if !onPass(pass, current) {
+			break
+		}
+	}
+
+	return current, nil
+}
+
+// renderPreview traces the center of each PreviewBlockSize block and fills
+// the block with its color
+func (c *camera) renderPreview(ctx context.Context, w World, workers int) (image.Image, error) {
+	im := InitCanvas(c.Vsize, c.Hsize)
+	bounds := im.Bounds()
+
+	err := c.renderTiles(ctx, workers, func(x, y int) {
+		if x%PreviewBlockSize != 0 || y%PreviewBlockSize != 0 {
+			return
+		}
+
+		block := image.Rect(x, y, x+PreviewBlockSize, y+PreviewBlockSize).Intersect(bounds)
+		color := c.colorThrough(w, x, y, float64(block.Dx())/2, float64(block.Dy())/2)
+
+		for by := block.Min.Y; by < block.Max.Y; by++ {
+			for bx := block.Min.X; bx < block.Max.X; bx++ {
+				im.Set(bx, by, color)
+			}
+		}
+	})
+
+	return im, err
+}
@@ -0,0 +1,113 @@
+package scene
+
+import (
+	"context"
+	"github.com/seantur/ray_tracer_challenge/datatypes"
+	"github.com/seantur/ray_tracer_challenge/raytracing"
+	"github.com/seantur/ray_tracer_challenge/shapes"
+	"image"
+	"math"
+	"testing"
+)
+
+func TestProgressive(t *testing.T) {
+
+	viewWorld := func(size int) (World, camera) {
+		c := GetCamera(size, size, math.Pi/2)
+		c.Transform = datatypes.ViewTransform(datatypes.Point(0, 0, -5), datatypes.Point(0, 0, 0), datatypes.Vector(0, 1, 0))
+		return GetWorld(), c
+	}
+
+	t.Run("The first pass is a block preview", func(t *testing.T) {
+		w, c := viewWorld(20)
+
+		passes := 0
+		_, err := c.RenderProgressive(context.Background(), w, 5, func(pass int, im image.Image) bool {
+			passes++
+			datatypes.AssertVal(t, float64(pass), 1)
+
+			// every pixel in a block shares the color traced at its center
+			block := pixelColorAt(im, 8, 8)
+			for y := 8; y < 16; y++ {
+				for x := 8; x < 16; x++ {
+					raytracing.AssertColorsEqual(t, pixelColorAt(im, x, y), block)
+				}
+			}
+
+			raytracing.AssertColorsEqual(t, block, w.ColorAt(c.RayForPixelOffset(8, 8, 4, 4), c.Depth))
+
+			// the bottom right blocks are clipped to 4x4
+			raytracing.AssertColorsEqual(t, pixelColorAt(im, 19, 19), w.ColorAt(c.RayForPixelOffset(16, 16, 2, 2), c.Depth))
+			return false
+		})
+
+		if err != nil {
+			t.Error(err)
+		}
+		datatypes.AssertVal(t, float64(passes), 1)
+	})
+
+	t.Run("Rendering stops after the requested passes", func(t *testing.T) {
+		w, c := viewWorld(8)
+
+		seen := []int{}
+		_, err := c.RenderProgressive(context.Background(), w, 3, func(pass int, im image.Image) bool {
+			seen = append(seen, pass)
+			return true
+		})
+
+		if err != nil {
+			t.Error(err)
+		}
+		if len(seen) != 3 || seen[0] != 1 || seen[2] != 3 {
+			t.Errorf("got passes %v", seen)
+		}
+	})
+
+	t.Run("Passes average into the underlying color", func(t *testing.T) {
+		wall := shapes.GetPlane()
+		wall.SetTransform(datatypes.GetTransform(datatypes.GetRotationX(math.Pi/2), datatypes.GetTranslation(0, 0, -5)))
+		m := wall.GetMaterial()
+		m.RGB = raytracing.RGB{Red: 0.2, Green: 0.4, Blue: 0.6}
+		m.Ambient, m.Diffuse, m.Specular = 1, 0, 0
+		wall.SetMaterial(m)
+
+		w := World{
+			Lights: []Light{PointLight{Position: datatypes.Point(0, 0, 0), Intensity: raytracing.RGB{Red: 1, Green: 1, Blue: 1}}},
+			Shapes: []shapes.Shape{wall}}
+		c := GetCamera(6, 6, math.Pi/2)
+
+		final, err := c.RenderProgressive(context.Background(), w, 4, func(int, image.Image) bool { return true })
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// allow for the image's 16 bit channels
+		got := pixelColorAt(final, 3, 3)
+		if math.Abs(got.Red-0.2) > 1e-4 || math.Abs(got.Green-0.4) > 1e-4 || math.Abs(got.Blue-0.6) > 1e-4 {
+			t.Errorf("got %v, want %v", got, m.RGB)
+		}
+	})
+
+	t.Run("Cancelling keeps the last complete pass", func(t *testing.T) {
+		w, c := viewWorld(8)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		var second image.Image
+
+		final, err := c.RenderProgressive(ctx, w, 0, func(pass int, im image.Image) bool {
+			if pass == 2 {
+				second = im
+				cancel()
+			}
+			return true
+		})
+
+		if err != context.Canceled {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+		if final != second {
+			t.Error("expected the image from the last finished pass")
+		}
+	})
+}
@@ -0,0 +1,78 @@
+package scene
+
+import (
+	"fmt"
+	"github.com/seantur/ray_tracer_challenge/datatypes"
+	"math"
+)
+
+// Projection decides how pixels map to rays leaving the camera
+type Projection int
+
+const (
+	// Perspective is a pinhole (or thin lens) camera covering Fov
+	Perspective Projection = iota
+	// Orthographic shoots parallel rays from a view OrthoWidth units wide
+	Orthographic
+	// Fisheye is an equidistant fisheye, Fov is the angle across the image
+	// circle inscribed in the shorter side of the image
+	Fisheye
+	// Equirectangular covers every direction, longitude across the image and
+	// latitude down it, as used by environment maps
+	Equirectangular
+)
+
+var projectionNames = map[string]Projection{
+	"perspective":     Perspective,
+	"orthographic":    Orthographic,
+	"fisheye":         Fisheye,
+	"equirectangular": Equirectangular,
+}
+
+func GetProjection(name string) (Projection, error) {
+	p, ok := projectionNames[name]
+	if !ok {
+		return Perspective, fmt.Errorf("unknown projection %q", name)
+	}
+	return p, nil
+}
+
+// orthographicRay returns the camera space origin and direction through the
+// image point (x, y), which is measured in the perspective view plane
+func (c *camera) orthographicRay(x, y float64) (origin, direction datatypes.Tuple) {
+	scale := c.OrthoWidth / (2 * c.HalfWidth)
+	return datatypes.Point(x*scale, y*scale, 0), datatypes.Vector(0, 0, -1)
+}
+
+// fisheyeDirection returns the camera space direction through image position
+// (u, v), in pixels, and false outside the image circle
+func (c *camera) fisheyeDirection(u, v float64) (datatypes.Tuple, bool) {
+	radius := math.Min(float64(c.Hsize), float64(c.Vsize)) / 2
+
+	// positive x is to the left of the image, matching the perspective view
+	x := (float64(c.Hsize)/2 - u) / radius
+	y := (float64(c.Vsize)/2 - v) / radius
+	r := math.Hypot(x, y)
+
+	if r > 1 {
+		return datatypes.Tuple{}, false
+	} else if r == 0 {
+		return datatypes.Vector(0, 0, -1), true
+	}
+
+	theta := r * c.Fov / 2
+	sin := math.Sin(theta)
+
+	return datatypes.Vector(sin*x/r, sin*y/r, -math.Cos(theta)), true
+}
+
+// equirectangularDirection returns the camera space direction through image
+// position (u, v), in pixels, the center of the image looks down -z
+func (c *camera) equirectangularDirection(u, v float64) datatypes.Tuple {
+	longitude := (u/float64(c.Hsize) - 0.5) * 2 * math.Pi
+	latitude := (0.5 - v/float64(c.Vsize)) * math.Pi
+
+	cosLat := math.Cos(latitude)
+
+	return datatypes.Vector(-math.Sin(longitude)*cosLat, math.Sin(latitude), -math.Cos(longitude)*cosLat)
+}
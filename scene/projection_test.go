@@ -0,0 +1,98 @@
+package scene
+
+import (
+	"github.com/seantur/ray_tracer_challenge/datatypes"
+	"github.com/seantur/ray_tracer_challenge/raytracing"
+	"math"
+	"testing"
+)
+
+func TestProjection(t *testing.T) {
+
+	t.Run("Looking up projections by name", func(t *testing.T) {
+		p, err := GetProjection("fisheye")
+		if err != nil || p != Fisheye {
+			t.Errorf("got %v, %v", p, err)
+		}
+
+		if _, err := GetProjection("cylindrical"); err == nil {
+			t.Error("expected an error")
+		}
+	})
+
+	t.Run("Orthographic rays are parallel and spread over OrthoWidth", func(t *testing.T) {
+		c := GetCamera(200, 100, math.Pi/2)
+		c.Projection = Orthographic
+		c.OrthoWidth = 10
+
+		left := c.RayForPixelOffset(0, 50, 0, 0)
+		center := c.RayForPixelOffset(100, 50, 0, 0)
+
+		datatypes.AssertTupleEqual(t, left.Direction, datatypes.Vector(0, 0, -1))
+		datatypes.AssertTupleEqual(t, center.Direction, datatypes.Vector(0, 0, -1))
+		datatypes.AssertTupleEqual(t, left.Origin, datatypes.Point(5, 0, 0))
+		datatypes.AssertTupleEqual(t, center.Origin, datatypes.Point(0, 0, 0))
+	})
+
+	t.Run("Orthographic rays follow the camera transform", func(t *testing.T) {
+		c := GetCamera(200, 100, math.Pi/2)
+		c.Projection = Orthographic
+		c.Transform = datatypes.ViewTransform(datatypes.Point(0, 0, -5), datatypes.Point(0, 0, 0), datatypes.Vector(0, 1, 0))
+
+		r := c.RayForPixelOffset(100, 50, 0, 0)
+		datatypes.AssertTupleEqual(t, r.Origin, datatypes.Point(0, 0, -5))
+		datatypes.AssertTupleEqual(t, r.Direction, datatypes.Vector(0, 0, 1))
+	})
+
+	t.Run("The fisheye center looks straight ahead and its edge at half the fov", func(t *testing.T) {
+		c := GetCamera(100, 100, math.Pi)
+		c.Projection = Fisheye
+
+		datatypes.AssertTupleEqual(t, c.RayForPixelOffset(50, 50, 0, 0).Direction, datatypes.Vector(0, 0, -1))
+		datatypes.AssertTupleEqual(t, c.RayForPixelOffset(0, 50, 0, 0).Direction, datatypes.Vector(1, 0, 0))
+		datatypes.AssertTupleEqual(t, c.RayForPixelOffset(50, 0, 0, 0).Direction, datatypes.Vector(0, 1, 0))
+
+		r := c.RayForPixelOffset(75, 50, 0, 0)
+		datatypes.AssertTupleEqual(t, r.Direction, datatypes.Vector(-math.Sqrt(2)/2, 0, -math.Sqrt(2)/2))
+	})
+
+	t.Run("Nothing is seen outside the fisheye image circle", func(t *testing.T) {
+		c := GetCamera(100, 100, math.Pi)
+		c.Projection = Fisheye
+
+		if _, ok := c.cameraRay(0, 0, 0, 0); ok {
+			t.Error("expected the corner to be outside the image circle")
+		}
+
+		w := GetWorld()
+		raytracing.AssertColorsEqual(t, c.PixelColor(w, 0, 0), raytracing.RGB{})
+	})
+
+	t.Run("Equirectangular images cover every direction", func(t *testing.T) {
+		c := GetCamera(400, 200, math.Pi/2)
+		c.Projection = Equirectangular
+
+		datatypes.AssertTupleEqual(t, c.RayForPixelOffset(200, 100, 0, 0).Direction, datatypes.Vector(0, 0, -1))
+		datatypes.AssertTupleEqual(t, c.RayForPixelOffset(100, 100, 0, 0).Direction, datatypes.Vector(1, 0, 0))
+		datatypes.AssertTupleEqual(t, c.RayForPixelOffset(300, 100, 0, 0).Direction, datatypes.Vector(-1, 0, 0))
+		datatypes.AssertTupleEqual(t, c.RayForPixelOffset(0, 100, 0, 0).Direction, datatypes.Vector(0, 0, 1))
+		datatypes.AssertTupleEqual(t, c.RayForPixelOffset(200, 0, 0, 0).Direction, datatypes.Vector(0, 1, 0))
+		datatypes.AssertTupleEqual(t, c.RayForPixelOffset(200, 200, 0, 0).Direction, datatypes.Vector(0, -1, 0))
+	})
+
+	t.Run("Every projection renders through the same path", func(t *testing.T) {
+		w := GetWorld()
+
+		for _, p := range []Projection{Perspective, Orthographic, Fisheye, Equirectangular} {
+			c := GetCamera(11, 11, math.Pi/2)
+			c.Transform = datatypes.ViewTransform(datatypes.Point(0, 0, -5), datatypes.Point(0, 0, 0), datatypes.Vector(0, 1, 0))
+			c.Projection = p
+
+			// the world's spheres are straight ahead
+			raytracing.AssertColorsEqual(t, pixelColorAt(c.Render(w), 5, 5), pixelColorAt(c.RenderConcurrent(w), 5, 5))
+			if pixelColorAt(c.Render(w), 5, 5) == (raytracing.RGB{}) {
+				t.Errorf("projection %v missed the spheres", p)
+			}
+		}
+	})
+}
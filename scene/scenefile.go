@@ -0,0 +1,1416 @@
+package scene
+
+import (
+	"errors"
+	"fmt"
+	"github.com/seantur/ray_tracer_challenge/datatypes"
+	"github.com/seantur/ray_tracer_challenge/raytracing"
+	"github.com/seantur/ray_tracer_challenge/shapes"
+	"gopkg.in/yaml.v3"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// LoadSceneFile reads a YAML scene description, relative paths inside it (such
+// as OBJ files) are resolved against the scene file's directory
+func LoadSceneFile(path string) (World, camera, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return World{}, camera{}, err
+	}
+
+	w, c, err := LoadScene(data, filepath.Dir(path))
+	if err != nil {
+		return World{}, camera{}, fmt.Errorf("%s: %v", path, err)
+	}
+
+	return w, c, nil
+}
+
+// LoadScene builds a World and camera from a YAML scene description. The scene
+// is a list of items, each of which is one of
+//
+//   - add: camera|light|point-light|area-light|spot-light|directional-light
+//   - add: background
+//     type: color|gradient|equirectangular|cube-map
+//   - add: environment-light
+//     file: an equirectangular image, usually .hdr
+//     intensity: 1, samples: 16 (optional)
+//   - add: sphere|plane|cube|cylinder|cone|triangle|smooth-triangle|group|csg|obj
+//   - define: name
+//     extend: other-name (optional, materials only)
+//     value: a material or a list of transforms
+//
+// Transforms are lists of [translate|scale, x, y, z],
+// [rotate-x|rotate-y|rotate-z, radians], [shear, xy, xz, yx, yz, zx, zy] or the
+// name of a defined transform list, applied in the order they are listed. A
+// material on a group, csg or obj is passed down to every shape inside without
+// one of its own. Shapes take the boolean keys cast-shadows, receive-shadows,
+// visible-to-camera, visible-in-reflections and visible-in-refractions, all
+// true by default. Colors are lists of linear [r, g, b] values or quoted sRGB
+// hex strings such as "#ff8000". Patterns have a type of stripes, gradient,
+// rings or checkers with two colors, or image with a file, mapping
+// (planar|spherical|cylindrical|cube), wrap (repeat|clamp|mirror) and filter
+// (bilinear|nearest). The noise patterns fbm, turbulence, marble and wood take
+// two colors, perturb takes a pattern and an amount, and all of them take seed,
+// octaves, lacunarity and gain, marble and wood also frequency and strength. A
+// blend mixes a list of patterns, evenly or by a list of weights, and a mask
+// takes two colors and a mask pattern choosing between them. Any pattern color
+// can be a nested pattern instead. Backgrounds take a color, top and bottom
+// colors, an image file, or six image faces in +x, -x, +y, -y, +z, -z order.
+func LoadScene(data []byte, dir string) (World, camera, error) {
+	var root yaml.Node
+
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return World{}, camera{}, err
+	}
+
+	l := sceneLoader{dir: dir, defines: map[string]*yaml.Node{}, styled: map[shapes.Shape]bool{}}
+
+	if err := l.load(&root); err != nil {
+		return World{}, camera{}, err
+	}
+
+	if l.camera == nil {
+		return World{}, camera{}, errors.New("scene does not add a camera")
+	}
+
+	return l.world, *l.camera, nil
+}
+
+type sceneLoader struct {
+	dir     string
+	defines map[string]*yaml.Node
+	styled  map[shapes.Shape]bool // shapes given a material of their own
+	world   World
+	camera  *camera
+}
+
+type sceneField struct {
+	key, value *yaml.Node
+}
+
+func nodeError(n *yaml.Node, format string, args ...interface{}) error {
+	return fmt.Errorf("line %d, column %d: %s", n.Line, n.Column, fmt.Sprintf(format, args...))
+}
+
+func resolveNode(n *yaml.Node) *yaml.Node {
+	for n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	return n
+}
+
+// mappingFields returns the key/value pairs of a mapping node in file order
+func mappingFields(n *yaml.Node) ([]sceneField, error) {
+	n = resolveNode(n)
+	if n.Kind != yaml.MappingNode {
+		return nil, nodeError(n, "expected a mapping")
+	}
+
+	fields := make([]sceneField, 0, len(n.Content)/2)
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		fields = append(fields, sceneField{n.Content[i], resolveNode(n.Content[i+1])})
+	}
+
+	return fields, nil
+}
+
+func (l *sceneLoader) load(root *yaml.Node) error {
+	if root.Kind == 0 {
+		return errors.New("scene is empty")
+	}
+
+	doc := root
+	if doc.Kind == yaml.DocumentNode {
+		doc = doc.Content[0]
+	}
+	doc = resolveNode(doc)
+
+	if doc.Kind != yaml.SequenceNode {
+		return nodeError(doc, "expected a list of scene items")
+	}
+
+	for _, item := range doc.Content {
+		fields, err := mappingFields(item)
+		if err != nil {
+			return err
+		}
+
+		if len(fields) == 0 {
+			return nodeError(item, "empty scene item")
+		}
+
+		switch fields[0].key.Value {
+		case "add":
+			err = l.add(item, fields)
+		case "define":
+			err = l.define(fields)
+		default:
+			err = nodeError(fields[0].key, "expected \"add\" or \"define\", got %q", fields[0].key.Value)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (l *sceneLoader) define(fields []sceneField) error {
+	name := fields[0].value
+	if name.Kind != yaml.ScalarNode {
+		return nodeError(name, "expected a name to define")
+	}
+
+	var value, extend *yaml.Node
+
+	for _, f := range fields[1:] {
+		switch f.key.Value {
+		case "value":
+			value = f.value
+		case "extend":
+			extend = f.value
+		default:
+			return nodeError(f.key, "unknown define key %q", f.key.Value)
+		}
+	}
+
+	if value == nil {
+		return nodeError(fields[0].key, "define %q has no value", name.Value)
+	}
+
+	if extend != nil {
+		parent, ok := l.defines[extend.Value]
+		if !ok {
+			return nodeError(extend, "undefined name %q", extend.Value)
+		}
+
+		if parent.Kind != yaml.MappingNode || value.Kind != yaml.MappingNode {
+			return nodeError(extend, "only mappings can be extended")
+		}
+
+		// Later keys win when the mapping is applied, so the extension's
+		// values override its parent's
+		merged := *value
+		merged.Content = append(append([]*yaml.Node{}, parent.Content...), value.Content...)
+		value = &merged
+	}
+
+	l.defines[name.Value] = value
+
+	return nil
+}
+
+func (l *sceneLoader) add(item *yaml.Node, fields []sceneField) error {
+	kind := fields[0].value
+
+	switch kind.Value {
+	case "camera":
+		c, err := l.buildCamera(kind, fields[1:])
+		if err != nil {
+			return err
+		}
+		l.camera = &c
+	case "background":
+		b, err := l.buildBackground(kind, fields[1:])
+		if err != nil {
+			return err
+		}
+		l.world.Background = b
+	case "environment-light":
+		env, err := l.buildEnvironmentLight(kind, fields[1:])
+		if err != nil {
+			return err
+		}
+		l.world.Environment = env
+	case "light", "point-light", "area-light", "spot-light", "directional-light":
+		light, err := l.buildLight(kind, fields[1:])
+		if err != nil {
+			return err
+		}
+		l.world.Lights = append(l.world.Lights, light)
+	default:
+		shape, err := l.buildShape(item)
+		if err != nil {
+			return err
+		}
+		l.world.Shapes = append(l.world.Shapes, shape)
+	}
+
+	return nil
+}
+
+func (l *sceneLoader) float(n *yaml.Node) (float64, error) {
+	if n.Kind == yaml.ScalarNode {
+		if val, err := strconv.ParseFloat(n.Value, 64); err == nil {
+			return val, nil
+		}
+	}
+	return 0, nodeError(n, "expected a number")
+}
+
+func (l *sceneLoader) int(n *yaml.Node) (int, error) {
+	if n.Kind == yaml.ScalarNode {
+		if val, err := strconv.Atoi(n.Value); err == nil {
+			return val, nil
+		}
+	}
+	return 0, nodeError(n, "expected an integer")
+}
+
+func (l *sceneLoader) bool(n *yaml.Node) (bool, error) {
+	if n.Kind == yaml.ScalarNode {
+		if val, err := strconv.ParseBool(n.Value); err == nil {
+			return val, nil
+		}
+	}
+	return false, nodeError(n, "expected true or false")
+}
+
+func (l *sceneLoader) floats(n *yaml.Node, count int) ([]float64, error) {
+	if n.Kind != yaml.SequenceNode || len(n.Content) != count {
+		return nil, nodeError(n, "expected a list of %d numbers", count)
+	}
+
+	vals := make([]float64, count)
+	for i, item := range n.Content {
+		val, err := l.float(resolveNode(item))
+		if err != nil {
+			return nil, err
+		}
+		vals[i] = val
+	}
+
+	return vals, nil
+}
+
+func (l *sceneLoader) point(n *yaml.Node) (datatypes.Tuple, error) {
+	vals, err := l.floats(n, 3)
+	if err != nil {
+		return datatypes.Tuple{}, err
+	}
+	return datatypes.Point(vals[0], vals[1], vals[2]), nil
+}
+
+func (l *sceneLoader) vector(n *yaml.Node) (datatypes.Tuple, error) {
+	vals, err := l.floats(n, 3)
+	if err != nil {
+		return datatypes.Tuple{}, err
+	}
+	return datatypes.Vector(vals[0], vals[1], vals[2]), nil
+}
+
+// color reads a list of linear [r, g, b] values, or an sRGB "#rrggbb" string
+// which is converted to linear
+func (l *sceneLoader) color(n *yaml.Node) (raytracing.RGB, error) {
+	if n.Kind == yaml.ScalarNode && strings.HasPrefix(n.Value, "#") {
+		hex, err := strconv.ParseUint(n.Value[1:], 16, 24)
+		if err != nil || len(n.Value) != 7 {
+			return raytracing.RGB{}, nodeError(n, "expected an sRGB color such as \"#ff8000\"")
+		}
+		return raytracing.HexColorSRGB(int(hex)), nil
+	}
+
+	vals, err := l.floats(n, 3)
+	if err != nil {
+		return raytracing.RGB{}, err
+	}
+	return raytracing.RGB{Red: vals[0], Green: vals[1], Blue: vals[2]}, nil
+}
+
+func (l *sceneLoader) buildCamera(kind *yaml.Node, fields []sceneField) (camera, error) {
+	hsize, vsize := 0, 0
+	fov := 0.0
+	from := datatypes.Point(0, 0, 0)
+	to := datatypes.Point(0, 0, -1)
+	up := datatypes.Vector(0, 1, 0)
+	samples := 1
+	jitter := false
+	var filter Filter
+	adaptive := false
+	threshold, adaptiveDepth := -1.0, -1
+	aperture, focalDistance := 0.0, 0.0
+	blades := 0
+	projection, orthoWidth := Perspective, 0.0
+
+	var err error
+
+	for _, f := range fields {
+		switch f.key.Value {
+		case "width":
+			hsize, err = l.int(f.value)
+		case "height":
+			vsize, err = l.int(f.value)
+		case "field-of-view":
+			fov, err = l.float(f.value)
+		case "from":
+			from, err = l.point(f.value)
+		case "to":
+			to, err = l.point(f.value)
+		case "up":
+			up, err = l.vector(f.value)
+		case "samples":
+			samples, err = l.int(f.value)
+		case "jitter":
+			jitter, err = l.bool(f.value)
+		case "filter":
+			if filter, err = GetFilter(f.value.Value); err != nil {
+				err = nodeError(f.value, "%v", err)
+			}
+		case "adaptive":
+			adaptive, err = l.bool(f.value)
+		case "adaptive-threshold":
+			threshold, err = l.float(f.value)
+		case "adaptive-depth":
+			adaptiveDepth, err = l.int(f.value)
+		case "aperture":
+			aperture, err = l.float(f.value)
+		case "focal-distance":
+			focalDistance, err = l.float(f.value)
+		case "blades":
+			blades, err = l.int(f.value)
+		case "projection":
+			if projection, err = GetProjection(f.value.Value); err != nil {
+				err = nodeError(f.value, "%v", err)
+			}
+		case "ortho-width":
+			orthoWidth, err = l.float(f.value)
+		default:
+			err = nodeError(f.key, "unknown camera key %q", f.key.Value)
+		}
+
+		if err != nil {
+			return camera{}, err
+		}
+	}
+
+	if hsize <= 0 || vsize <= 0 || fov <= 0 {
+		return camera{}, nodeError(kind, "camera needs a positive width, height and field-of-view")
+	}
+
+	c := GetCamera(hsize, vsize, fov)
+	c.Transform = datatypes.ViewTransform(from, to, up)
+	c.SamplesPerPixel = samples
+	c.Jitter = jitter
+	c.Filter = filter
+	c.Adaptive = adaptive
+	if threshold >= 0 {
+		c.AdaptiveThreshold = threshold
+	}
+	if adaptiveDepth >= 0 {
+		c.AdaptiveDepth = adaptiveDepth
+	}
+
+	c.Projection = projection
+	if orthoWidth > 0 {
+		c.OrthoWidth = orthoWidth
+	}
+
+	// without a focal distance the point the camera looks at is in focus
+	c.Aperture, c.Blades = aperture, blades
+	if focalDistance > 0 {
+		c.FocalDistance = focalDistance
+	} else {
+		toTarget := datatypes.Subtract(to, from)
+		c.FocalDistance = toTarget.Magnitude()
+	}
+
+	return c, nil
+}
+
+func (l *sceneLoader) buildBackground(kind *yaml.Node, fields []sceneField) (Background, error) {
+	var kindOf, color, top, bottom, file, faces *yaml.Node
+
+	for _, f := range fields {
+		switch f.key.Value {
+		case "type":
+			kindOf = f.value
+		case "color":
+			color = f.value
+		case "top":
+			top = f.value
+		case "bottom":
+			bottom = f.value
+		case "file":
+			file = f.value
+		case "faces":
+			faces = f.value
+		default:
+			return nil, nodeError(f.key, "unknown background key %q", f.key.Value)
+		}
+	}
+
+	if kindOf == nil {
+		return nil, nodeError(kind, "background has no type")
+	}
+
+	switch kindOf.Value {
+	case "color":
+		if color == nil {
+			return nil, nodeError(kind, "color background needs a color")
+		}
+		c, err := l.color(color)
+		if err != nil {
+			return nil, err
+		}
+		return ConstantBackground{Color: c}, nil
+	case "gradient":
+		if top == nil || bottom == nil {
+			return nil, nodeError(kind, "gradient background needs a top and a bottom color")
+		}
+		t, err := l.color(top)
+		if err != nil {
+			return nil, err
+		}
+		b, err := l.color(bottom)
+		if err != nil {
+			return nil, err
+		}
+		return GradientBackground{Bottom: b, Top: t}, nil
+	case "equirectangular":
+		if file == nil {
+			return nil, nodeError(kind, "equirectangular background needs a file")
+		}
+		t, err := l.texture(file)
+		if err != nil {
+			return nil, err
+		}
+		return EquirectBackground{Texture: t}, nil
+	case "cube-map":
+		if faces == nil || faces.Kind != yaml.SequenceNode || len(faces.Content) != 6 {
+			return nil, nodeError(kind, "cube-map background needs a list of six faces")
+		}
+		var b CubeMapBackground
+		for i, face := range faces.Content {
+			t, err := l.texture(resolveNode(face))
+			if err != nil {
+				return nil, err
+			}
+			t.Wrap = raytracing.Clamp
+			b.Faces[i] = t
+		}
+		return b, nil
+	}
+
+	return nil, nodeError(kindOf, "unknown background type %q", kindOf.Value)
+}
+
+func (l *sceneLoader) buildEnvironmentLight(kind *yaml.Node, fields []sceneField) (*EnvironmentLight, error) {
+	var file *yaml.Node
+	intensity, samples := 1.0, 16
+
+	var err error
+
+	for _, f := range fields {
+		switch f.key.Value {
+		case "file":
+			file = f.value
+		case "intensity":
+			intensity, err = l.float(f.value)
+		case "samples":
+			samples, err = l.int(f.value)
+		default:
+			err = nodeError(f.key, "unknown environment-light key %q", f.key.Value)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if file == nil || samples < 1 {
+		return nil, nodeError(kind, "environment light needs a file and at least one sample")
+	}
+
+	t, err := l.texture(file)
+	if err != nil {
+		return nil, err
+	}
+
+	env := GetEnvironmentLight(t, samples)
+	env.Intensity = intensity
+
+	return env, nil
+}
+
+func (l *sceneLoader) buildLight(kind *yaml.Node, fields []sceneField) (Light, error) {
+	intensity := raytracing.RGB{Red: 1, Green: 1, Blue: 1}
+	position := datatypes.Point(0, 0, 0)
+	direction := datatypes.Vector(0, -1, 0)
+	corner := datatypes.Point(0, 0, 0)
+	uvec, vvec := datatypes.Vector(1, 0, 0), datatypes.Vector(0, 0, 1)
+	usteps, vsteps := 1, 1
+	jitter := true
+	inner, outer := 0.0, 0.0
+	radius, samples := 0.0, 16
+
+	var err error
+
+	for _, f := range fields {
+		switch f.key.Value {
+		case "intensity":
+			intensity, err = l.color(f.value)
+		case "at":
+			position, err = l.point(f.value)
+		case "direction":
+			direction, err = l.vector(f.value)
+		case "corner":
+			corner, err = l.point(f.value)
+		case "uvec":
+			uvec, err = l.vector(f.value)
+		case "vvec":
+			vvec, err = l.vector(f.value)
+		case "usteps":
+			usteps, err = l.int(f.value)
+		case "vsteps":
+			vsteps, err = l.int(f.value)
+		case "jitter":
+			jitter, err = l.bool(f.value)
+		case "inner-angle":
+			inner, err = l.float(f.value)
+		case "outer-angle":
+			outer, err = l.float(f.value)
+		case "radius":
+			radius, err = l.float(f.value)
+		case "samples":
+			samples, err = l.int(f.value)
+		default:
+			err = nodeError(f.key, "unknown %s key %q", kind.Value, f.key.Value)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch kind.Value {
+	case "area-light":
+		if usteps < 1 || vsteps < 1 {
+			return nil, nodeError(kind, "area light needs at least one step in u and v")
+		}
+		light := GetAreaLight(corner, uvec, usteps, vvec, vsteps, intensity)
+		light.Jitter = jitter
+		return light, nil
+	case "spot-light":
+		if outer < inner {
+			return nil, nodeError(kind, "spot light outer-angle must not be less than inner-angle")
+		}
+		return GetSpotLight(position, direction, inner, outer, intensity), nil
+	case "directional-light":
+		return DirectionalLight{Direction: direction, Intensity: intensity}, nil
+	}
+
+	if radius < 0 || samples < 1 {
+		return nil, nodeError(kind, "point light needs a radius of at least 0 and at least one sample")
+	}
+
+	return PointLight{Position: position, Intensity: intensity, Radius: radius, Samples: samples}, nil
+}
+
+func (l *sceneLoader) transform(n *yaml.Node) (datatypes.Matrix, error) {
+	matrices, err := l.transformList(n, nil)
+	if err != nil {
+		return datatypes.Matrix{}, err
+	}
+
+	if len(matrices) == 0 {
+		return datatypes.GetIdentity(), nil
+	} else if len(matrices) == 1 {
+		return matrices[0], nil
+	}
+
+	return datatypes.GetTransform(matrices...), nil
+}
+
+// transformList flattens a transform list, expanding defined names, in the
+// order the transforms are applied. expanding is the chain of names being
+// expanded, so a name that refers back to itself is an error.
+func (l *sceneLoader) transformList(n *yaml.Node, expanding []string) ([]datatypes.Matrix, error) {
+	n = resolveNode(n)
+
+	if n.Kind == yaml.ScalarNode {
+		value, ok := l.defines[n.Value]
+		if !ok {
+			return nil, nodeError(n, "undefined transform %q", n.Value)
+		}
+		for i, name := range expanding {
+			if name == n.Value {
+				cycle := append(expanding[i:], n.Value)
+				return nil, nodeError(n, "transform %q refers to itself: %s", n.Value, strings.Join(cycle, " -> "))
+			}
+		}
+		return l.transformList(value, append(expanding, n.Value))
+	}
+
+	if n.Kind != yaml.SequenceNode {
+		return nil, nodeError(n, "expected a list of transforms")
+	}
+
+	matrices := []datatypes.Matrix{}
+
+	for _, item := range n.Content {
+		item = resolveNode(item)
+
+		if item.Kind == yaml.ScalarNode {
+			defined, err := l.transformList(item, expanding)
+			if err != nil {
+				return nil, err
+			}
+			matrices = append(matrices, defined...)
+			continue
+		}
+
+		if item.Kind != yaml.SequenceNode || len(item.Content) == 0 {
+			return nil, nodeError(item, "expected a transform such as [translate, x, y, z]")
+		}
+
+		op := item.Content[0]
+		args := make([]float64, len(item.Content)-1)
+		for i, arg := range item.Content[1:] {
+			val, err := l.float(resolveNode(arg))
+			if err != nil {
+				return nil, err
+			}
+			args[i] = val
+		}
+
+		want := map[string]int{
+			"translate": 3, "scale": 3, "rotate-x": 1, "rotate-y": 1, "rotate-z": 1, "shear": 6}
+
+		count, ok := want[op.Value]
+		if !ok {
+			return nil, nodeError(op, "unknown transform %q", op.Value)
+		}
+		if len(args) != count {
+			return nil, nodeError(item, "%s takes %d arguments, got %d", op.Value, count, len(args))
+		}
+
+		var m datatypes.Matrix
+
+		switch op.Value {
+		case "translate":
+			m = datatypes.GetTranslation(args[0], args[1], args[2])
+		case "scale":
+			m = datatypes.GetScaling(args[0], args[1], args[2])
+		case "rotate-x":
+			m = datatypes.GetRotationX(args[0])
+		case "rotate-y":
+			m = datatypes.GetRotationY(args[0])
+		case "rotate-z":
+			m = datatypes.GetRotationZ(args[0])
+		case "shear":
+			m = datatypes.GetShearing(args[0], args[1], args[2], args[3], args[4], args[5])
+		}
+
+		matrices = append(matrices, m)
+	}
+
+	return matrices, nil
+}
+
+func (l *sceneLoader) material(n *yaml.Node) (raytracing.Material, error) {
+	n = resolveNode(n)
+
+	if n.Kind == yaml.ScalarNode {
+		value, ok := l.defines[n.Value]
+		if !ok {
+			return raytracing.Material{}, nodeError(n, "undefined material %q", n.Value)
+		}
+		n = value
+	}
+
+	fields, err := mappingFields(n)
+	if err != nil {
+		return raytracing.Material{}, err
+	}
+
+	m := raytracing.GetMaterial()
+
+	for _, f := range fields {
+		switch f.key.Value {
+		case "color":
+			m.RGB, err = l.color(f.value)
+		case "ambient":
+			m.Ambient, err = l.float(f.value)
+		case "diffuse":
+			m.Diffuse, err = l.float(f.value)
+		case "specular":
+			m.Specular, err = l.float(f.value)
+		case "shininess":
+			m.Shininess, err = l.float(f.value)
+		case "reflective":
+			m.Reflective, err = l.float(f.value)
+		case "transparency":
+			m.Transparency, err = l.float(f.value)
+		case "refractive-index":
+			m.RefractiveIndex, err = l.float(f.value)
+		case "absorption":
+			m.Absorption, err = l.float(f.value)
+		case "pattern":
+			m.Pattern, err = l.pattern(f.value)
+		default:
+			err = nodeError(f.key, "unknown material key %q", f.key.Value)
+		}
+
+		if err != nil {
+			return raytracing.Material{}, err
+		}
+	}
+
+	return m, nil
+}
+
+func (l *sceneLoader) pattern(n *yaml.Node) (raytracing.Pattern, error) {
+	fields, err := mappingFields(n)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range fields {
+		if f.key.Value != "type" {
+			continue
+		}
+		switch f.value.Value {
+		case "image":
+			return l.imagePattern(f.value, fields)
+		case "fbm", "turbulence", "marble", "wood", "perturb":
+			return l.noisePattern(n, f.value, fields)
+		}
+	}
+
+	var kind, colors, patterns, weights, mask, transform *yaml.Node
+
+	for _, f := range fields {
+		switch f.key.Value {
+		case "type":
+			kind = f.value
+		case "colors":
+			colors = f.value
+		case "patterns":
+			patterns = f.value
+		case "weights":
+			weights = f.value
+		case "mask":
+			mask = f.value
+		case "transform":
+			transform = f.value
+		default:
+			return nil, nodeError(f.key, "unknown pattern key %q", f.key.Value)
+		}
+	}
+
+	if kind == nil {
+		return nil, nodeError(n, "pattern has no type")
+	}
+
+	if (patterns != nil || weights != nil) != (kind.Value == "blend") {
+		return nil, nodeError(n, "only blend patterns take patterns and weights")
+	}
+	if (mask != nil) != (kind.Value == "mask") {
+		return nil, nodeError(n, "mask patterns take a mask, other patterns don't")
+	}
+
+	var p raytracing.Pattern
+
+	if kind.Value == "blend" {
+		if p, err = l.blendPattern(n, patterns, weights); err != nil {
+			return nil, err
+		}
+	} else {
+		a, b, err := l.colorPair(n, colors)
+		if err != nil {
+			return nil, err
+		}
+
+		switch kind.Value {
+		case "stripes":
+			p = raytracing.GetStripeOf(a, b)
+		case "gradient":
+			p = raytracing.GetGradientOf(a, b)
+		case "rings":
+			p = raytracing.GetRingOf(a, b)
+		case "checkers":
+			p = raytracing.GetCheckersOf(a, b)
+		case "mask":
+			m, err := l.slot(mask)
+			if err != nil {
+				return nil, err
+			}
+			p = raytracing.GetMask(a, b, m)
+		default:
+			return nil, nodeError(kind, "unknown pattern type %q", kind.Value)
+		}
+	}
+
+	if transform != nil {
+		m, err := l.transform(transform)
+		if err != nil {
+			return nil, err
+		}
+		p.SetTransform(m)
+	}
+
+	return p, nil
+}
+
+// blendPattern reads the patterns of a blend and their weights, if any
+func (l *sceneLoader) blendPattern(n, patterns, weights *yaml.Node) (raytracing.Pattern, error) {
+	if patterns == nil || patterns.Kind != yaml.SequenceNode || len(patterns.Content) == 0 {
+		return nil, nodeError(n, "blend pattern needs a list of patterns")
+	}
+
+	b := raytracing.GetBlend()
+
+	for _, item := range patterns.Content {
+		p, err := l.slot(item)
+		if err != nil {
+			return nil, err
+		}
+		b.Patterns = append(b.Patterns, p)
+	}
+
+	if weights != nil {
+		var err error
+		if b.Weights, err = l.floats(weights, len(b.Patterns)); err != nil {
+			return nil, err
+		}
+	}
+
+	return b, nil
+}
+
+// slot reads a color, or a nested pattern, for another pattern to use
+func (l *sceneLoader) slot(n *yaml.Node) (raytracing.Pattern, error) {
+	n = resolveNode(n)
+
+	if n.Kind == yaml.MappingNode {
+		return l.pattern(n)
+	}
+
+	c, err := l.color(n)
+	if err != nil {
+		return nil, err
+	}
+	return raytracing.GetSolid(c), nil
+}
+
+// colorPair reads the two colors, or nested patterns, of the pattern n
+func (l *sceneLoader) colorPair(n, colors *yaml.Node) (a, b raytracing.Pattern, err error) {
+	if colors == nil || colors.Kind != yaml.SequenceNode || len(colors.Content) != 2 {
+		return nil, nil, nodeError(n, "pattern needs a list of two colors")
+	}
+
+	if a, err = l.slot(colors.Content[0]); err != nil {
+		return nil, nil, err
+	}
+	b, err = l.slot(colors.Content[1])
+	return a, b, err
+}
+
+// noisePattern builds the patterns made from seeded noise, perturb wraps the
+// pattern under its pattern key instead of taking colors
+func (l *sceneLoader) noisePattern(n, kind *yaml.Node, fields []sceneField) (raytracing.Pattern, error) {
+	fractal := raytracing.GetFractal()
+	var seed int
+	var frequency, strength, amount *float64
+	var colors, inner, transform *yaml.Node
+	var err error
+
+	optional := func(v **float64, value *yaml.Node) error {
+		f, err := l.float(value)
+		*v = &f
+		return err
+	}
+
+	perturb := kind.Value == "perturb"
+	rings := kind.Value == "marble" || kind.Value == "wood"
+
+	for _, f := range fields {
+		switch key := f.key.Value; {
+		case key == "colors" && !perturb:
+			colors = f.value
+		case key == "pattern" && perturb:
+			inner = f.value
+		case key == "amount" && perturb:
+			err = optional(&amount, f.value)
+		case key == "frequency" && rings:
+			err = optional(&frequency, f.value)
+		case key == "strength" && rings:
+			err = optional(&strength, f.value)
+		case key == "type":
+		case key == "seed":
+			seed, err = l.int(f.value)
+		case key == "octaves":
+			fractal.Octaves, err = l.int(f.value)
+		case key == "lacunarity":
+			fractal.Lacunarity, err = l.float(f.value)
+		case key == "gain":
+			fractal.Gain, err = l.float(f.value)
+		case key == "transform":
+			transform = f.value
+		default:
+			err = nodeError(f.key, "unknown %s pattern key %q", kind.Value, key)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if fractal.Octaves < 1 {
+		return nil, nodeError(n, "pattern needs at least one octave")
+	}
+
+	var p raytracing.Pattern
+
+	if perturb {
+		if inner == nil {
+			return nil, nodeError(kind, "perturb pattern needs a pattern")
+		}
+		wrapped, err := l.pattern(inner)
+		if err != nil {
+			return nil, err
+		}
+		perturbed := raytracing.GetPerturb(wrapped, int64(seed))
+		perturbed.Fractal = fractal
+		if amount != nil {
+			perturbed.Amount = *amount
+		}
+		p = perturbed
+	} else {
+		a, b, err := l.colorPair(n, colors)
+		if err != nil {
+			return nil, err
+		}
+
+		switch kind.Value {
+		case "fbm":
+			fbm := raytracing.GetFBmPattern(a, b, int64(seed))
+			fbm.Fractal = fractal
+			p = fbm
+		case "turbulence":
+			turbulence := raytracing.GetTurbulencePattern(a, b, int64(seed))
+			turbulence.Fractal = fractal
+			p = turbulence
+		case "marble":
+			marble := raytracing.GetMarble(a, b, int64(seed))
+			marble.Fractal = fractal
+			if frequency != nil {
+				marble.Frequency = *frequency
+			}
+			if strength != nil {
+				marble.Strength = *strength
+			}
+			p = marble
+		case "wood":
+			wood := raytracing.GetWood(a, b, int64(seed))
+			wood.Fractal = fractal
+			if frequency != nil {
+				wood.Frequency = *frequency
+			}
+			if strength != nil {
+				wood.Strength = *strength
+			}
+			p = wood
+		}
+	}
+
+	if transform != nil {
+		m, err := l.transform(transform)
+		if err != nil {
+			return nil, err
+		}
+		p.SetTransform(m)
+	}
+
+	return p, nil
+}
+
+// path resolves a file name against the scene file's directory
+func (l *sceneLoader) path(n *yaml.Node) string {
+	if filepath.IsAbs(n.Value) {
+		return n.Value
+	}
+	return filepath.Join(l.dir, n.Value)
+}
+
+func (l *sceneLoader) texture(n *yaml.Node) (*raytracing.ImageTexture, error) {
+	t, err := LoadTexture(l.path(n))
+	if err != nil {
+		return nil, nodeError(n, "%v", err)
+	}
+	return t, nil
+}
+
+// imagePattern loads an image texture, the file is relative to the scene
+func (l *sceneLoader) imagePattern(kind *yaml.Node, fields []sceneField) (raytracing.Pattern, error) {
+	var file *yaml.Node
+	var mapping raytracing.UVMapping
+	var wrap raytracing.TextureWrap
+	var nearest bool
+	var transform *yaml.Node
+	var err error
+
+	for _, f := range fields {
+		switch f.key.Value {
+		case "type":
+		case "file":
+			file = f.value
+		case "mapping":
+			mapping, err = raytracing.GetUVMapping(f.value.Value)
+		case "wrap":
+			wrap, err = raytracing.GetTextureWrap(f.value.Value)
+		case "filter":
+			switch f.value.Value {
+			case "bilinear":
+				nearest = false
+			case "nearest":
+				nearest = true
+			default:
+				err = fmt.Errorf("unknown filter %q", f.value.Value)
+			}
+		case "transform":
+			transform = f.value
+		default:
+			return nil, nodeError(f.key, "unknown image pattern key %q", f.key.Value)
+		}
+
+		if err != nil {
+			return nil, nodeError(f.value, "%v", err)
+		}
+	}
+
+	if file == nil {
+		return nil, nodeError(kind, "image pattern needs a file")
+	}
+
+	t, err := l.texture(file)
+	if err != nil {
+		return nil, err
+	}
+
+	t.Mapping, t.Wrap, t.Nearest = mapping, wrap, nearest
+
+	if transform != nil {
+		m, err := l.transform(transform)
+		if err != nil {
+			return nil, err
+		}
+		t.SetTransform(m)
+	}
+
+	return t, nil
+}
+
+// buildShape builds the shape described by an "add" mapping
+func (l *sceneLoader) buildShape(n *yaml.Node) (shapes.Shape, error) {
+	fields, err := mappingFields(n)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(fields) == 0 || fields[0].key.Value != "add" {
+		return nil, nodeError(n, "expected a shape starting with \"add\"")
+	}
+
+	kind := fields[0].value
+	fields = fields[1:]
+
+	var shape shapes.Shape
+
+	// Settings shared by every shape are applied once the shape is built
+	common := []sceneField{}
+	visibility := []sceneField{}
+	specific := []sceneField{}
+	for _, f := range fields {
+		switch f.key.Value {
+		case "material", "transform":
+			common = append(common, f)
+		case "cast-shadows", "receive-shadows", "visible-to-camera", "visible-in-reflections", "visible-in-refractions":
+			visibility = append(visibility, f)
+		default:
+			specific = append(specific, f)
+		}
+	}
+
+	switch kind.Value {
+	case "sphere":
+		shape, err = shapes.GetSphere(), l.noFields(kind, specific)
+	case "plane":
+		shape, err = shapes.GetPlane(), l.noFields(kind, specific)
+	case "cube":
+		shape, err = shapes.GetCube(), l.noFields(kind, specific)
+	case "cylinder":
+		cyl := shapes.GetCylinder()
+		shape = cyl
+		err = l.truncated(kind, specific, &cyl.Min, &cyl.Max, &cyl.Closed)
+	case "cone":
+		cone := shapes.GetCone()
+		shape = cone
+		err = l.truncated(kind, specific, &cone.Min, &cone.Max, &cone.Closed)
+	case "triangle", "smooth-triangle":
+		shape, err = l.buildTriangle(kind, specific)
+	case "group":
+		shape, err = l.buildGroup(kind, specific)
+	case "csg":
+		shape, err = l.buildCSG(kind, specific)
+	case "obj":
+		shape, err = l.buildObj(kind, specific)
+	default:
+		return nil, nodeError(kind, "unknown item %q", kind.Value)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range common {
+		switch f.key.Value {
+		case "material":
+			m, err := l.material(f.value)
+			if err != nil {
+				return nil, err
+			}
+			l.setMaterial(shape, m)
+			l.styled[shape] = true
+		case "transform":
+			m, err := l.transform(f.value)
+			if err != nil {
+				return nil, err
+			}
+			shape.SetTransform(m)
+		}
+	}
+
+	v := shape.GetVisibility()
+	for _, f := range visibility {
+		on, err := l.bool(f.value)
+		if err != nil {
+			return nil, err
+		}
+
+		switch f.key.Value {
+		case "cast-shadows":
+			v.NoShadow = !on
+		case "receive-shadows":
+			v.NoReceiveShadow = !on
+		case "visible-to-camera":
+			v.HiddenFromCamera = !on
+		case "visible-in-reflections":
+			v.HiddenFromReflection = !on
+		case "visible-in-refractions":
+			v.HiddenFromRefraction = !on
+		}
+	}
+	shape.SetVisibility(v)
+
+	return shape, nil
+}
+
+// setMaterial sets m on s and on every shape inside it that wasn't given a
+// material of its own
+func (l *sceneLoader) setMaterial(s shapes.Shape, m raytracing.Material) {
+	s.SetMaterial(m)
+
+	var children []shapes.Shape
+
+	switch s := s.(type) {
+	case *shapes.Group:
+		children = s.Shapes
+	case *shapes.CSG:
+		children = []shapes.Shape{s.Left, s.Right}
+	}
+
+	for _, child := range children {
+		if !l.styled[child] {
+			l.setMaterial(child, m)
+		}
+	}
+}
+
+func (l *sceneLoader) noFields(kind *yaml.Node, fields []sceneField) error {
+	if len(fields) > 0 {
+		return nodeError(fields[0].key, "unknown %s key %q", kind.Value, fields[0].key.Value)
+	}
+	return nil
+}
+
+func (l *sceneLoader) truncated(kind *yaml.Node, fields []sceneField, min, max *float64, closed *bool) (err error) {
+	for _, f := range fields {
+		switch f.key.Value {
+		case "min":
+			*min, err = l.float(f.value)
+		case "max":
+			*max, err = l.float(f.value)
+		case "closed":
+			*closed, err = l.bool(f.value)
+		default:
+			err = nodeError(f.key, "unknown %s key %q", kind.Value, f.key.Value)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (l *sceneLoader) buildTriangle(kind *yaml.Node, fields []sceneField) (shapes.Shape, error) {
+	points := map[string]*datatypes.Tuple{}
+	names := []string{"p1", "p2", "p3"}
+	if kind.Value == "smooth-triangle" {
+		names = append(names, "n1", "n2", "n3")
+	}
+	for _, name := range names {
+		points[name] = nil
+	}
+
+	for _, f := range fields {
+		if _, ok := points[f.key.Value]; !ok {
+			return nil, nodeError(f.key, "unknown %s key %q", kind.Value, f.key.Value)
+		}
+
+		var t datatypes.Tuple
+		var err error
+
+		if f.key.Value[0] == 'n' {
+			t, err = l.vector(f.value)
+		} else {
+			t, err = l.point(f.value)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+		points[f.key.Value] = &t
+	}
+
+	for _, name := range names {
+		if points[name] == nil {
+			return nil, nodeError(kind, "%s is missing %s", kind.Value, name)
+		}
+	}
+
+	if kind.Value == "smooth-triangle" {
+		return shapes.GetSmoothTriangle(*points["p1"], *points["p2"], *points["p3"],
+			*points["n1"], *points["n2"], *points["n3"]), nil
+	}
+
+	return shapes.GetTriangle(*points["p1"], *points["p2"], *points["p3"]), nil
+}
+
+func (l *sceneLoader) buildGroup(kind *yaml.Node, fields []sceneField) (shapes.Shape, error) {
+	g := shapes.GetGroup()
+	threshold := 0
+
+	for _, f := range fields {
+		switch f.key.Value {
+		case "children":
+			if f.value.Kind != yaml.SequenceNode {
+				return nil, nodeError(f.value, "expected a list of shapes")
+			}
+
+			for _, child := range f.value.Content {
+				s, err := l.buildShape(child)
+				if err != nil {
+					return nil, err
+				}
+				g.AddChild(s)
+			}
+		case "divide":
+			var err error
+			if threshold, err = l.int(f.value); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, nodeError(f.key, "unknown group key %q", f.key.Value)
+		}
+	}
+
+	if threshold > 0 {
+		g.Divide(threshold)
+	}
+
+	return g, nil
+}
+
+func (l *sceneLoader) buildCSG(kind *yaml.Node, fields []sceneField) (shapes.Shape, error) {
+	var op shapes.CSGOperation
+	var left, right shapes.Shape
+	hasOp := false
+
+	for _, f := range fields {
+		var err error
+
+		switch f.key.Value {
+		case "operation":
+			hasOp = true
+			switch f.value.Value {
+			case "union":
+				op = shapes.CSGUnion
+			case "intersection":
+				op = shapes.CSGIntersect
+			case "difference":
+				op = shapes.CSGDifference
+			default:
+				err = nodeError(f.value, "unknown csg operation %q", f.value.Value)
+			}
+		case "left":
+			left, err = l.buildShape(f.value)
+		case "right":
+			right, err = l.buildShape(f.value)
+		default:
+			err = nodeError(f.key, "unknown csg key %q", f.key.Value)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !hasOp || left == nil || right == nil {
+		return nil, nodeError(kind, "csg needs an operation, left and right")
+	}
+
+	return shapes.GetCSG(op, left, right), nil
+}
+
+func (l *sceneLoader) buildObj(kind *yaml.Node, fields []sceneField) (shapes.Shape, error) {
+	var file *yaml.Node
+	threshold := 0
+
+	for _, f := range fields {
+		switch f.key.Value {
+		case "file":
+			file = f.value
+		case "divide":
+			var err error
+			if threshold, err = l.int(f.value); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, nodeError(f.key, "unknown obj key %q", f.key.Value)
+		}
+	}
+
+	if file == nil {
+		return nil, nodeError(kind, "obj needs a file")
+	}
+
+	parser, err := shapes.ParseObjFile(l.path(file))
+	if err != nil {
+		return nil, nodeError(file, "%v", err)
+	}
+
+	g := parser.ToGroup()
+	if threshold > 0 {
+		g.Divide(threshold)
+	}
+
+	return g, nil
+}
@@ -0,0 +1,624 @@
+package scene
+
+import (
+	"github.com/seantur/ray_tracer_challenge/datatypes"
+	"github.com/seantur/ray_tracer_challenge/raytracing"
+	"github.com/seantur/ray_tracer_challenge/shapes"
+	"image"
+	"image/color"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSceneFile(t *testing.T) {
+
+	t.Run("Loading a camera", func(t *testing.T) {
+		w, c, err := LoadScene([]byte(`
+- add: camera
+  width: 100
+  height: 50
+  field-of-view: 0.785
+  samples: 16
+  jitter: true
+  filter: mitchell
+  aperture: 0.1
+  blades: 5
+  from: [0, 1.5, -5]
+  to: [0, 1, 0]
+  up: [0, 1, 0]
+`), ".")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		datatypes.AssertVal(t, float64(c.Hsize), 100)
+		datatypes.AssertVal(t, float64(c.Vsize), 50)
+		datatypes.AssertVal(t, c.Fov, 0.785)
+		datatypes.AssertVal(t, float64(c.SamplesPerPixel), 16)
+		datatypes.AssertVal(t, c.Aperture, 0.1)
+		datatypes.AssertVal(t, float64(c.Blades), 5)
+		if _, ok := c.Filter.(MitchellFilter); !ok || !c.Jitter {
+			t.Errorf("expected a jittered Mitchell filter, got %T", c.Filter)
+		}
+
+		want := datatypes.ViewTransform(datatypes.Point(0, 1.5, -5), datatypes.Point(0, 1, 0), datatypes.Vector(0, 1, 0))
+		datatypes.AssertMatrixEqual(t, c.Transform, want)
+
+		toTarget := datatypes.Subtract(datatypes.Point(0, 1, 0), datatypes.Point(0, 1.5, -5))
+		datatypes.AssertVal(t, c.FocalDistance, toTarget.Magnitude())
+
+		if len(w.Lights) != 0 || len(w.Shapes) != 0 {
+			t.Errorf("expected an empty world, got %d lights and %d shapes", len(w.Lights), len(w.Shapes))
+		}
+	})
+
+	t.Run("Loading lights", func(t *testing.T) {
+		w, _, err := LoadScene([]byte(`
+- add: camera
+  width: 10
+  height: 10
+  field-of-view: 1
+- add: light
+  at: [-10, 10, -10]
+  intensity: [1, 1, 1]
+- add: area-light
+  corner: [-1, 2, 4]
+  uvec: [2, 0, 0]
+  usteps: 4
+  vvec: [0, 2, 0]
+  vsteps: 2
+  jitter: false
+  intensity: [1.5, 1.5, 1.5]
+- add: spot-light
+  at: [0, 5, 0]
+  direction: [0, -1, 0]
+  inner-angle: 0.2
+  outer-angle: 0.4
+- add: directional-light
+  direction: [0, -1, 0]
+  intensity: [0.5, 0.5, 0.5]
+- add: point-light
+  at: [0, 3, 0]
+  radius: 0.5
+  samples: 9
+`), ".")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(w.Lights) != 5 {
+			t.Fatalf("expected 5 lights, got %d", len(w.Lights))
+		}
+
+		point := w.Lights[0].(PointLight)
+		datatypes.AssertTupleEqual(t, point.Position, datatypes.Point(-10, 10, -10))
+
+		area := w.Lights[1].(AreaLight)
+		datatypes.AssertTupleEqual(t, area.UVec, datatypes.Vector(0.5, 0, 0))
+		datatypes.AssertVal(t, float64(area.VSteps), 2)
+		if area.Jitter {
+			t.Error("expected jitter to be disabled")
+		}
+
+		spot := w.Lights[2].(SpotLight)
+		datatypes.AssertVal(t, spot.OuterAngle, 0.4)
+
+		directional := w.Lights[3].(DirectionalLight)
+		raytracing.AssertColorsEqual(t, directional.Intensity, raytracing.RGB{Red: 0.5, Green: 0.5, Blue: 0.5})
+
+		sphere := w.Lights[4].(PointLight)
+		datatypes.AssertVal(t, sphere.Radius, 0.5)
+		datatypes.AssertVal(t, float64(sphere.Samples), 9)
+	})
+
+	t.Run("Defined materials can be extended", func(t *testing.T) {
+		w, _, err := LoadScene([]byte(`
+- add: camera
+  width: 10
+  height: 10
+  field-of-view: 1
+- define: white-material
+  value:
+    color: [1, 1, 1]
+    diffuse: 0.7
+    ambient: 0.1
+- define: blue-material
+  extend: white-material
+  value:
+    color: [0.537, 0.831, 0.914]
+- add: sphere
+  material: blue-material
+`), ".")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		m := w.Shapes[0].GetMaterial()
+		raytracing.AssertColorsEqual(t, m.RGB, raytracing.RGB{Red: 0.537, Green: 0.831, Blue: 0.914})
+		datatypes.AssertVal(t, m.Diffuse, 0.7)
+		datatypes.AssertVal(t, m.Ambient, 0.1)
+		datatypes.AssertVal(t, m.Specular, raytracing.GetMaterial().Specular)
+	})
+
+	t.Run("Hex colors are read as sRGB and absorption is loaded", func(t *testing.T) {
+		w, _, err := LoadScene([]byte(`
+- add: camera
+  width: 10
+  height: 10
+  field-of-view: 1
+- add: sphere
+  material:
+    color: "#ff8000"
+    absorption: 0.5
+`), ".")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		raytracing.AssertColorsEqual(t, w.Shapes[0].GetMaterial().RGB, raytracing.HexColorSRGB(0xFF8000))
+		datatypes.AssertVal(t, w.Shapes[0].GetMaterial().Absorption, 0.5)
+	})
+
+	t.Run("Shapes can opt out of shadows and kinds of rays", func(t *testing.T) {
+		w, _, err := LoadScene([]byte(`
+- add: camera
+  width: 10
+  height: 10
+  field-of-view: 1
+- define: red
+  value:
+    color: [1, 0, 0]
+- add: cube
+  cast-shadows: false
+  visible-in-reflections: false
+  material: red
+- add: sphere
+  material: red
+  receive-shadows: false
+  visible-to-camera: false
+  visible-in-refractions: false
+`), ".")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		room, ball := w.Shapes[0].GetVisibility(), w.Shapes[1].GetVisibility()
+
+		if !room.NoShadow || !room.HiddenFromReflection || room.NoReceiveShadow || room.HiddenFromCamera {
+			t.Errorf("got room flags %+v", room)
+		}
+		if !ball.NoReceiveShadow || !ball.HiddenFromCamera || !ball.HiddenFromRefraction || ball.NoShadow {
+			t.Errorf("got ball flags %+v", ball)
+		}
+	})
+
+	t.Run("Transforms are applied in the order they are listed", func(t *testing.T) {
+		w, _, err := LoadScene([]byte(`
+- add: camera
+  width: 10
+  height: 10
+  field-of-view: 1
+- define: standard-transform
+  value:
+    - [translate, 1, -1, 1]
+    - [scale, 0.5, 0.5, 0.5]
+- add: cube
+  transform:
+    - standard-transform
+    - [rotate-y, 1.5707963267948966]
+    - [shear, 1, 0, 0, 0, 0, 0]
+`), ".")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := datatypes.GetTransform(
+			datatypes.GetTranslation(1, -1, 1),
+			datatypes.GetScaling(0.5, 0.5, 0.5),
+			datatypes.GetRotationY(math.Pi/2),
+			datatypes.GetShearing(1, 0, 0, 0, 0, 0))
+
+		datatypes.AssertMatrixEqual(t, w.Shapes[0].GetTransform(), want)
+	})
+
+	t.Run("Loading patterns and every shape type", func(t *testing.T) {
+		w, _, err := LoadScene([]byte(`
+- add: camera
+  width: 10
+  height: 10
+  field-of-view: 1
+- add: plane
+  material:
+    pattern:
+      type: checkers
+      colors: [[1, 1, 1], [0, 0, 0]]
+      transform:
+        - [scale, 0.25, 0.25, 0.25]
+    reflective: 0.5
+- add: group
+  transform:
+    - [translate, 0, 1, 0]
+  material:
+    color: [0, 0, 1]
+  children:
+    - add: cylinder
+      min: 0
+      max: 2
+      closed: true
+    - add: cone
+      min: -1
+      max: 0
+      material:
+        color: [1, 0, 0]
+    - add: group
+      children:
+        - add: triangle
+          p1: [0, 1, 0]
+          p2: [-1, 0, 0]
+          p3: [1, 0, 0]
+        - add: smooth-triangle
+          p1: [0, 1, 0]
+          p2: [-1, 0, 0]
+          p3: [1, 0, 0]
+          n1: [0, 1, 0]
+          n2: [-1, 0, 0]
+          n3: [1, 0, 0]
+- add: csg
+  operation: difference
+  left:
+    add: cube
+  right:
+    add: sphere
+    transform:
+      - [scale, 1.5, 1.5, 1.5]
+`), ".")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(w.Shapes) != 3 {
+			t.Fatalf("expected 3 shapes, got %d", len(w.Shapes))
+		}
+
+		plane := w.Shapes[0].GetMaterial()
+		datatypes.AssertVal(t, plane.Reflective, 0.5)
+		raytracing.AssertColorsEqual(t, plane.Pattern.At(datatypes.Point(1.3, 0, 0)), raytracing.HexColor(raytracing.Black))
+
+		g := w.Shapes[1].(*shapes.Group)
+		cyl := g.Shapes[0].(*shapes.Cylinder)
+		datatypes.AssertVal(t, cyl.Max, 2)
+		if !cyl.Closed {
+			t.Error("expected the cylinder to be closed")
+		}
+
+		if cyl.GetParent() != g {
+			t.Error("expected children to have the group as their parent")
+		}
+
+		inner := g.Shapes[2].(*shapes.Group)
+		if _, ok := inner.Shapes[1].(*shapes.SmoothTriangle); !ok {
+			t.Errorf("expected a smooth triangle, got %T", inner.Shapes[1])
+		}
+
+		// the group's material reaches every child without one of its own
+		blue, red := raytracing.RGB{Red: 0, Green: 0, Blue: 1}, raytracing.RGB{Red: 1, Green: 0, Blue: 0}
+		raytracing.AssertColorsEqual(t, cyl.GetMaterial().RGB, blue)
+		raytracing.AssertColorsEqual(t, g.Shapes[1].GetMaterial().RGB, red)
+		raytracing.AssertColorsEqual(t, inner.Shapes[1].GetMaterial().RGB, blue)
+
+		csg := w.Shapes[2].(*shapes.CSG)
+		if csg.Operation != shapes.CSGDifference {
+			t.Errorf("expected a difference, got %v", csg.Operation)
+		}
+	})
+
+	t.Run("Loading an OBJ file relative to the scene file", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "scene")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		obj := "v -1 1 0\nv -1 0 0\nv 1 0 0\nv 1 1 0\nf 1 2 3 4\n"
+		if err := ioutil.WriteFile(filepath.Join(dir, "quad.obj"), []byte(obj), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		scene := "- add: camera\n  width: 10\n  height: 10\n  field-of-view: 1\n- add: obj\n  file: quad.obj\n  material:\n    diffuse: 0.5\n"
+		path := filepath.Join(dir, "scene.yml")
+		if err := ioutil.WriteFile(path, []byte(scene), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		w, _, err := LoadSceneFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		g := w.Shapes[0].(*shapes.Group)
+		if len(g.Shapes) != 2 {
+			t.Fatalf("expected 2 triangles, got %d", len(g.Shapes))
+		}
+		for _, triangle := range g.Shapes {
+			datatypes.AssertVal(t, triangle.GetMaterial().Diffuse, 0.5)
+		}
+	})
+
+	t.Run("Loading an image pattern relative to the scene file", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "scene")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		im := image.NewRGBA(image.Rect(0, 0, 2, 1))
+		im.Set(0, 0, color.RGBA{R: 255, A: 255})
+		im.Set(1, 0, color.RGBA{B: 255, A: 255})
+		if err := SavePng(im, filepath.Join(dir, "map.png")); err != nil {
+			t.Fatal(err)
+		}
+
+		scene := `- add: camera
+  width: 10
+  height: 10
+  field-of-view: 1
+- add: sphere
+  material:
+    pattern:
+      type: image
+      file: map.png
+      mapping: spherical
+      wrap: clamp
+      filter: nearest
+      transform:
+        - [rotate-y, 1]
+`
+		path := filepath.Join(dir, "scene.yml")
+		if err := ioutil.WriteFile(path, []byte(scene), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		w, _, err := LoadSceneFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tex, ok := w.Shapes[0].GetMaterial().Pattern.(*raytracing.ImageTexture)
+		if !ok {
+			t.Fatalf("expected an image texture, got %T", w.Shapes[0].GetMaterial().Pattern)
+		}
+		if tex.Width != 2 || tex.Mapping != raytracing.SphericalMapping || tex.Wrap != raytracing.Clamp || !tex.Nearest {
+			t.Errorf("got %dx%d mapping %v wrap %v nearest %v", tex.Width, tex.Height, tex.Mapping, tex.Wrap, tex.Nearest)
+		}
+		raytracing.AssertColorsEqual(t, tex.Pixels[1], raytracing.RGB{Red: 0, Green: 0, Blue: 1})
+		datatypes.AssertMatrixEqual(t, tex.GetTransform(), datatypes.GetRotationY(1))
+
+		bad := strings.Replace(scene, "wrap: clamp", "wrap: tile", 1)
+		if err := ioutil.WriteFile(path, []byte(bad), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := LoadSceneFile(path); err == nil || !strings.Contains(err.Error(), "line 11") {
+			t.Errorf("expected an error on line 11, got %v", err)
+		}
+	})
+
+	t.Run("Loading backgrounds and environment lights", func(t *testing.T) {
+		camera := "- add: camera\n  width: 10\n  height: 10\n  field-of-view: 1\n"
+
+		w, _, err := LoadScene([]byte(camera+"- add: background\n  type: gradient\n  top: [0, 0, 1]\n  bottom: [1, 1, 1]\n"), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(w.Background, GradientBackground{Bottom: raytracing.RGB{Red: 1, Green: 1, Blue: 1}, Top: raytracing.RGB{Blue: 1}}) {
+			t.Errorf("got %#v", w.Background)
+		}
+
+		dir, err := ioutil.TempDir("", "scene")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		sky := GetFramebuffer(2, 1)
+		sky.SetRGB(0, 0, raytracing.RGB{Red: 4, Green: 2, Blue: 1})
+		if err := SaveHdr(sky, filepath.Join(dir, "sky.hdr")); err != nil {
+			t.Fatal(err)
+		}
+
+		w, _, err = LoadScene([]byte(camera+"- add: background\n  type: equirectangular\n  file: sky.hdr\n"), dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		equirect, ok := w.Background.(EquirectBackground)
+		if !ok {
+			t.Fatalf("expected an equirectangular background, got %T", w.Background)
+		}
+		raytracing.AssertColorsEqual(t, equirect.Texture.Pixels[0], raytracing.RGBEColor(sky.RGBAt(0, 0).RGBE()))
+
+		faces := "[sky.hdr, sky.hdr, sky.hdr, sky.hdr, sky.hdr, sky.hdr]"
+		w, _, err = LoadScene([]byte(camera+"- add: background\n  type: cube-map\n  faces: "+faces+"\n"), dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cube, ok := w.Background.(CubeMapBackground); !ok || cube.Faces[CubeNegativeZ] == nil {
+			t.Errorf("expected a cube map with six faces, got %#v", w.Background)
+		}
+
+		_, _, err = LoadScene([]byte(camera+"- add: background\n  type: cube-map\n  faces: [sky.hdr]\n"), dir)
+		if err == nil || !strings.Contains(err.Error(), "six faces") {
+			t.Errorf("expected an error about six faces, got %v", err)
+		}
+
+		w, _, err = LoadScene([]byte(camera+"- add: environment-light\n  file: sky.hdr\n  intensity: 2\n  samples: 4\n"), dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if env := w.Environment; env == nil || env.Intensity != 2 || env.Samples != 4 || env.Texture.Width != 2 {
+			t.Errorf("got environment light %+v", env)
+		}
+
+		_, _, err = LoadScene([]byte(camera+"- add: environment-light\n  samples: 4\n"), dir)
+		if err == nil || !strings.Contains(err.Error(), "needs a file") {
+			t.Errorf("expected an error about the file, got %v", err)
+		}
+	})
+
+	t.Run("Loading noise patterns", func(t *testing.T) {
+		w, _, err := LoadScene([]byte(`
+- add: camera
+  width: 10
+  height: 10
+  field-of-view: 1
+- add: sphere
+  material:
+    pattern:
+      type: marble
+      colors: [[1, 1, 1], [0.2, 0.2, 0.3]]
+      seed: 42
+      octaves: 6
+      lacunarity: 2.5
+      gain: 0.4
+      strength: 3
+- add: cube
+  material:
+    pattern:
+      type: perturb
+      amount: 0.5
+      pattern:
+        type: stripes
+        colors: [[1, 0, 0], [0, 0, 1]]
+        transform:
+          - [scale, 0.1, 0.1, 0.1]
+`), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		marble, ok := w.Shapes[0].GetMaterial().Pattern.(*raytracing.Marble)
+		if !ok {
+			t.Fatalf("expected marble, got %T", w.Shapes[0].GetMaterial().Pattern)
+		}
+		if marble.Fractal != (raytracing.Fractal{Octaves: 6, Lacunarity: 2.5, Gain: 0.4}) || marble.Strength != 3 || marble.Frequency != 1 {
+			t.Errorf("got %+v", marble)
+		}
+		if want := raytracing.GetNoise(42); *marble.Noise != *want {
+			t.Error("expected the noise to come from the seed")
+		}
+
+		perturb, ok := w.Shapes[1].GetMaterial().Pattern.(*raytracing.Perturb)
+		if !ok {
+			t.Fatalf("expected perturb, got %T", w.Shapes[1].GetMaterial().Pattern)
+		}
+		if _, ok := perturb.Pattern.(*raytracing.Stripe); !ok || perturb.Amount != 0.5 {
+			t.Errorf("got %+v", perturb)
+		}
+
+		_, _, err = LoadScene([]byte("- add: sphere\n  material:\n    pattern:\n      type: fbm\n      colors: [[1, 1, 1], [0, 0, 0]]\n      frequency: 2\n"), "")
+		if err == nil || !strings.Contains(err.Error(), "unknown fbm pattern key \"frequency\"") {
+			t.Errorf("expected frequency to be rejected for fbm, got %v", err)
+		}
+	})
+
+	t.Run("Loading nested, blended and masked patterns", func(t *testing.T) {
+		w, _, err := LoadScene([]byte(`
+- add: camera
+  width: 10
+  height: 10
+  field-of-view: 1
+- add: plane
+  material:
+    pattern:
+      type: stripes
+      colors:
+        - type: checkers
+          colors: [[1, 1, 1], [0, 0, 0]]
+          transform:
+            - [scale, 0.25, 0.25, 0.25]
+        - [1, 0, 0]
+- add: plane
+  material:
+    pattern:
+      type: blend
+      patterns:
+        - [1, 0, 0]
+        - type: rings
+          colors: [[0, 0, 1], [0, 1, 0]]
+      weights: [3, 1]
+- add: plane
+  material:
+    pattern:
+      type: mask
+      colors: [[1, 0, 0], [0, 0, 1]]
+      mask:
+        type: gradient
+        colors: [[0, 0, 0], [1, 1, 1]]
+`), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		at := func(i int, x float64) raytracing.RGB {
+			return w.Shapes[i].GetMaterial().Pattern.At(datatypes.Point(x, 0, 0))
+		}
+
+		raytracing.AssertColorsEqual(t, at(0, 0.1), raytracing.RGB{Red: 1, Green: 1, Blue: 1})
+		raytracing.AssertColorsEqual(t, at(0, 0.3), raytracing.RGB{})
+		raytracing.AssertColorsEqual(t, at(0, 1.3), raytracing.RGB{Red: 1})
+
+		raytracing.AssertColorsEqual(t, at(1, 0.5), raytracing.RGB{Red: 0.75, Blue: 0.25})
+
+		raytracing.AssertColorsEqual(t, at(2, 0), raytracing.RGB{Red: 1})
+		raytracing.AssertColorsEqual(t, at(2, 0.5), raytracing.RGB{Red: 0.5, Blue: 0.5})
+
+		_, _, err = LoadScene([]byte("- add: sphere\n  material:\n    pattern:\n      type: blend\n      patterns: [[1, 0, 0]]\n      weights: [1, 2]\n"), "")
+		if err == nil || !strings.Contains(err.Error(), "list of 1 numbers") {
+			t.Errorf("expected a weight per pattern, got %v", err)
+		}
+	})
+
+	t.Run("Errors point to the bad node", func(t *testing.T) {
+		camera := "- add: camera\n  width: 10\n  height: 10\n  field-of-view: 1\n"
+
+		tests := []struct {
+			scene, want string
+		}{
+			{"", "scene is empty"},
+			{"add: sphere", "line 1, column 1: expected a list of scene items"},
+			{"- add: light\n", "scene does not add a camera"},
+			{camera + "- add: teapot\n", "line 5, column 8: unknown item \"teapot\""},
+			{camera + "- add: sphere\n  radius: 2\n", "line 6, column 3: unknown sphere key \"radius\""},
+			{camera + "- add: sphere\n  material: shiny\n", "line 6, column 13: undefined material \"shiny\""},
+			{camera + "- add: sphere\n  material:\n    diffuse: lots\n", "line 7, column 14: expected a number"},
+			{camera + "- add: sphere\n  transform:\n    - [translate, 1, 2]\n", "line 7, column 7: translate takes 3 arguments, got 2"},
+			{camera + "- add: sphere\n  transform:\n    - [spin, 1]\n", "line 7, column 8: unknown transform \"spin\""},
+			{camera + "- define: a\n  value: a\n- add: sphere\n  transform: a\n", "line 6, column 10: transform \"a\" refers to itself: a -> a"},
+			{camera + "- define: a\n  value: [b]\n- define: b\n  value: [a]\n- add: sphere\n  transform: a\n", "line 8, column 11: transform \"a\" refers to itself: a -> b -> a"},
+			{camera + "- add: cube\n  cast-shadows: sometimes\n", "line 6, column 17: expected true or false"},
+			{camera + "- add: light\n  at: [1, 2]\n", "line 6, column 7: expected a list of 3 numbers"},
+			{camera + "- add: light\n  radius: 1\n  samples: 0\n", "line 5, column 8: point light needs a radius of at least 0 and at least one sample"},
+			{camera + "- add: group\n  children:\n    - add: cube\n      size: 1\n", "line 8, column 7: unknown cube key \"size\""},
+			{camera + "- add: csg\n  operation: xor\n  left:\n    add: cube\n  right:\n    add: cube\n", "line 6, column 14: unknown csg operation \"xor\""},
+			{camera + "- add: sphere\n  material:\n    color: \"#ff80\"\n", "line 7, column 12: expected an sRGB color such as \"#ff8000\""},
+			{"- add: camera\n  projection: warped\n", "line 2, column 15: unknown projection \"warped\""},
+			{"- add: camera\n  width: 10\n  height: 0\n  field-of-view: 1\n", "line 1, column 8: camera needs a positive width, height and field-of-view"},
+			{camera + "- remove: sphere\n", "line 5, column 3: expected \"add\" or \"define\", got \"remove\""},
+		}
+
+		for _, test := range tests {
+			_, _, err := LoadScene([]byte(test.scene), ".")
+			if err == nil {
+				t.Errorf("expected an error for %q", test.scene)
+				continue
+			}
+
+			if !strings.Contains(err.Error(), test.want) {
+				t.Errorf("got error %q, want %q", err, test.want)
+			}
+		}
+	})
+}
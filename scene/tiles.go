@@ -0,0 +1,149 @@
+package scene
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"sync"
+	"time"
+)
+
+// DefaultTileSize is the side of a square render tile in pixels
+const DefaultTileSize = 32
+
+// TileOrder is the order tiles are handed to the render goroutines
+type TileOrder int
+
+const (
+	// Scanline goes left to right, top to bottom
+	Scanline TileOrder = iota
+	// Spiral starts at the center of the image and works outward, so the
+	// usual subject of a render is finished first
+	Spiral
+)
+
+func GetTileOrder(name string) (TileOrder, error) {
+	switch name {
+	case "scanline":
+		return Scanline, nil
+	case "spiral":
+		return Spiral, nil
+	}
+	return Scanline, fmt.Errorf("unknown tile order %q", name)
+}
+
+// RenderProgress is passed to a camera's Progress callback after every tile
+type RenderProgress struct {
+	TilesDone, Tiles int
+	Elapsed          time.Duration
+	Remaining        time.Duration // estimated from the average time per tile so far
+}
+
+// Tiles splits the image into tiles in the camera's TileOrder, tiles on the
+// right and bottom edges may be smaller than TileSize
+func (c *camera) Tiles() []image.Rectangle {
+	size := c.TileSize
+	if size < 1 {
+		size = DefaultTileSize
+	}
+
+	cols := (c.Hsize + size - 1) / size
+	rows := (c.Vsize + size - 1) / size
+	bounds := image.Rect(0, 0, c.Hsize, c.Vsize)
+
+	tile := func(col, row int) image.Rectangle {
+		return image.Rect(col*size, row*size, (col+1)*size, (row+1)*size).Intersect(bounds)
+	}
+
+	tiles := make([]image.Rectangle, 0, cols*rows)
+
+	if c.TileOrder != Spiral {
+		for row := 0; row < rows; row++ {
+			for col := 0; col < cols; col++ {
+				tiles = append(tiles, tile(col, row))
+			}
+		}
+		return tiles
+	}
+
+	// walk a square spiral out from the center tile, taking the steps
+	// right 1, down 1, left 2, up 2, right 3, ... and keeping the tiles that
+	// fall inside the image
+	col, row := (cols-1)/2, (rows-1)/2
+	dirs := [4][2]int{{1, 0}, {0, 1}, {-1, 0}, {0, -1}}
+
+	for steps, dir := 1, 0; len(tiles) < cols*rows; dir++ {
+		for i := 0; i < steps && len(tiles) < cols*rows; i++ {
+			if col >= 0 && col < cols && row >= 0 && row < rows {
+				tiles = append(tiles, tile(col, row))
+			}
+			col, row = col+dirs[dir%4][0], row+dirs[dir%4][1]
+		}
+
+		if dir%2 == 1 {
+			steps++
+		}
+	}
+
+	return tiles
+}
+
+// renderTiles calls shade for every pixel, a tile at a time on workers
+// goroutines, reporting progress as tiles finish
+func (c *camera) renderTiles(ctx context.Context, workers int, shade func(x, y int)) error {
+	tiles := c.Tiles()
+	start := time.Now()
+
+	var mu sync.Mutex
+	done := 0
+
+	channel := make(chan image.Rectangle)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+
+			for tile := range channel {
+				for y := tile.Min.Y; y < tile.Max.Y; y++ {
+					if ctx.Err() != nil {
+						break
+					}
+					for x := tile.Min.X; x < tile.Max.X; x++ {
+						shade(x, y)
+					}
+				}
+
+				if c.Progress == nil || ctx.Err() != nil {
+					continue
+				}
+
+				mu.Lock()
+				done++
+				elapsed := time.Since(start)
+				c.Progress(RenderProgress{
+					TilesDone: done,
+					Tiles:     len(tiles),
+					Elapsed:   elapsed,
+					Remaining: elapsed / time.Duration(done) * time.Duration(len(tiles)-done)})
+				mu.Unlock()
+			}
+		}()
+	}
+
+send:
+	for _, tile := range tiles {
+		select {
+		case channel <- tile:
+		case <-ctx.Done():
+			break send
+		}
+	}
+
+	close(channel)
+	wg.Wait()
+
+	// checked after waiting since a tile may be cut short after the last send
+	return ctx.Err()
+}
@@ -0,0 +1,124 @@
+package scene
+
+import (
+	"context"
+	"github.com/seantur/ray_tracer_challenge/datatypes"
+	"image"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestTiles(t *testing.T) {
+
+	t.Run("Scanline tiles cover the image left to right, top to bottom", func(t *testing.T) {
+		c := GetCamera(5, 3, math.Pi/2)
+		c.TileSize = 2
+
+		want := []image.Rectangle{
+			image.Rect(0, 0, 2, 2), image.Rect(2, 0, 4, 2), image.Rect(4, 0, 5, 2),
+			image.Rect(0, 2, 2, 3), image.Rect(2, 2, 4, 3), image.Rect(4, 2, 5, 3)}
+
+		got := c.Tiles()
+
+		if len(got) != len(want) {
+			t.Fatalf("got %d tiles, want %d", len(got), len(want))
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("tile %d is %v, want %v", i, got[i], want[i])
+			}
+		}
+	})
+
+	t.Run("Spiral tiles start in the center and cover every tile once", func(t *testing.T) {
+		c := GetCamera(100, 60, math.Pi/2)
+		c.TileSize = 10
+		c.TileOrder = Spiral
+
+		tiles := c.Tiles()
+		datatypes.AssertVal(t, float64(len(tiles)), 60)
+
+		if tiles[0] != image.Rect(40, 20, 50, 30) {
+			t.Errorf("expected the first tile to be central, got %v", tiles[0])
+		}
+
+		seen := map[image.Rectangle]bool{}
+		for _, tile := range tiles {
+			if seen[tile] {
+				t.Errorf("tile %v was visited twice", tile)
+			}
+			seen[tile] = true
+		}
+
+		if tiles[1] != image.Rect(50, 20, 60, 30) {
+			t.Errorf("expected the spiral to step right first, got %v", tiles[1])
+		}
+	})
+
+	t.Run("Looking up tile orders by name", func(t *testing.T) {
+		if order, err := GetTileOrder("spiral"); err != nil || order != Spiral {
+			t.Errorf("got %v, %v", order, err)
+		}
+		if _, err := GetTileOrder("hilbert"); err == nil {
+			t.Error("expected an error")
+		}
+	})
+
+	t.Run("A cancelled render stops with the context's error", func(t *testing.T) {
+		c := GetCamera(50, 50, math.Pi/2)
+		c.TileSize = 5
+
+		ctx, cancel := context.WithCancel(context.Background())
+		tiles := 0
+		c.Progress = func(p RenderProgress) {
+			tiles = p.TilesDone
+			if p.TilesDone == 3 {
+				cancel()
+			}
+		}
+
+		im, _, err := c.RenderContext(ctx, GetWorld())
+
+		if err != context.Canceled {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+		if im == nil || tiles >= len(c.Tiles()) {
+			t.Errorf("expected a partial image, %d of %d tiles were reported", tiles, len(c.Tiles()))
+		}
+	})
+
+	t.Run("A render that runs out of time reports the deadline", func(t *testing.T) {
+		c := GetCamera(50, 50, math.Pi/2)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+		defer cancel()
+		time.Sleep(time.Millisecond)
+
+		if _, _, err := c.RenderContext(ctx, GetWorld()); err != context.DeadlineExceeded {
+			t.Errorf("expected context.DeadlineExceeded, got %v", err)
+		}
+	})
+
+	t.Run("Progress estimates the time remaining", func(t *testing.T) {
+		c := GetCamera(20, 20, math.Pi/2)
+		c.TileSize = 10
+		c.Workers = 1
+
+		var reports []RenderProgress
+		c.Progress = func(p RenderProgress) {
+			reports = append(reports, p)
+		}
+
+		if _, _, err := c.RenderContext(context.Background(), GetWorld()); err != nil {
+			t.Fatal(err)
+		}
+
+		datatypes.AssertVal(t, float64(len(reports)), 4)
+		last := reports[len(reports)-1]
+		datatypes.AssertVal(t, float64(last.Remaining), 0)
+		if last.Elapsed <= 0 {
+			t.Error("expected elapsed time to be recorded")
+		}
+	})
+}
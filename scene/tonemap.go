@@ -0,0 +1,88 @@
+package scene
+
+import (
+	"fmt"
+	"github.com/seantur/ray_tracer_challenge/raytracing"
+	"image"
+	"math"
+)
+
+// ToneOperator compresses rendered radiance, which has no upper limit, into
+// the [0, 1] range an ordinary image can show
+type ToneOperator int
+
+const (
+	// Clamp cuts off everything above 1, so highlights blow out to white
+	Clamp ToneOperator = iota
+	// Reinhard maps x to x/(1+x), nothing ever quite reaches white
+	Reinhard
+	// ReinhardExtended is Reinhard scaled so WhitePoint maps to exactly 1
+	ReinhardExtended
+	// ACES is Narkowicz's fit of the ACES filmic curve, with a gentle toe and
+	// shoulder
+	ACES
+)
+
+var toneOperatorNames = map[string]ToneOperator{
+	"clamp":             Clamp,
+	"reinhard":          Reinhard,
+	"reinhard-extended": ReinhardExtended,
+	"aces":              ACES,
+}
+
+func GetToneOperator(name string) (ToneOperator, error) {
+	op, ok := toneOperatorNames[name]
+	if !ok {
+		return Clamp, fmt.Errorf("unknown tone mapping operator %q", name)
+	}
+	return op, nil
+}
+
+// ToneMap turns a float framebuffer into a displayable sRGB image
+type ToneMap struct {
+	Exposure   float64 // in stops, each one doubles the brightness
+	Operator   ToneOperator
+	WhitePoint float64 // the radiance ReinhardExtended maps to white
+}
+
+func GetToneMap() ToneMap {
+	return ToneMap{Operator: Clamp, WhitePoint: 4}
+}
+
+// Map exposes and tone maps a linear color then encodes it as sRGB
+func (t ToneMap) Map(c raytracing.RGB) raytracing.RGB {
+	c = c.Multiply(math.Exp2(t.Exposure))
+
+	channel := func(x float64) float64 {
+		x = math.Max(x, 0)
+
+		switch t.Operator {
+		case Reinhard:
+			x = x / (1 + x)
+		case ReinhardExtended:
+			white := math.Max(t.WhitePoint, 1)
+			x = x * (1 + x/(white*white)) / (1 + x)
+		case ACES:
+			x = x * (2.51*x + 0.03) / (x*(2.43*x+0.59) + 0.14)
+		}
+
+		return raytracing.LinearToSRGB(math.Min(x, 1))
+	}
+
+	return raytracing.RGB{Red: channel(c.Red), Green: channel(c.Green), Blue: channel(c.Blue)}
+}
+
+// Apply maps every pixel of im, which is usually a Framebuffer, ready to be
+// saved as a PNG or JPEG
+func (t ToneMap) Apply(im image.Image) *image.RGBA64 {
+	b := im.Bounds()
+	out := image.NewRGBA64(b)
+
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			out.SetRGBA64(x, y, t.Map(rgbOf(im.At(x, y))).Cvt())
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,101 @@
+package scene
+
+import (
+	"github.com/seantur/ray_tracer_challenge/datatypes"
+	"github.com/seantur/ray_tracer_challenge/raytracing"
+	"testing"
+)
+
+func TestToneMap(t *testing.T) {
+
+	grey := func(v float64) raytracing.RGB {
+		return raytracing.RGB{Red: v, Green: v, Blue: v}
+	}
+
+	// mapped returns the linear value a tone map gives v, undoing the sRGB
+	mapped := func(tm ToneMap, v float64) float64 {
+		return raytracing.SRGBToLinear(tm.Map(grey(v)).Red)
+	}
+
+	assertClose := func(t *testing.T, got, want float64) {
+		t.Helper()
+		if !datatypes.IsClose(got, want) {
+			t.Errorf("got %f want %f", got, want)
+		}
+	}
+
+	t.Run("Operators are looked up by name", func(t *testing.T) {
+		op, err := GetToneOperator("reinhard-extended")
+		if err != nil || op != ReinhardExtended {
+			t.Errorf("got %v, %v", op, err)
+		}
+
+		if _, err := GetToneOperator("filmic"); err == nil {
+			t.Error("expected an error for an unknown operator")
+		}
+	})
+
+	t.Run("The default clamps and encodes as sRGB", func(t *testing.T) {
+		tm := GetToneMap()
+
+		raytracing.AssertColorsEqual(t, tm.Map(grey(0.5)), grey(raytracing.LinearToSRGB(0.5)))
+		raytracing.AssertColorsEqual(t, tm.Map(grey(7)), grey(1))
+		raytracing.AssertColorsEqual(t, tm.Map(grey(-1)), grey(0))
+	})
+
+	t.Run("Each stop of exposure doubles the brightness", func(t *testing.T) {
+		tm := GetToneMap()
+		tm.Exposure = 2
+
+		assertClose(t, mapped(tm, 0.125), 0.5)
+	})
+
+	t.Run("Reinhard compresses highlights", func(t *testing.T) {
+		tm := GetToneMap()
+		tm.Operator = Reinhard
+
+		assertClose(t, mapped(tm, 1), 0.5)
+		assertClose(t, mapped(tm, 3), 0.75)
+	})
+
+	t.Run("Extended Reinhard maps the white point to white", func(t *testing.T) {
+		tm := GetToneMap()
+		tm.Operator = ReinhardExtended
+		tm.WhitePoint = 4
+
+		assertClose(t, mapped(tm, 4), 1)
+		assertClose(t, mapped(tm, 1), 1.0625/2)
+	})
+
+	t.Run("ACES rolls off smoothly into white", func(t *testing.T) {
+		tm := GetToneMap()
+		tm.Operator = ACES
+
+		assertClose(t, mapped(tm, 0), 0)
+		if low, high := mapped(tm, 1), mapped(tm, 4); !(low < high && high < 1) {
+			t.Errorf("expected ACES to keep increasing below white, got %f then %f", low, high)
+		}
+		assertClose(t, mapped(tm, 100), 1)
+	})
+
+	t.Run("Applying a tone map encodes every pixel", func(t *testing.T) {
+		f := GetFramebuffer(3, 2)
+		f.SetRGB(2, 1, grey(9))
+		f.SetRGB(0, 0, grey(0.25))
+
+		tm := GetToneMap()
+		tm.Operator = Reinhard
+		im := tm.Apply(f)
+
+		if im.Bounds() != f.Bounds() {
+			t.Fatalf("got bounds %v", im.Bounds())
+		}
+
+		got := pixelColorAt(im, 2, 1)
+		want := grey(raytracing.LinearToSRGB(0.9))
+		if diff := got.Red - want.Red; diff > 1e-4 || diff < -1e-4 {
+			t.Errorf("got %v want %v", got, want)
+		}
+	})
+
+}
@@ -5,16 +5,21 @@ import (
 	"github.com/seantur/ray_tracer_challenge/raytracing"
 	"github.com/seantur/ray_tracer_challenge/shapes"
 	"math"
+	"math/rand"
 	"sort"
 )
 
 type World struct {
-	Light  PointLight
-	Shapes []shapes.Shape
+	Lights     []Light
+	Shapes     []shapes.Shape
+	Background Background // seen by rays that miss, black when nil
+
+	// Environment lights the scene from an image all around it, if set
+	Environment *EnvironmentLight
 }
 
 func GetWorld() World {
-	w := World{Light: PointLight{Position: datatypes.Point(-10, 10, -10), Intensity: raytracing.RGB{Red: 1, Green: 1, Blue: 1}}}
+	w := World{Lights: []Light{PointLight{Position: datatypes.Point(-10, 10, -10), Intensity: raytracing.RGB{Red: 1, Green: 1, Blue: 1}}}}
 
 	s1 := shapes.GetSphere()
 
@@ -32,13 +37,23 @@ func GetWorld() World {
 	return w
 }
 
+// Intersect returns the intersections of a camera ray with the world
 func (w *World) Intersect(r datatypes.Ray) []shapes.Intersection {
+	return w.IntersectKind(r, shapes.CameraRay)
+}
+
+// IntersectKind returns the intersections with the shapes visible to rays of
+// the given kind
+func (w *World) IntersectKind(r datatypes.Ray, kind shapes.RayKind) []shapes.Intersection {
 
 	intersections := []shapes.Intersection{}
 
 	for i, _ := range w.Shapes {
-		intersection := shapes.Intersect(w.Shapes[i], r)
-		intersections = append(intersections, intersection...)
+		for _, intersection := range shapes.Intersect(w.Shapes[i], r) {
+			if shapes.VisibleTo(intersection.Object, kind) {
+				intersections = append(intersections, intersection)
+			}
+		}
 	}
 	sort.Sort(shapes.ByT(intersections))
 
@@ -47,9 +62,26 @@ func (w *World) Intersect(r datatypes.Ray) []shapes.Intersection {
 }
 
 func (w *World) ShadeHit(c shapes.Computation, remaining int) raytracing.RGB {
-	shadowed := w.IsShadowed(c.OverPoint)
+	surfaceColor := raytracing.RGB{}
+
+	receives := shapes.ReceivesShadows(c.Object)
+	material := c.Object.GetMaterial()
+	color := hitColor(material, c)
+
+	for _, light := range w.Lights {
+		filter := raytracing.RGB{Red: 1, Green: 1, Blue: 1}
+		if receives {
+			filter = w.LightAt(light, c.OverPoint)
+		}
+
+		surfaceColor = raytracing.Add(surfaceColor,
+			lighting(material, color, light, c.OverPoint, c.Eyev, c.Normalv, filter))
+	}
+
+	if w.Environment != nil {
+		surfaceColor = raytracing.Add(surfaceColor, w.EnvironmentAt(c, receives))
+	}
 
-	surfaceColor := Lighting(c.Object.GetMaterial(), c.Object, w.Light, c.OverPoint, c.Eyev, c.Normalv, shadowed)
 	reflectedColor := w.ReflectedColor(c, remaining)
 	refractedColor := w.RefractedColor(c, remaining)
 
@@ -65,13 +97,20 @@ func (w *World) ShadeHit(c shapes.Computation, remaining int) raytracing.RGB {
 	return raytracing.Add(surfaceColor, reflectedColor, refractedColor)
 }
 
+// ColorAt returns the color seen along a camera ray
 func (w *World) ColorAt(r datatypes.Ray, remaining int) raytracing.RGB {
-	intersections := w.Intersect(r)
+	return w.ColorAtKind(r, remaining, shapes.CameraRay)
+}
+
+// ColorAtKind returns the color seen along a ray of the given kind, only
+// shapes visible to that kind are hit
+func (w *World) ColorAtKind(r datatypes.Ray, remaining int, kind shapes.RayKind) raytracing.RGB {
+	intersections := w.IntersectKind(r, kind)
 
 	hit, err := shapes.Hit(intersections)
 
 	if err != nil {
-		return raytracing.RGB{}
+		return w.BackgroundAt(r.Direction)
 	}
 
 	comp := hit.PrepareComputations(r, intersections)
@@ -81,20 +120,142 @@ func (w *World) ColorAt(r datatypes.Ray, remaining int) raytracing.RGB {
 	return c
 }
 
-func (w *World) IsShadowed(p datatypes.Tuple) bool {
-	v := datatypes.Subtract(w.Light.Position, p)
-	distance := v.Magnitude()
-	direction := v.Normalize()
+// BackgroundAt returns the color of the background along direction
+func (w *World) BackgroundAt(direction datatypes.Tuple) raytracing.RGB {
+	if w.Background == nil {
+		return raytracing.RGB{}
+	}
+
+	return w.Background.ColorAt(direction.Normalize())
+}
+
+// EnvironmentAt estimates the diffuse and glossy light the environment
+// reflects toward the eye at a hit, averaging its Samples directions. The
+// glossy lobe is the normalized Phong lobe so it keeps its brightness as the
+// shininess goes up.
+func (w *World) EnvironmentAt(c shapes.Computation, receivesShadows bool) raytracing.RGB {
+	env := w.Environment
+	if env.Samples < 1 {
+		return raytracing.RGB{}
+	}
+
+	mat := c.Object.GetMaterial()
+	diffuseColor := hitColor(mat, c)
+	diffuseColor = diffuseColor.Multiply(mat.Diffuse / math.Pi)
+	glossy := mat.Specular * (mat.Shininess + 2) / (2 * math.Pi)
+
+	sum := raytracing.RGB{}
+
+	for i := 0; i < env.Samples; i++ {
+		direction, radiance, pdf := env.Sample(rand.Float64(), rand.Float64(), rand.Float64(), rand.Float64())
+
+		cos := datatypes.Dot(direction, c.Normalv)
+		if pdf <= 0 || cos <= 0 {
+			continue
+		}
+
+		reflectance := diffuseColor
+
+		reflectv := direction.Negate()
+		reflectv = reflectv.Reflect(c.Normalv)
+		if reflectDotEye := datatypes.Dot(reflectv, c.Eyev); reflectDotEye > 0 {
+			spec := glossy * math.Pow(reflectDotEye, mat.Shininess)
+			reflectance = raytracing.Add(reflectance, raytracing.RGB{Red: spec, Green: spec, Blue: spec})
+		}
+
+		light := raytracing.Hadamard(radiance, reflectance)
+		if receivesShadows {
+			light = raytracing.Hadamard(light, w.Transmittance(c.OverPoint, LightSample{Direction: direction, Distance: math.Inf(1)}))
+		}
+
+		sum = raytracing.Add(sum, light.Multiply(cos/pdf))
+	}
+
+	return sum.Multiply(1 / float64(env.Samples))
+}
+
+// IntensityAt returns the fraction of the light reaching p, LightAt averaged
+// over the channels
+func (w *World) IntensityAt(light Light, p datatypes.Tuple) float64 {
+	c := w.LightAt(light, p)
+	return (c.Red + c.Green + c.Blue) / 3
+}
+
+// LightAt returns the fraction of the light reaching p in each channel,
+// averaged over the light's samples. Light passes through transparent
+// materials, picking up their color.
+func (w *World) LightAt(light Light, p datatypes.Tuple) raytracing.RGB {
+	samples := light.Sample(p)
+	total := raytracing.RGB{}
+
+	for _, sample := range samples {
+		total = raytracing.Add(total, w.Transmittance(p, sample))
+	}
 
-	r := datatypes.Ray{Origin: p, Direction: direction}
-	intersections := w.Intersect(r)
+	return total.Multiply(1 / float64(len(samples)))
+}
 
-	h, err := shapes.Hit(intersections)
-	if (err == nil) && (h.T < distance) {
-		return true
+// Transmittance returns how much of each channel of the light sample gets to
+// p. Opaque shapes block it, and each transparent shape it enters filters it
+// by the shape's transparency and color, then by the shape's Absorption over
+// the distance travelled inside.
+func (w *World) Transmittance(p datatypes.Tuple, sample LightSample) raytracing.RGB {
+	r := datatypes.Ray{Origin: p, Direction: sample.Direction}
+	filter := raytracing.RGB{Red: 1, Green: 1, Blue: 1}
+
+	// where the ray entered each shape it is inside, p starts inside the
+	// shapes crossed an odd number of times behind it
+	entered := map[shapes.Shape]float64{}
+
+	for _, i := range w.IntersectKind(r, shapes.ShadowRay) {
+		if i.T <= 0 {
+			if _, inside := entered[i.Object]; inside {
+				delete(entered, i.Object)
+			} else {
+				entered[i.Object] = 0
+			}
+			continue
+		} else if i.T >= sample.Distance {
+			break
+		}
+
+		material := i.Object.GetMaterial()
+		if material.Transparency == 0 {
+			return raytracing.RGB{}
+		}
+
+		color := material.RGB
+		if material.Pattern != nil {
+			color = shapes.AtHit(material.Pattern, i.Object, r.Position(i.T), i.U, i.V)
+		}
+
+		start, inside := entered[i.Object]
+		if !inside {
+			entered[i.Object] = i.T
+			filter = raytracing.Hadamard(filter, color.Multiply(material.Transparency))
+			continue
+		}
+
+		delete(entered, i.Object)
+		filter = raytracing.Hadamard(filter, absorption(color, material.Absorption*(i.T-start)))
 	}
 
-	return false
+	return filter
+}
+
+// absorption is the Beer-Lambert falloff of light through depth of a medium of
+// the given color, channels the color lacks fade fastest
+func absorption(color raytracing.RGB, depth float64) raytracing.RGB {
+	fade := func(c float64) float64 {
+		return math.Exp(-depth * (1 - math.Min(math.Max(c, 0), 1)))
+	}
+
+	return raytracing.RGB{Red: fade(color.Red), Green: fade(color.Green), Blue: fade(color.Blue)}
+}
+
+// IsShadowed reports whether none of the light sample gets to p
+func (w *World) IsShadowed(p datatypes.Tuple, sample LightSample) bool {
+	return w.Transmittance(p, sample) == raytracing.RGB{}
 }
 
 func (w *World) ReflectedColor(c shapes.Computation, remaining int) raytracing.RGB {
@@ -109,7 +270,7 @@ func (w *World) ReflectedColor(c shapes.Computation, remaining int) raytracing.R
 
 	reflectRay := datatypes.Ray{Origin: c.OverPoint, Direction: c.Reflectv}
 	remaining--
-	color := w.ColorAt(reflectRay, remaining-1)
+	color := w.ColorAtKind(reflectRay, remaining-1, shapes.ReflectionRay)
 
 	return color.Multiply(mat.Reflective)
 }
@@ -133,7 +294,7 @@ func (w *World) RefractedColor(c shapes.Computation, remaining int) raytracing.R
 	direction := datatypes.Subtract(c.Normalv.Multiply((nRatio*cosI)-cosT), c.Eyev.Multiply(nRatio))
 	refractRay := datatypes.Ray{Origin: c.UnderPoint, Direction: direction}
 
-	color := w.ColorAt(refractRay, remaining-1)
+	color := w.ColorAtKind(refractRay, remaining-1, shapes.RefractionRay)
 
 	return color.Multiply(material.Transparency)
 }
@@ -0,0 +1,131 @@
+package shapes
+
+import (
+	"github.com/seantur/ray_tracer_challenge/datatypes"
+	"math"
+)
+
+// BoundingBox is an axis aligned box, an empty box has Min > Max
+type BoundingBox struct {
+	Min, Max datatypes.Tuple
+}
+
+func GetBoundingBox() BoundingBox {
+	return BoundingBox{
+		Min: datatypes.Point(math.Inf(1), math.Inf(1), math.Inf(1)),
+		Max: datatypes.Point(math.Inf(-1), math.Inf(-1), math.Inf(-1))}
+}
+
+func (b *BoundingBox) IsEmpty() bool {
+	return b.Min.X > b.Max.X || b.Min.Y > b.Max.Y || b.Min.Z > b.Max.Z
+}
+
+func (b *BoundingBox) AddPoint(p datatypes.Tuple) {
+	b.Min = datatypes.Point(math.Min(b.Min.X, p.X), math.Min(b.Min.Y, p.Y), math.Min(b.Min.Z, p.Z))
+	b.Max = datatypes.Point(math.Max(b.Max.X, p.X), math.Max(b.Max.Y, p.Y), math.Max(b.Max.Z, p.Z))
+}
+
+func (b *BoundingBox) AddBox(box BoundingBox) {
+	if box.IsEmpty() {
+		return
+	}
+
+	b.AddPoint(box.Min)
+	b.AddPoint(box.Max)
+}
+
+func (b *BoundingBox) ContainsPoint(p datatypes.Tuple) bool {
+	return b.Min.X <= p.X && p.X <= b.Max.X &&
+		b.Min.Y <= p.Y && p.Y <= b.Max.Y &&
+		b.Min.Z <= p.Z && p.Z <= b.Max.Z
+}
+
+func (b *BoundingBox) ContainsBox(box BoundingBox) bool {
+	return b.ContainsPoint(box.Min) && b.ContainsPoint(box.Max)
+}
+
+// Transform returns the box enclosing all eight transformed corners
+func (b *BoundingBox) Transform(m datatypes.Matrix) BoundingBox {
+	out := GetBoundingBox()
+
+	if b.IsEmpty() {
+		return out
+	}
+
+	corners := []datatypes.Tuple{
+		b.Min,
+		datatypes.Point(b.Min.X, b.Min.Y, b.Max.Z),
+		datatypes.Point(b.Min.X, b.Max.Y, b.Min.Z),
+		datatypes.Point(b.Min.X, b.Max.Y, b.Max.Z),
+		datatypes.Point(b.Max.X, b.Min.Y, b.Min.Z),
+		datatypes.Point(b.Max.X, b.Min.Y, b.Max.Z),
+		datatypes.Point(b.Max.X, b.Max.Y, b.Min.Z),
+		b.Max}
+
+	for _, corner := range corners {
+		out.AddPoint(datatypes.TupleMultiply(m, corner))
+	}
+
+	return out
+}
+
+func (b *BoundingBox) Intersects(r datatypes.Ray) bool {
+	if b.IsEmpty() {
+		return false
+	}
+
+	xtmin, xtmax := checkAxis(r.Origin.X, r.Direction.X, b.Min.X, b.Max.X)
+	ytmin, ytmax := checkAxis(r.Origin.Y, r.Direction.Y, b.Min.Y, b.Max.Y)
+	ztmin, ztmax := checkAxis(r.Origin.Z, r.Direction.Z, b.Min.Z, b.Max.Z)
+
+	tmin := math.Max(math.Max(xtmin, ytmin), ztmin)
+	tmax := math.Min(math.Min(xtmax, ytmax), ztmax)
+
+	return tmin <= tmax
+}
+
+// Split halves the box along its longest axis
+func (b *BoundingBox) Split() (left, right BoundingBox) {
+	dx := b.Max.X - b.Min.X
+	dy := b.Max.Y - b.Min.Y
+	dz := b.Max.Z - b.Min.Z
+
+	greatest := math.Max(math.Max(dx, dy), dz)
+
+	x0, y0, z0 := b.Min.X, b.Min.Y, b.Min.Z
+	x1, y1, z1 := b.Max.X, b.Max.Y, b.Max.Z
+
+	if greatest == dx {
+		x0 = x0 + dx/2
+		x1 = x0
+	} else if greatest == dy {
+		y0 = y0 + dy/2
+		y1 = y0
+	} else {
+		z0 = z0 + dz/2
+		z1 = z0
+	}
+
+	left = BoundingBox{Min: b.Min, Max: datatypes.Point(x1, y1, z1)}
+	right = BoundingBox{Min: datatypes.Point(x0, y0, z0), Max: b.Max}
+
+	return
+}
+
+// ParentSpaceBounds returns the bounds of s transformed into its parent's space
+func ParentSpaceBounds(s Shape) BoundingBox {
+	bounds := s.Bounds()
+	return bounds.Transform(s.GetTransform())
+}
+
+// invalidateBounds clears the cached bounds of every group above s
+func invalidateBounds(s Shape) {
+	for s != nil {
+		if g, ok := s.(*Group); ok {
+			g.boundsMu.Lock()
+			g.bounds = nil
+			g.boundsMu.Unlock()
+		}
+		s = s.GetParent()
+	}
+}
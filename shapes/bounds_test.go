@@ -0,0 +1,180 @@
+package shapes
+
+import (
+	"github.com/seantur/ray_tracer_challenge/datatypes"
+	"math"
+	"testing"
+)
+
+func TestBounds(t *testing.T) {
+
+	assertBounds := func(t *testing.T, got BoundingBox, min, max datatypes.Tuple) {
+		t.Helper()
+		datatypes.AssertTupleEqual(t, got.Min, min)
+		datatypes.AssertTupleEqual(t, got.Max, max)
+	}
+
+	t.Run("Creating an empty bounding box", func(t *testing.T) {
+		b := GetBoundingBox()
+
+		if !b.IsEmpty() {
+			t.Error("expected a new bounding box to be empty")
+		}
+		if !math.IsInf(b.Min.X, 1) || !math.IsInf(b.Max.X, -1) {
+			t.Error("expected an empty box to span +inf to -inf")
+		}
+	})
+
+	t.Run("Adding points to an empty bounding box", func(t *testing.T) {
+		b := GetBoundingBox()
+		b.AddPoint(datatypes.Point(-5, 2, 0))
+		b.AddPoint(datatypes.Point(7, 0, -3))
+
+		assertBounds(t, b, datatypes.Point(-5, 0, -3), datatypes.Point(7, 2, 0))
+	})
+
+	t.Run("Shapes have bounding boxes", func(t *testing.T) {
+		assertBounds(t, GetSphere().Bounds(), datatypes.Point(-1, -1, -1), datatypes.Point(1, 1, 1))
+		assertBounds(t, GetCube().Bounds(), datatypes.Point(-1, -1, -1), datatypes.Point(1, 1, 1))
+		assertBounds(t, GetPlane().Bounds(),
+			datatypes.Point(-datatypes.INFINITY, 0, -datatypes.INFINITY),
+			datatypes.Point(datatypes.INFINITY, 0, datatypes.INFINITY))
+
+		cyl := GetCylinder()
+		assertBounds(t, cyl.Bounds(),
+			datatypes.Point(-1, -datatypes.INFINITY, -1), datatypes.Point(1, datatypes.INFINITY, 1))
+		cyl.Min = -5
+		cyl.Max = 3
+		assertBounds(t, cyl.Bounds(), datatypes.Point(-1, -5, -1), datatypes.Point(1, 3, 1))
+
+		cone := GetCone()
+		cone.Min = -5
+		cone.Max = 3
+		assertBounds(t, cone.Bounds(), datatypes.Point(-5, -5, -5), datatypes.Point(5, 3, 5))
+
+		tri := GetTriangle(datatypes.Point(-3, 7, 2), datatypes.Point(6, 2, -4), datatypes.Point(2, -1, -1))
+		assertBounds(t, tri.Bounds(), datatypes.Point(-3, -1, -4), datatypes.Point(6, 7, 2))
+	})
+
+	t.Run("Adding one bounding box to another", func(t *testing.T) {
+		b1 := BoundingBox{Min: datatypes.Point(-5, -2, 0), Max: datatypes.Point(7, 4, 4)}
+		b2 := BoundingBox{Min: datatypes.Point(8, -7, -2), Max: datatypes.Point(14, 2, 8)}
+		b1.AddBox(b2)
+
+		assertBounds(t, b1, datatypes.Point(-5, -7, -2), datatypes.Point(14, 4, 8))
+	})
+
+	t.Run("Checking to see if a box contains a point or a box", func(t *testing.T) {
+		b := BoundingBox{Min: datatypes.Point(5, -2, 0), Max: datatypes.Point(11, 4, 7)}
+
+		if !b.ContainsPoint(datatypes.Point(5, -2, 0)) || !b.ContainsPoint(datatypes.Point(8, 1, 3)) {
+			t.Error("expected points to be inside the box")
+		}
+		if b.ContainsPoint(datatypes.Point(3, 0, 3)) || b.ContainsPoint(datatypes.Point(8, 1, 8)) {
+			t.Error("expected points to be outside the box")
+		}
+		if !b.ContainsBox(BoundingBox{Min: datatypes.Point(6, -1, 1), Max: datatypes.Point(10, 3, 6)}) {
+			t.Error("expected box to be inside the box")
+		}
+		if b.ContainsBox(BoundingBox{Min: datatypes.Point(4, -3, -1), Max: datatypes.Point(10, 3, 6)}) {
+			t.Error("expected box to be outside the box")
+		}
+	})
+
+	t.Run("Transforming a bounding box", func(t *testing.T) {
+		b := BoundingBox{Min: datatypes.Point(-1, -1, -1), Max: datatypes.Point(1, 1, 1)}
+		m := datatypes.Multiply(datatypes.GetRotationX(math.Pi/4), datatypes.GetRotationY(math.Pi/4))
+
+		assertBounds(t, b.Transform(m),
+			datatypes.Point(-1.41421, -1.70711, -1.70711), datatypes.Point(1.41421, 1.70711, 1.70711))
+	})
+
+	t.Run("Querying a shape's bounding box in its parent's space", func(t *testing.T) {
+		s := GetSphere()
+		s.SetTransform(datatypes.Multiply(datatypes.GetTranslation(1, -3, 5), datatypes.GetScaling(0.5, 2, 4)))
+
+		assertBounds(t, ParentSpaceBounds(s), datatypes.Point(0.5, -5, 1), datatypes.Point(1.5, -1, 9))
+	})
+
+	t.Run("A group has a bounding box that contains its children", func(t *testing.T) {
+		s := GetSphere()
+		s.SetTransform(datatypes.Multiply(datatypes.GetTranslation(2, 5, -3), datatypes.GetScaling(2, 2, 2)))
+
+		c := GetCylinder()
+		c.Min = -2
+		c.Max = 2
+		c.SetTransform(datatypes.Multiply(datatypes.GetTranslation(-4, -1, 4), datatypes.GetScaling(0.5, 1, 0.5)))
+
+		g := GetGroup()
+		g.AddChild(s)
+		g.AddChild(c)
+
+		assertBounds(t, g.Bounds(), datatypes.Point(-4.5, -3, -5), datatypes.Point(4, 7, 4.5))
+	})
+
+	t.Run("Changing a child's transform updates the group's bounds", func(t *testing.T) {
+		s := GetSphere()
+		g := GetGroup()
+		g.AddChild(s)
+
+		assertBounds(t, g.Bounds(), datatypes.Point(-1, -1, -1), datatypes.Point(1, 1, 1))
+
+		s.SetTransform(datatypes.GetTranslation(5, 0, 0))
+		assertBounds(t, g.Bounds(), datatypes.Point(4, -1, -1), datatypes.Point(6, 1, 1))
+	})
+
+	t.Run("Intersecting a ray with a bounding box at the origin", func(t *testing.T) {
+		b := BoundingBox{Min: datatypes.Point(-1, -1, -1), Max: datatypes.Point(1, 1, 1)}
+
+		rays := []datatypes.Ray{
+			datatypes.Ray{Origin: datatypes.Point(5, 0.5, 0), Direction: datatypes.Vector(-1, 0, 0)},
+			datatypes.Ray{Origin: datatypes.Point(-5, 0.5, 0), Direction: datatypes.Vector(1, 0, 0)},
+			datatypes.Ray{Origin: datatypes.Point(0.5, 5, 0), Direction: datatypes.Vector(0, -1, 0)},
+			datatypes.Ray{Origin: datatypes.Point(0, 0.5, 0), Direction: datatypes.Vector(0, 0, 1)},
+			datatypes.Ray{Origin: datatypes.Point(-2, 0, 0), Direction: datatypes.Vector(2, 4, 6)},
+			datatypes.Ray{Origin: datatypes.Point(0, -2, 0), Direction: datatypes.Vector(6, 2, 4)},
+			datatypes.Ray{Origin: datatypes.Point(2, 0, 2), Direction: datatypes.Vector(0, 0, -1)},
+			datatypes.Ray{Origin: datatypes.Point(2, 2, 0), Direction: datatypes.Vector(-1, 0, 0)}}
+		want := []bool{true, true, true, true, false, false, false, false}
+
+		for i, r := range rays {
+			r.Direction = r.Direction.Normalize()
+			if b.Intersects(r) != want[i] {
+				t.Errorf("ray %d: expected intersects to be %v", i, want[i])
+			}
+		}
+	})
+
+	t.Run("Intersecting a ray with a non-cubic bounding box", func(t *testing.T) {
+		b := BoundingBox{Min: datatypes.Point(5, -2, 0), Max: datatypes.Point(11, 4, 7)}
+
+		rays := []datatypes.Ray{
+			datatypes.Ray{Origin: datatypes.Point(15, 1, 2), Direction: datatypes.Vector(-1, 0, 0)},
+			datatypes.Ray{Origin: datatypes.Point(7, 6, 5), Direction: datatypes.Vector(0, -1, 0)},
+			datatypes.Ray{Origin: datatypes.Point(8, 2, 12), Direction: datatypes.Vector(0, 0, -1)},
+			datatypes.Ray{Origin: datatypes.Point(9, -1, -8), Direction: datatypes.Vector(2, 4, 6)},
+			datatypes.Ray{Origin: datatypes.Point(12, 5, 4), Direction: datatypes.Vector(-1, 0, 0)}}
+		want := []bool{true, true, true, false, false}
+
+		for i, r := range rays {
+			r.Direction = r.Direction.Normalize()
+			if b.Intersects(r) != want[i] {
+				t.Errorf("ray %d: expected intersects to be %v", i, want[i])
+			}
+		}
+	})
+
+	t.Run("Splitting a bounding box along its longest axis", func(t *testing.T) {
+		b := BoundingBox{Min: datatypes.Point(-1, -2, -3), Max: datatypes.Point(9, 5.5, 3)}
+		left, right := b.Split()
+
+		assertBounds(t, left, datatypes.Point(-1, -2, -3), datatypes.Point(4, 5.5, 3))
+		assertBounds(t, right, datatypes.Point(4, -2, -3), datatypes.Point(9, 5.5, 3))
+
+		b = BoundingBox{Min: datatypes.Point(-1, -2, -3), Max: datatypes.Point(5, 3, 7)}
+		left, right = b.Split()
+
+		assertBounds(t, left, datatypes.Point(-1, -2, -3), datatypes.Point(5, 3, 2))
+		assertBounds(t, right, datatypes.Point(-1, -2, 2), datatypes.Point(5, 3, 7))
+	})
+}
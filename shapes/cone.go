@@ -12,6 +12,7 @@ type Cone struct {
 	Min, Max float64
 	Closed   bool
 	Parent   Shape
+	Visibility
 }
 
 func GetCone() *Cone {
@@ -46,6 +47,13 @@ func (c *Cone) GetTransform() datatypes.Matrix {
 
 func (c *Cone) SetTransform(m datatypes.Matrix) {
 	c.Transform = m
+	invalidateBounds(c.Parent)
+}
+
+func (c *Cone) Bounds() BoundingBox {
+	limit := math.Max(math.Abs(c.Min), math.Abs(c.Max))
+
+	return BoundingBox{Min: datatypes.Point(-limit, c.Min, -limit), Max: datatypes.Point(limit, c.Max, limit)}
 }
 
 func (cyl *Cone) Intersect(r datatypes.Ray) (xs []Intersection) {
@@ -73,15 +81,15 @@ func (cyl *Cone) Intersect(r datatypes.Ray) (xs []Intersection) {
 
 		y0 := r.Origin.Y + t0*r.Direction.Y
 		if cyl.Min < y0 && y0 < cyl.Max {
-			xs = append(xs, Intersection{t0, cyl})
+			xs = append(xs, Intersection{T: t0, Object: cyl})
 		}
 
 		y1 := r.Origin.Y + t1*r.Direction.Y
 		if cyl.Min < y1 && y1 < cyl.Max {
-			xs = append(xs, Intersection{t1, cyl})
+			xs = append(xs, Intersection{T: t1, Object: cyl})
 		}
 	} else if math.Abs(b) > datatypes.EPSILON { // a is zero, but b is non zero
-		xs = append(xs, Intersection{-c / (2 * b), cyl})
+		xs = append(xs, Intersection{T: -c / (2 * b), Object: cyl})
 	}
 
 	xs = cyl.intersectCap(r, xs)
@@ -121,12 +129,12 @@ func (c *Cone) intersectCap(r datatypes.Ray, xs []Intersection) (xsRet []Interse
 
 	t := (c.Min - r.Origin.Y) / r.Direction.Y
 	if checkCapCone(r, t, c.Min) {
-		xs = append(xs, Intersection{t, c})
+		xs = append(xs, Intersection{T: t, Object: c})
 	}
 
 	t = (c.Max - r.Origin.Y) / r.Direction.Y
 	if checkCapCone(r, t, c.Max) {
-		xs = append(xs, Intersection{t, c})
+		xs = append(xs, Intersection{T: t, Object: c})
 	}
 
 	return xs
@@ -0,0 +1,145 @@
+package shapes
+
+import (
+	"github.com/seantur/ray_tracer_challenge/datatypes"
+	"github.com/seantur/ray_tracer_challenge/raytracing"
+	"log"
+	"sort"
+)
+
+type CSGOperation int
+
+const (
+	CSGUnion CSGOperation = iota
+	CSGIntersect
+	CSGDifference
+)
+
+// CSG combines two shapes with a constructive solid geometry operation
+type CSG struct {
+	Transform datatypes.Matrix
+	raytracing.Material
+	Operation   CSGOperation
+	Left, Right Shape
+	Parent      Shape
+	Visibility
+}
+
+func GetCSG(op CSGOperation, left, right Shape) *CSG {
+	c := CSG{Operation: op, Left: left, Right: right}
+	c.Transform = datatypes.GetIdentity()
+	c.Material = raytracing.GetMaterial()
+
+	left.SetParent(&c)
+	right.SetParent(&c)
+
+	return &c
+}
+
+func (c *CSG) GetParent() Shape {
+	return c.Parent
+}
+
+func (c *CSG) SetParent(s Shape) {
+	c.Parent = s
+}
+
+func (c *CSG) GetMaterial() raytracing.Material {
+	return c.Material
+}
+
+func (c *CSG) SetMaterial(m raytracing.Material) {
+	c.Material = m
+}
+
+func (c *CSG) GetTransform() datatypes.Matrix {
+	return c.Transform
+}
+
+func (c *CSG) SetTransform(m datatypes.Matrix) {
+	c.Transform = m
+	invalidateBounds(c.Parent)
+}
+
+func (c *CSG) Bounds() BoundingBox {
+	b := GetBoundingBox()
+	b.AddBox(ParentSpaceBounds(c.Left))
+	b.AddBox(ParentSpaceBounds(c.Right))
+
+	return b
+}
+
+func (c *CSG) Normal(datatypes.Tuple) datatypes.Tuple {
+	log.Fatal("csg should not call Normal")
+	return datatypes.Tuple{} // needed to satisfy the Shape interface
+}
+
+func (c *CSG) Intersect(r datatypes.Ray) []Intersection {
+	intersections := append(Intersect(c.Left, r), Intersect(c.Right, r)...)
+	sort.Sort(ByT(intersections))
+
+	return c.FilterIntersections(intersections)
+}
+
+// Divide builds bounding volume hierarchies below both operands
+func (c *CSG) Divide(threshold int) {
+	Divide(c.Left, threshold)
+	Divide(c.Right, threshold)
+}
+
+// FilterIntersections keeps the intersections, sorted by T, that lie on the
+// surface of the combined shape
+func (c *CSG) FilterIntersections(intersections []Intersection) []Intersection {
+	inLeft := false
+	inRight := false
+
+	result := []Intersection{}
+
+	for _, i := range intersections {
+		leftHit := includes(c.Left, i.Object)
+
+		if IntersectionAllowed(c.Operation, leftHit, inLeft, inRight) {
+			result = append(result, i)
+		}
+
+		if leftHit {
+			inLeft = !inLeft
+		} else {
+			inRight = !inRight
+		}
+	}
+
+	return result
+}
+
+// IntersectionAllowed reports whether a hit on the left (or right) operand is
+// kept, given whether the hit point is currently inside either operand
+func IntersectionAllowed(op CSGOperation, leftHit, inLeft, inRight bool) bool {
+	switch op {
+	case CSGUnion:
+		return (leftHit && !inRight) || (!leftHit && !inLeft)
+	case CSGIntersect:
+		return (leftHit && inRight) || (!leftHit && inLeft)
+	case CSGDifference:
+		return (leftHit && !inRight) || (!leftHit && inLeft)
+	}
+
+	return false
+}
+
+// includes reports whether target is s or one of its descendants
+func includes(s Shape, target Shape) bool {
+	switch v := s.(type) {
+	case *Group:
+		for _, child := range v.Shapes {
+			if includes(child, target) {
+				return true
+			}
+		}
+		return false
+	case *CSG:
+		return includes(v.Left, target) || includes(v.Right, target)
+	}
+
+	return s == target
+}
@@ -0,0 +1,240 @@
+package shapes
+
+import (
+	"github.com/seantur/ray_tracer_challenge/datatypes"
+	"github.com/seantur/ray_tracer_challenge/raytracing"
+	"math"
+	"testing"
+)
+
+func TestCSG(t *testing.T) {
+
+	assertShape := func(t *testing.T, got Shape, want Shape) {
+		t.Helper()
+		if got != want {
+			t.Errorf("shapes did not match: got %v want %v", got, want)
+		}
+	}
+
+	t.Run("CSG is created with an operation and two shapes", func(t *testing.T) {
+		s1 := GetSphere()
+		s2 := GetCube()
+		c := GetCSG(CSGUnion, s1, s2)
+
+		if c.Operation != CSGUnion {
+			t.Error("expected a union operation")
+		}
+		assertShape(t, c.Left, s1)
+		assertShape(t, c.Right, s2)
+		assertShape(t, s1.GetParent(), c)
+		assertShape(t, s2.GetParent(), c)
+	})
+
+	t.Run("Evaluating the rule for a CSG operation", func(t *testing.T) {
+		cases := []struct {
+			op                       CSGOperation
+			leftHit, inLeft, inRight bool
+			want                     bool
+		}{
+			{CSGUnion, true, true, true, false},
+			{CSGUnion, true, true, false, true},
+			{CSGUnion, true, false, true, false},
+			{CSGUnion, true, false, false, true},
+			{CSGUnion, false, true, true, false},
+			{CSGUnion, false, true, false, false},
+			{CSGUnion, false, false, true, true},
+			{CSGUnion, false, false, false, true},
+			{CSGIntersect, true, true, true, true},
+			{CSGIntersect, true, true, false, false},
+			{CSGIntersect, true, false, true, true},
+			{CSGIntersect, true, false, false, false},
+			{CSGIntersect, false, true, true, true},
+			{CSGIntersect, false, true, false, true},
+			{CSGIntersect, false, false, true, false},
+			{CSGIntersect, false, false, false, false},
+			{CSGDifference, true, true, true, false},
+			{CSGDifference, true, true, false, true},
+			{CSGDifference, true, false, true, false},
+			{CSGDifference, true, false, false, true},
+			{CSGDifference, false, true, true, true},
+			{CSGDifference, false, true, false, true},
+			{CSGDifference, false, false, true, false},
+			{CSGDifference, false, false, false, false},
+		}
+
+		for _, c := range cases {
+			if got := IntersectionAllowed(c.op, c.leftHit, c.inLeft, c.inRight); got != c.want {
+				t.Errorf("%v %v %v %v: got %v want %v", c.op, c.leftHit, c.inLeft, c.inRight, got, c.want)
+			}
+		}
+	})
+
+	t.Run("Filtering a list of intersections", func(t *testing.T) {
+		cases := []struct {
+			op     CSGOperation
+			x0, x1 int
+		}{
+			{CSGUnion, 0, 3},
+			{CSGIntersect, 1, 2},
+			{CSGDifference, 0, 1},
+		}
+
+		for _, c := range cases {
+			s1 := GetSphere()
+			s2 := GetCube()
+			csg := GetCSG(c.op, s1, s2)
+
+			xs := []Intersection{
+				Intersection{T: 1, Object: s1},
+				Intersection{T: 2, Object: s2},
+				Intersection{T: 3, Object: s1},
+				Intersection{T: 4, Object: s2}}
+
+			result := csg.FilterIntersections(xs)
+
+			if len(result) != 2 {
+				t.Fatalf("expected 2 intersections got %v", len(result))
+			}
+			if result[0] != xs[c.x0] || result[1] != xs[c.x1] {
+				t.Errorf("operation %v kept the wrong intersections", c.op)
+			}
+		}
+	})
+
+	t.Run("A ray misses a CSG object", func(t *testing.T) {
+		c := GetCSG(CSGUnion, GetSphere(), GetCube())
+		r := datatypes.Ray{Origin: datatypes.Point(0, 2, -5), Direction: datatypes.Vector(0, 0, 1)}
+
+		datatypes.AssertVal(t, float64(len(c.Intersect(r))), 0)
+	})
+
+	t.Run("A ray hits a CSG object", func(t *testing.T) {
+		s1 := GetSphere()
+		s2 := GetSphere()
+		s2.SetTransform(datatypes.GetTranslation(0, 0, 0.5))
+		c := GetCSG(CSGUnion, s1, s2)
+
+		r := datatypes.Ray{Origin: datatypes.Point(0, 0, -5), Direction: datatypes.Vector(0, 0, 1)}
+		xs := c.Intersect(r)
+
+		if len(xs) != 2 {
+			t.Fatalf("expected 2 intersections got %v", len(xs))
+		}
+		datatypes.AssertVal(t, xs[0].T, 4)
+		assertShape(t, xs[0].Object, s1)
+		datatypes.AssertVal(t, xs[1].T, 6.5)
+		assertShape(t, xs[1].Object, s2)
+	})
+
+	t.Run("Filtering includes the children of groups", func(t *testing.T) {
+		s1 := GetSphere()
+		g := GetGroup()
+		g.AddChild(s1)
+		s2 := GetCube()
+		csg := GetCSG(CSGDifference, g, s2)
+
+		xs := []Intersection{
+			Intersection{T: 1, Object: s1},
+			Intersection{T: 2, Object: s2},
+			Intersection{T: 3, Object: s1},
+			Intersection{T: 4, Object: s2}}
+
+		result := csg.FilterIntersections(xs)
+
+		if len(result) != 2 {
+			t.Fatalf("expected 2 intersections got %v", len(result))
+		}
+		if result[0] != xs[0] || result[1] != xs[1] {
+			t.Error("difference kept the wrong intersections")
+		}
+	})
+
+	t.Run("A sphere carved out of a cube", func(t *testing.T) {
+		cube := GetCube()
+		sphere := GetSphere()
+		sphere.SetTransform(datatypes.GetScaling(1.5, 1.5, 1.5))
+		c := GetCSG(CSGDifference, cube, sphere)
+
+		// Through the middle of each face the sphere has eaten the cube away
+		r := datatypes.Ray{Origin: datatypes.Point(0, 0, -5), Direction: datatypes.Vector(0, 0, 1)}
+		datatypes.AssertVal(t, float64(len(c.Intersect(r))), 0)
+
+		// Near the corners the cube survives, entered at the cube's face and
+		// left at the sphere's surface
+		r = datatypes.Ray{Origin: datatypes.Point(0.9, 0.9, -5), Direction: datatypes.Vector(0, 0, 1)}
+		xs := c.Intersect(r)
+
+		if len(xs) != 4 {
+			t.Fatalf("expected 4 intersections got %v", len(xs))
+		}
+		assertShape(t, xs[0].Object, cube)
+		assertShape(t, xs[1].Object, sphere)
+		datatypes.AssertVal(t, xs[1].T, 5-math.Sqrt(1.5*1.5-0.9*0.9-0.9*0.9))
+	})
+
+	t.Run("Normals and patterns work through nested CSG and groups", func(t *testing.T) {
+		s1 := GetSphere()
+		s2 := GetSphere()
+		s2.SetTransform(datatypes.GetTranslation(0, 0, 0.5))
+		inner := GetCSG(CSGIntersect, s1, s2)
+
+		outer := GetCSG(CSGUnion, inner, GetCube())
+		outer.Right.SetTransform(datatypes.GetTranslation(10, 0, 0))
+
+		g := GetGroup()
+		g.SetTransform(datatypes.GetScaling(2, 2, 2))
+		g.AddChild(outer)
+
+		r := datatypes.Ray{Origin: datatypes.Point(0, 0, -5), Direction: datatypes.Vector(0, 0, 1)}
+		xs := Intersect(g, r)
+
+		if len(xs) != 2 {
+			t.Fatalf("expected 2 intersections got %v", len(xs))
+		}
+		datatypes.AssertVal(t, xs[0].T, 4)
+		assertShape(t, xs[0].Object, s2)
+
+		comps := xs[0].PrepareComputations(r, xs)
+		datatypes.AssertTupleEqual(t, comps.Normalv, datatypes.Vector(0, 0, -1))
+
+		pattern := raytracing.GetTestPat()
+		raytracing.AssertColorsEqual(t, AtObj(pattern, s2, datatypes.Point(2, 4, 6)), raytracing.RGB{Red: 1, Green: 2, Blue: 2.5})
+	})
+
+	t.Run("A CSG's bounds contain both children", func(t *testing.T) {
+		s1 := GetSphere()
+		s2 := GetSphere()
+		s2.SetTransform(datatypes.GetTranslation(2, 3, 4))
+		c := GetCSG(CSGDifference, s1, s2)
+
+		b := c.Bounds()
+		datatypes.AssertTupleEqual(t, b.Min, datatypes.Point(-1, -1, -1))
+		datatypes.AssertTupleEqual(t, b.Max, datatypes.Point(3, 4, 5))
+	})
+
+	t.Run("Subdividing a CSG shape subdivides its children", func(t *testing.T) {
+		s1 := GetSphere()
+		s1.SetTransform(datatypes.GetTranslation(-1.5, 0, 0))
+		s2 := GetSphere()
+		s2.SetTransform(datatypes.GetTranslation(1.5, 0, 0))
+		left := GetGroup()
+		left.AddChild(s1)
+		left.AddChild(s2)
+
+		s3 := GetSphere()
+		s3.SetTransform(datatypes.GetTranslation(0, 0, -1.5))
+		s4 := GetSphere()
+		s4.SetTransform(datatypes.GetTranslation(0, 0, 1.5))
+		right := GetGroup()
+		right.AddChild(s3)
+		right.AddChild(s4)
+
+		c := GetCSG(CSGDifference, left, right)
+		Divide(c, 1)
+
+		assertShape(t, left.Shapes[0].(*Group).Shapes[0], s1)
+		assertShape(t, left.Shapes[1].(*Group).Shapes[0], s2)
+		assertShape(t, right.Shapes[0].(*Group).Shapes[0], s3)
+		assertShape(t, right.Shapes[1].(*Group).Shapes[0], s4)
+	})
+}
@@ -10,6 +10,7 @@ type Cube struct {
 	Transform datatypes.Matrix
 	raytracing.Material
 	Parent Shape
+	Visibility
 }
 
 func GetCube() *Cube {
@@ -42,11 +43,16 @@ func (c *Cube) GetTransform() datatypes.Matrix {
 
 func (c *Cube) SetTransform(m datatypes.Matrix) {
 	c.Transform = m
+	invalidateBounds(c.Parent)
 }
 
-func checkAxis(origin, direction float64) (tmin, tmax float64) {
-	tminNumerator := -1 - origin
-	tmaxNumerator := 1 - origin
+func (c *Cube) Bounds() BoundingBox {
+	return BoundingBox{Min: datatypes.Point(-1, -1, -1), Max: datatypes.Point(1, 1, 1)}
+}
+
+func checkAxis(origin, direction, min, max float64) (tmin, tmax float64) {
+	tminNumerator := min - origin
+	tmaxNumerator := max - origin
 
 	if math.Abs(direction) >= datatypes.EPSILON {
 		tmin = tminNumerator / direction
@@ -67,9 +73,9 @@ func checkAxis(origin, direction float64) (tmin, tmax float64) {
 
 func (c *Cube) Intersect(r datatypes.Ray) []Intersection {
 
-	xtmin, xtmax := checkAxis(r.Origin.X, r.Direction.X)
-	ytmin, ytmax := checkAxis(r.Origin.Y, r.Direction.Y)
-	ztmin, ztmax := checkAxis(r.Origin.Z, r.Direction.Z)
+	xtmin, xtmax := checkAxis(r.Origin.X, r.Direction.X, -1, 1)
+	ytmin, ytmax := checkAxis(r.Origin.Y, r.Direction.Y, -1, 1)
+	ztmin, ztmax := checkAxis(r.Origin.Z, r.Direction.Z, -1, 1)
 
 	tmin := math.Max(math.Max(xtmin, ytmin), ztmin)
 	tmax := math.Min(math.Min(xtmax, ytmax), ztmax)
@@ -79,8 +85,8 @@ func (c *Cube) Intersect(r datatypes.Ray) []Intersection {
 	}
 
 	xs := []Intersection{
-		Intersection{tmin, c},
-		Intersection{tmax, c}}
+		Intersection{T: tmin, Object: c},
+		Intersection{T: tmax, Object: c}}
 
 	return xs
 }
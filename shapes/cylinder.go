@@ -12,6 +12,7 @@ type Cylinder struct {
 	Min, Max float64
 	Closed   bool
 	Parent   Shape
+	Visibility
 }
 
 func GetCylinder() *Cylinder {
@@ -46,6 +47,11 @@ func (c *Cylinder) GetTransform() datatypes.Matrix {
 
 func (c *Cylinder) SetTransform(m datatypes.Matrix) {
 	c.Transform = m
+	invalidateBounds(c.Parent)
+}
+
+func (c *Cylinder) Bounds() BoundingBox {
+	return BoundingBox{Min: datatypes.Point(-1, c.Min, -1), Max: datatypes.Point(1, c.Max, 1)}
 }
 
 func (cyl *Cylinder) Intersect(r datatypes.Ray) (xs []Intersection) {
@@ -75,12 +81,12 @@ func (cyl *Cylinder) Intersect(r datatypes.Ray) (xs []Intersection) {
 
 		y0 := r.Origin.Y + t0*r.Direction.Y
 		if cyl.Min < y0 && y0 < cyl.Max {
-			xs = append(xs, Intersection{t0, cyl})
+			xs = append(xs, Intersection{T: t0, Object: cyl})
 		}
 
 		y1 := r.Origin.Y + t1*r.Direction.Y
 		if cyl.Min < y1 && y1 < cyl.Max {
-			xs = append(xs, Intersection{t1, cyl})
+			xs = append(xs, Intersection{T: t1, Object: cyl})
 		}
 	}
 
@@ -115,12 +121,12 @@ func (c *Cylinder) intersectCap(r datatypes.Ray, xs []Intersection) (xsRet []Int
 
 	t := (c.Min - r.Origin.Y) / r.Direction.Y
 	if checkCapCylinder(r, t) {
-		xs = append(xs, Intersection{t, c})
+		xs = append(xs, Intersection{T: t, Object: c})
 	}
 
 	t = (c.Max - r.Origin.Y) / r.Direction.Y
 	if checkCapCylinder(r, t) {
-		xs = append(xs, Intersection{t, c})
+		xs = append(xs, Intersection{T: t, Object: c})
 	}
 
 	return xs
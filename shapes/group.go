@@ -5,6 +5,7 @@ import (
 	"github.com/seantur/ray_tracer_challenge/raytracing"
 	"log"
 	"sort"
+	"sync"
 )
 
 type Group struct {
@@ -12,6 +13,12 @@ type Group struct {
 	raytracing.Material
 	Shapes []Shape
 	Parent Shape
+	Visibility
+
+	// bounds caches the union of the children's bounds, it is reset whenever
+	// a child is added or a transform below the group changes
+	bounds   *BoundingBox
+	boundsMu sync.Mutex
 }
 
 func GetGroup() *Group {
@@ -37,6 +44,22 @@ func (g *Group) GetTransform() datatypes.Matrix {
 
 func (g *Group) SetTransform(m datatypes.Matrix) {
 	g.Transform = m
+	invalidateBounds(g.Parent)
+}
+
+func (g *Group) Bounds() BoundingBox {
+	g.boundsMu.Lock()
+	defer g.boundsMu.Unlock()
+
+	if g.bounds == nil {
+		b := GetBoundingBox()
+		for _, shape := range g.Shapes {
+			b.AddBox(ParentSpaceBounds(shape))
+		}
+		g.bounds = &b
+	}
+
+	return *g.bounds
 }
 
 func (g *Group) Normal(datatypes.Tuple) datatypes.Tuple {
@@ -45,6 +68,10 @@ func (g *Group) Normal(datatypes.Tuple) datatypes.Tuple {
 }
 
 func (g *Group) Intersect(r datatypes.Ray) (intersections []Intersection) {
+	bounds := g.Bounds()
+	if !bounds.Intersects(r) {
+		return
+	}
 
 	for _, shape := range g.Shapes {
 		shapeIntersection := Intersect(shape, r)
@@ -67,4 +94,78 @@ func (g *Group) SetParent(parent Shape) {
 func (g *Group) AddChild(s Shape) {
 	g.Shapes = append(g.Shapes, s)
 	s.SetParent(g)
+	invalidateBounds(g)
+}
+
+// PartitionChildren removes the children that fit entirely in either half of
+// the group's bounds, the rest stay in the group
+func (g *Group) PartitionChildren() (left, right []Shape) {
+	bounds := g.Bounds()
+	leftBounds, rightBounds := bounds.Split()
+
+	remaining := []Shape{}
+
+	for _, shape := range g.Shapes {
+		shapeBounds := ParentSpaceBounds(shape)
+
+		if leftBounds.ContainsBox(shapeBounds) {
+			left = append(left, shape)
+		} else if rightBounds.ContainsBox(shapeBounds) {
+			right = append(right, shape)
+		} else {
+			remaining = append(remaining, shape)
+		}
+	}
+
+	g.Shapes = remaining
+	invalidateBounds(g)
+
+	return
+}
+
+func (g *Group) MakeSubgroup(shapes ...Shape) {
+	sub := GetGroup()
+	for _, shape := range shapes {
+		sub.AddChild(shape)
+	}
+
+	g.AddChild(sub)
+}
+
+// Divide recursively splits the group into a bounding volume hierarchy, any
+// group with at least threshold children is partitioned along its longest axis
+func (g *Group) Divide(threshold int) {
+	if threshold <= len(g.Shapes) {
+		left, right := g.PartitionChildren()
+
+		if len(g.Shapes) == 0 && (len(left) == 0 || len(right) == 0) {
+			// Nothing was separated (every child shares the same bounds), so
+			// subdividing again would never terminate
+			g.Shapes = append(left, right...)
+		} else {
+			if len(left) > 0 {
+				g.MakeSubgroup(left...)
+			}
+
+			if len(right) > 0 {
+				g.MakeSubgroup(right...)
+			}
+		}
+	}
+
+	for _, shape := range g.Shapes {
+		Divide(shape, threshold)
+	}
+}
+
+// Divide builds a bounding volume hierarchy below s, shapes without children
+// are left untouched
+func Divide(s Shape, threshold int) {
+	if d, ok := s.(divider); ok {
+		d.Divide(threshold)
+	}
+}
+
+type divider interface {
+	Divide(threshold int)
 }
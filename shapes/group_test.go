@@ -151,4 +151,151 @@ func TestGroups(t *testing.T) {
 		datatypes.AssertTupleEqual(t, n, datatypes.Vector(0.28570, 0.42854, -0.85716))
 	})
 
+	t.Run("Intersecting a ray with a group misses when the box is missed", func(t *testing.T) {
+		child := GetTestShape()
+		g := GetGroup()
+		g.AddChild(child)
+
+		r := datatypes.Ray{Origin: datatypes.Point(0, 0, -5), Direction: datatypes.Vector(0, 1, 0)}
+		Intersect(g, r)
+
+		if child.SavedRay != nil {
+			t.Error("expected the child not to be tested")
+		}
+	})
+
+	t.Run("Intersecting a ray with a group tests children when the box is hit", func(t *testing.T) {
+		child := GetTestShape()
+		g := GetGroup()
+		g.AddChild(child)
+
+		r := datatypes.Ray{Origin: datatypes.Point(0, 0, -5), Direction: datatypes.Vector(0, 0, 1)}
+		Intersect(g, r)
+
+		if child.SavedRay == nil {
+			t.Error("expected the child to be tested")
+		}
+	})
+
+	t.Run("Partitioning a group's children", func(t *testing.T) {
+		s1 := GetSphere()
+		s1.SetTransform(datatypes.GetTranslation(-2, 0, 0))
+		s2 := GetSphere()
+		s2.SetTransform(datatypes.GetTranslation(2, 0, 0))
+		s3 := GetSphere()
+
+		g := GetGroup()
+		g.AddChild(s1)
+		g.AddChild(s2)
+		g.AddChild(s3)
+
+		left, right := g.PartitionChildren()
+
+		assertVal(t, float64(len(g.Shapes)), 1)
+		assertShape(t, g.Shapes[0], s3)
+		assertVal(t, float64(len(left)), 1)
+		assertShape(t, left[0], s1)
+		assertVal(t, float64(len(right)), 1)
+		assertShape(t, right[0], s2)
+	})
+
+	t.Run("Creating a sub-group from a list of children", func(t *testing.T) {
+		s1 := GetSphere()
+		s2 := GetSphere()
+		g := GetGroup()
+
+		g.MakeSubgroup(s1, s2)
+
+		assertVal(t, float64(len(g.Shapes)), 1)
+		sub := g.Shapes[0].(*Group)
+		assertShape(t, sub.Shapes[0], s1)
+		assertShape(t, sub.Shapes[1], s2)
+		assertShape(t, s1.GetParent(), sub)
+	})
+
+	t.Run("Subdividing a group partitions its children", func(t *testing.T) {
+		s1 := GetSphere()
+		s1.SetTransform(datatypes.GetTranslation(-2, -2, 0))
+		s2 := GetSphere()
+		s2.SetTransform(datatypes.GetTranslation(-2, 2, 0))
+		s3 := GetSphere()
+		s3.SetTransform(datatypes.GetScaling(4, 4, 4))
+
+		g := GetGroup()
+		g.AddChild(s1)
+		g.AddChild(s2)
+		g.AddChild(s3)
+
+		g.Divide(1)
+
+		assertShape(t, g.Shapes[0], s3)
+		sub := g.Shapes[1].(*Group)
+		assertVal(t, float64(len(sub.Shapes)), 2)
+		assertShape(t, sub.Shapes[0].(*Group).Shapes[0], s1)
+		assertShape(t, sub.Shapes[1].(*Group).Shapes[0], s2)
+	})
+
+	t.Run("Subdividing a group with too few children", func(t *testing.T) {
+		s1 := GetSphere()
+		s1.SetTransform(datatypes.GetTranslation(-2, 0, 0))
+		s2 := GetSphere()
+		s2.SetTransform(datatypes.GetTranslation(2, 1, 0))
+		s3 := GetSphere()
+		s3.SetTransform(datatypes.GetTranslation(2, -1, 0))
+		sub := GetGroup()
+		sub.AddChild(s1)
+		sub.AddChild(s2)
+		sub.AddChild(s3)
+
+		s4 := GetSphere()
+		g := GetGroup()
+		g.AddChild(sub)
+		g.AddChild(s4)
+
+		g.Divide(3)
+
+		assertShape(t, g.Shapes[0], sub)
+		assertShape(t, g.Shapes[1], s4)
+		assertVal(t, float64(len(sub.Shapes)), 2)
+		assertShape(t, sub.Shapes[0].(*Group).Shapes[0], s1)
+		assertShape(t, sub.Shapes[1].(*Group).Shapes[0], s2)
+		assertShape(t, sub.Shapes[1].(*Group).Shapes[1], s3)
+	})
+
+	t.Run("Subdividing children that share the same bounds terminates", func(t *testing.T) {
+		g := GetGroup()
+		for i := 0; i < 4; i++ {
+			g.AddChild(GetTriangle(datatypes.Point(0, 0, 0), datatypes.Point(0, 0, 0), datatypes.Point(0, 0, 0)))
+		}
+
+		g.Divide(1)
+
+		assertVal(t, float64(len(g.Shapes)), 4)
+	})
+
+	t.Run("A divided group intersects like the original", func(t *testing.T) {
+		g := GetGroup()
+		for x := -3; x <= 3; x++ {
+			for y := -3; y <= 3; y++ {
+				s := GetSphere()
+				s.SetTransform(datatypes.Multiply(
+					datatypes.GetTranslation(float64(x), float64(y), 0),
+					datatypes.GetScaling(0.4, 0.4, 0.4)))
+				g.AddChild(s)
+			}
+		}
+
+		r := datatypes.Ray{Origin: datatypes.Point(1, 2, -5), Direction: datatypes.Vector(0, 0, 1)}
+		before := Intersect(g, r)
+
+		g.Divide(4)
+		after := Intersect(g, r)
+
+		if len(before) != 2 || len(after) != 2 {
+			t.Fatalf("expected 2 intersections got %v and %v", len(before), len(after))
+		}
+		assertVal(t, after[0].T, before[0].T)
+		assertShape(t, after[0].Object, before[0].Object)
+	})
+
 }
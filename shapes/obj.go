@@ -0,0 +1,237 @@
+package shapes
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/seantur/ray_tracer_challenge/datatypes"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ObjParser holds the result of reading a Wavefront OBJ file. Faces are
+// fan-triangulated into the group that was active when they were read: the
+// current "g" group, else the current "o" object, else Default. A "g" or "o"
+// with no name goes back to the enclosing object or Default.
+type ObjParser struct {
+	Vertices      []datatypes.Tuple
+	Normals       []datatypes.Tuple
+	TextureCoords []datatypes.Tuple
+	Default       *Group
+	Objects       map[string]*Group
+	Groups        map[string]map[string]*Group // by object name, "" outside any object
+	Ignored       int
+
+	object, group *Group
+}
+
+// ParseObjFile opens path and parses it with ParseObj
+func ParseObjFile(path string) (*ObjParser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ParseObj(f)
+}
+
+func ParseObj(r io.Reader) (*ObjParser, error) {
+	p := ObjParser{Default: GetGroup(), Objects: map[string]*Group{}, Groups: map[string]map[string]*Group{}}
+	objectName := ""
+
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+
+	for scanner.Scan() {
+		lineNum++
+
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		var err error
+
+		switch fields[0] {
+		case "v":
+			var v datatypes.Tuple
+			v, err = parseObjTuple(fields[1:], 3)
+			v.W = 1
+			p.Vertices = append(p.Vertices, v)
+		case "vn":
+			var vn datatypes.Tuple
+			vn, err = parseObjTuple(fields[1:], 3)
+			vn.W = 0
+			p.Normals = append(p.Normals, vn)
+		case "vt":
+			var vt datatypes.Tuple
+			vt, err = parseObjTuple(fields[1:], 1)
+			p.TextureCoords = append(p.TextureCoords, vt)
+		case "f":
+			err = p.parseFace(fields[1:])
+		case "o":
+			objectName = strings.Join(fields[1:], " ")
+			p.object = p.namedGroup(p.Objects, objectName, p.Default)
+			p.group = nil
+		case "g":
+			parent := p.Default
+			if p.object != nil {
+				parent = p.object
+			}
+			if p.Groups[objectName] == nil {
+				p.Groups[objectName] = map[string]*Group{}
+			}
+			p.group = p.namedGroup(p.Groups[objectName], strings.Join(fields[1:], " "), parent)
+		default:
+			p.Ignored++
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNum, err)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+// ToGroup returns the root group holding every face and named group
+func (p *ObjParser) ToGroup() *Group {
+	return p.Default
+}
+
+func (p *ObjParser) currentGroup() *Group {
+	if p.group != nil {
+		return p.group
+	} else if p.object != nil {
+		return p.object
+	}
+	return p.Default
+}
+
+// namedGroup returns the group called name in groups, creating it under
+// parent the first time the name is seen. An empty name is no group.
+func (p *ObjParser) namedGroup(groups map[string]*Group, name string, parent *Group) *Group {
+	if name == "" {
+		return nil
+	}
+
+	if g, ok := groups[name]; ok {
+		return g
+	}
+
+	g := GetGroup()
+	parent.AddChild(g)
+	groups[name] = g
+
+	return g
+}
+
+// parseObjTuple reads min to 4 numbers into X/Y/Z, a trailing w is dropped
+func parseObjTuple(fields []string, min int) (datatypes.Tuple, error) {
+	if len(fields) < min || len(fields) > 4 {
+		return datatypes.Tuple{}, fmt.Errorf("expected %d to 4 components, got %d", min, len(fields))
+	}
+
+	vals := [3]float64{}
+
+	for i := 0; i < len(fields) && i < 3; i++ {
+		val, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return datatypes.Tuple{}, fmt.Errorf("invalid number %q", fields[i])
+		}
+		vals[i] = val
+	}
+
+	return datatypes.Tuple{X: vals[0], Y: vals[1], Z: vals[2]}, nil
+}
+
+// objIndex resolves a 1-based (or negative, relative to the end) OBJ index
+func objIndex(field string, count int, kind string) (int, error) {
+	index, err := strconv.Atoi(field)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s index %q", kind, field)
+	}
+
+	if index < 0 {
+		index = count + index + 1
+	}
+
+	if index < 1 || index > count {
+		return 0, fmt.Errorf("%s index %s out of range", kind, field)
+	}
+
+	return index - 1, nil
+}
+
+type objFaceVertex struct {
+	vertex, texture, normal int
+}
+
+func (p *ObjParser) parseFace(fields []string) error {
+	if len(fields) < 3 {
+		return fmt.Errorf("face needs at least 3 vertices, got %d", len(fields))
+	}
+
+	verts := make([]objFaceVertex, len(fields))
+	smooth, textured := true, true
+
+	for i, field := range fields {
+		parts := strings.Split(field, "/")
+		if len(parts) > 3 {
+			return fmt.Errorf("invalid face vertex %q", field)
+		}
+
+		var err error
+		verts[i] = objFaceVertex{texture: -1, normal: -1}
+
+		if verts[i].vertex, err = objIndex(parts[0], len(p.Vertices), "vertex"); err != nil {
+			return err
+		}
+
+		if len(parts) > 1 && parts[1] != "" {
+			if verts[i].texture, err = objIndex(parts[1], len(p.TextureCoords), "texture"); err != nil {
+				return err
+			}
+		} else {
+			textured = false
+		}
+
+		if len(parts) > 2 && parts[2] != "" {
+			if verts[i].normal, err = objIndex(parts[2], len(p.Normals), "normal"); err != nil {
+				return err
+			}
+		} else {
+			smooth = false
+		}
+	}
+
+	g := p.currentGroup()
+
+	for i := 1; i < len(verts)-1; i++ {
+		a, b, c := verts[0], verts[i], verts[i+1]
+
+		if smooth {
+			t := GetSmoothTriangle(
+				p.Vertices[a.vertex], p.Vertices[b.vertex], p.Vertices[c.vertex],
+				p.Normals[a.normal], p.Normals[b.normal], p.Normals[c.normal])
+			if textured {
+				t.UV1, t.UV2, t.UV3, t.HasUVs = p.TextureCoords[a.texture], p.TextureCoords[b.texture], p.TextureCoords[c.texture], true
+			}
+			g.AddChild(t)
+		} else {
+			t := GetTriangle(p.Vertices[a.vertex], p.Vertices[b.vertex], p.Vertices[c.vertex])
+			if textured {
+				t.UV1, t.UV2, t.UV3, t.HasUVs = p.TextureCoords[a.texture], p.TextureCoords[b.texture], p.TextureCoords[c.texture], true
+			}
+			g.AddChild(t)
+		}
+	}
+
+	return nil
+}
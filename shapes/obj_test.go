@@ -0,0 +1,306 @@
+package shapes
+
+import (
+	"github.com/seantur/ray_tracer_challenge/datatypes"
+	"strings"
+	"testing"
+)
+
+func TestObj(t *testing.T) {
+
+	assertShape := func(t *testing.T, got Shape, want Shape) {
+		t.Helper()
+		if got != want {
+			t.Errorf("shapes did not match: got %v want %v", got, want)
+		}
+	}
+
+	t.Run("Ignoring unrecognized lines", func(t *testing.T) {
+		gibberish := `There was a young lady named Bright
+who traveled much faster than light.
+She set out one day
+in a relative way,
+and came back the previous night.`
+
+		p, err := ParseObj(strings.NewReader(gibberish))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		datatypes.AssertVal(t, float64(p.Ignored), 5)
+	})
+
+	t.Run("Vertex records", func(t *testing.T) {
+		file := `v -1 1 0
+v -1.0000 0.5000 0.0000
+v 1 0 0
+v 1 1 0`
+
+		p, err := ParseObj(strings.NewReader(file))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		datatypes.AssertTupleEqual(t, p.Vertices[0], datatypes.Point(-1, 1, 0))
+		datatypes.AssertTupleEqual(t, p.Vertices[1], datatypes.Point(-1, 0.5, 0))
+		datatypes.AssertTupleEqual(t, p.Vertices[2], datatypes.Point(1, 0, 0))
+		datatypes.AssertTupleEqual(t, p.Vertices[3], datatypes.Point(1, 1, 0))
+	})
+
+	t.Run("Parsing triangle faces", func(t *testing.T) {
+		file := `v -1 1 0
+v -1 0 0
+v 1 0 0
+v 1 1 0
+
+f 1 2 3
+f 1 3 4`
+
+		p, err := ParseObj(strings.NewReader(file))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		g := p.Default
+		t1 := g.Shapes[0].(*Triangle)
+		t2 := g.Shapes[1].(*Triangle)
+
+		datatypes.AssertTupleEqual(t, t1.P1, p.Vertices[0])
+		datatypes.AssertTupleEqual(t, t1.P2, p.Vertices[1])
+		datatypes.AssertTupleEqual(t, t1.P3, p.Vertices[2])
+		datatypes.AssertTupleEqual(t, t2.P1, p.Vertices[0])
+		datatypes.AssertTupleEqual(t, t2.P2, p.Vertices[2])
+		datatypes.AssertTupleEqual(t, t2.P3, p.Vertices[3])
+	})
+
+	t.Run("Triangulating polygons", func(t *testing.T) {
+		file := `v -1 1 0
+v -1 0 0
+v 1 0 0
+v 1 1 0
+v 0 2 0
+
+f 1 2 3 4 5`
+
+		p, err := ParseObj(strings.NewReader(file))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		g := p.Default
+		if len(g.Shapes) != 3 {
+			t.Fatalf("expected 3 triangles got %v", len(g.Shapes))
+		}
+
+		t1 := g.Shapes[0].(*Triangle)
+		t2 := g.Shapes[1].(*Triangle)
+		t3 := g.Shapes[2].(*Triangle)
+
+		datatypes.AssertTupleEqual(t, t1.P1, p.Vertices[0])
+		datatypes.AssertTupleEqual(t, t1.P2, p.Vertices[1])
+		datatypes.AssertTupleEqual(t, t1.P3, p.Vertices[2])
+		datatypes.AssertTupleEqual(t, t2.P1, p.Vertices[0])
+		datatypes.AssertTupleEqual(t, t2.P2, p.Vertices[2])
+		datatypes.AssertTupleEqual(t, t2.P3, p.Vertices[3])
+		datatypes.AssertTupleEqual(t, t3.P1, p.Vertices[0])
+		datatypes.AssertTupleEqual(t, t3.P2, p.Vertices[3])
+		datatypes.AssertTupleEqual(t, t3.P3, p.Vertices[4])
+	})
+
+	t.Run("Triangles in groups", func(t *testing.T) {
+		file := `v -1 1 0
+v -1 0 0
+v 1 0 0
+v 1 1 0
+g FirstGroup
+f 1 2 3
+g SecondGroup
+f 1 3 4`
+
+		p, err := ParseObj(strings.NewReader(file))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		g1 := p.Groups[""]["FirstGroup"]
+		g2 := p.Groups[""]["SecondGroup"]
+
+		t1 := g1.Shapes[0].(*Triangle)
+		t2 := g2.Shapes[0].(*Triangle)
+
+		datatypes.AssertTupleEqual(t, t1.P3, p.Vertices[2])
+		datatypes.AssertTupleEqual(t, t2.P3, p.Vertices[3])
+
+		g := p.ToGroup()
+		assertShape(t, g.Shapes[0], g1)
+		assertShape(t, g.Shapes[1], g2)
+		assertShape(t, g1.GetParent(), g)
+	})
+
+	t.Run("Groups are nested inside objects", func(t *testing.T) {
+		file := `v -1 1 0
+v -1 0 0
+v 1 0 0
+o Teapot
+g Lid
+f 1 2 3
+g Body
+f 1 2 3
+o Table
+f 1 2 3`
+
+		p, err := ParseObj(strings.NewReader(file))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		g := p.ToGroup()
+		teapot := p.Objects["Teapot"]
+		table := p.Objects["Table"]
+
+		if len(g.Shapes) != 2 {
+			t.Fatalf("expected 2 objects got %v", len(g.Shapes))
+		}
+		assertShape(t, g.Shapes[0], teapot)
+		assertShape(t, g.Shapes[1], table)
+		assertShape(t, teapot.Shapes[0], p.Groups["Teapot"]["Lid"])
+		assertShape(t, teapot.Shapes[1], p.Groups["Teapot"]["Body"])
+		datatypes.AssertVal(t, float64(len(table.Shapes)), 1)
+	})
+
+	t.Run("Group names are scoped to their object", func(t *testing.T) {
+		file := `v -1 1 0
+v -1 0 0
+v 1 0 0
+o Teapot
+g Body
+f 1 2 3
+o Kettle
+g Body
+f 1 2 3
+g
+f 1 2 3
+o
+f 1 2 3`
+
+		p, err := ParseObj(strings.NewReader(file))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		teapot, kettle := p.Objects["Teapot"], p.Objects["Kettle"]
+		if p.Groups["Teapot"]["Body"] == p.Groups["Kettle"]["Body"] {
+			t.Fatal("expected each object to have its own Body group")
+		}
+		assertShape(t, teapot.Shapes[0], p.Groups["Teapot"]["Body"])
+		assertShape(t, kettle.Shapes[0], p.Groups["Kettle"]["Body"])
+
+		// an unnamed g goes back to the object and an unnamed o to the root
+		datatypes.AssertVal(t, float64(len(kettle.Shapes)), 2)
+		datatypes.AssertVal(t, float64(len(p.ToGroup().Shapes)), 3)
+		if _, ok := p.Groups["Kettle"][""]; ok {
+			t.Error("expected no group named \"\"")
+		}
+	})
+
+	t.Run("Vertex normal and texture records", func(t *testing.T) {
+		file := `vn 0 0 1
+vn 0.707 0 -0.707
+vn 1 2 3
+vt 0.5 0.25`
+
+		p, err := ParseObj(strings.NewReader(file))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		datatypes.AssertTupleEqual(t, p.Normals[0], datatypes.Vector(0, 0, 1))
+		datatypes.AssertTupleEqual(t, p.Normals[1], datatypes.Vector(0.707, 0, -0.707))
+		datatypes.AssertTupleEqual(t, p.Normals[2], datatypes.Vector(1, 2, 3))
+		datatypes.AssertTupleEqual(t, p.TextureCoords[0], datatypes.Tuple{X: 0.5, Y: 0.25})
+	})
+
+	t.Run("Faces with normals", func(t *testing.T) {
+		file := `v 0 1 0
+v -1 0 0
+v 1 0 0
+
+vn -1 0 0
+vn 1 0 0
+vn 0 1 0
+
+f 1//3 2//1 3//2
+f 1/0/3 2/102/1 3/14/2`
+
+		_, err := ParseObj(strings.NewReader(file))
+		if err == nil {
+			t.Fatal("expected out of range texture index to fail")
+		}
+
+		file = strings.Replace(file, "f 1/0/3 2/102/1 3/14/2", "vt 0 0\nf 1/1/3 2/1/1 3/-1/2", 1)
+
+		p, err := ParseObj(strings.NewReader(file))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		g := p.Default
+		t1 := g.Shapes[0].(*SmoothTriangle)
+		t2 := g.Shapes[1].(*SmoothTriangle)
+
+		datatypes.AssertTupleEqual(t, t1.P1, p.Vertices[0])
+		datatypes.AssertTupleEqual(t, t1.P2, p.Vertices[1])
+		datatypes.AssertTupleEqual(t, t1.P3, p.Vertices[2])
+		datatypes.AssertTupleEqual(t, t1.N1, p.Normals[2])
+		datatypes.AssertTupleEqual(t, t1.N2, p.Normals[0])
+		datatypes.AssertTupleEqual(t, t1.N3, p.Normals[1])
+		datatypes.AssertTupleEqual(t, t2.N1, t1.N1)
+		datatypes.AssertTupleEqual(t, t2.N3, t1.N3)
+	})
+
+	t.Run("Faces with texture coordinates", func(t *testing.T) {
+		file := `v 0 1 0
+v -1 0 0
+v 1 0 0
+
+vt 0.5 1
+vt 0 0
+vt 1 0
+
+f 1/1 2/2 3/3
+f 1 2 3`
+
+		p, err := ParseObj(strings.NewReader(file))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		t1 := p.Default.Shapes[0].(*Triangle)
+		t2 := p.Default.Shapes[1].(*Triangle)
+
+		if !t1.HasUVs || t2.HasUVs {
+			t.Fatalf("expected only the first face to have uvs, got %v and %v", t1.HasUVs, t2.HasUVs)
+		}
+		datatypes.AssertTupleEqual(t, t1.UV1, p.TextureCoords[0])
+		datatypes.AssertTupleEqual(t, t1.UV2, p.TextureCoords[1])
+		datatypes.AssertTupleEqual(t, t1.UV3, p.TextureCoords[2])
+	})
+
+	t.Run("Malformed input reports the line number", func(t *testing.T) {
+		files := map[string]string{
+			"v 1 2 3\nv 1 x 3":                     "line 2: invalid number \"x\"",
+			"v 1 2 3\nv 1 2 3\nv 1 2 3\n\nf 1 2 4": "line 5: vertex index 4 out of range",
+			"v 1 2 3\nv 1 2 4\nf 1 2":              "line 3: face needs at least 3 vertices, got 2",
+			"v 1 2 3 4 5":                          "line 1: expected 3 to 4 components, got 5",
+		}
+
+		for file, want := range files {
+			_, err := ParseObj(strings.NewReader(file))
+			if err == nil {
+				t.Errorf("expected an error for %q", file)
+				continue
+			}
+			datatypes.AssertString(t, err.Error(), want)
+		}
+	})
+}
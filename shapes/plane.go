@@ -10,6 +10,7 @@ type Plane struct {
 	Transform datatypes.Matrix
 	raytracing.Material
 	Parent Shape
+	Visibility
 }
 
 func GetPlane() *Plane {
@@ -42,6 +43,13 @@ func (p *Plane) GetTransform() datatypes.Matrix {
 
 func (p *Plane) SetTransform(m datatypes.Matrix) {
 	p.Transform = m
+	invalidateBounds(p.Parent)
+}
+
+func (p *Plane) Bounds() BoundingBox {
+	return BoundingBox{
+		Min: datatypes.Point(-datatypes.INFINITY, 0, -datatypes.INFINITY),
+		Max: datatypes.Point(datatypes.INFINITY, 0, datatypes.INFINITY)}
 }
 
 func (p *Plane) Intersect(r datatypes.Ray) []Intersection {
@@ -14,8 +14,11 @@ type Shape interface {
 	SetTransform(datatypes.Matrix)
 	GetParent() Shape
 	SetParent(Shape)
+	GetVisibility() Visibility
+	SetVisibility(Visibility)
 	Normal(datatypes.Tuple) datatypes.Tuple
 	Intersect(datatypes.Ray) []Intersection
+	Bounds() BoundingBox
 }
 
 func Normal(s Shape, world_p datatypes.Tuple) datatypes.Tuple {
@@ -42,11 +45,13 @@ func Intersect(s Shape, r datatypes.Ray) []Intersection {
 type Intersection struct {
 	T      float64
 	Object Shape
+	U, V   float64 // barycentric coordinates, only set by triangles
 }
 
 type Computation struct {
 	T, N1, N2                                             float64
 	Object                                                Shape
+	U, V                                                  float64 // the hit's barycentric coordinates
 	Point, UnderPoint, Eyev, Normalv, OverPoint, Reflectv datatypes.Tuple
 	IsInside                                              bool
 }
@@ -56,9 +61,10 @@ func (i *Intersection) PrepareComputations(r datatypes.Ray, intersections []Inte
 
 	c.T = i.T
 	c.Object = i.Object
+	c.U, c.V = i.U, i.V
 	c.Point = r.Position(c.T)
 	c.Eyev = r.Direction.Negate()
-	c.Normalv = Normal(c.Object, c.Point)
+	c.Normalv = NormalAtHit(*i, c.Point)
 
 	if datatypes.Dot(c.Normalv, c.Eyev) < 0 {
 		c.IsInside = true
@@ -111,6 +117,64 @@ func (i *Intersection) PrepareComputations(r datatypes.Ray, intersections []Inte
 	return c
 }
 
+// RayKind is what a ray was traced for, shapes can be hidden from some kinds
+type RayKind int
+
+const (
+	CameraRay RayKind = iota
+	ShadowRay
+	ReflectionRay
+	RefractionRay
+)
+
+// Visibility is kept with each shape rather than its material, so shapes
+// sharing a material can still be hidden separately. NoShadow stops the shape
+// casting shadows, NoReceiveShadow lights it as if nothing were in the way,
+// and the Hidden flags leave it out of camera, reflected and refracted rays.
+// They're all off by default.
+type Visibility struct {
+	NoShadow, NoReceiveShadow                                    bool
+	HiddenFromCamera, HiddenFromReflection, HiddenFromRefraction bool
+}
+
+func (v *Visibility) GetVisibility() Visibility {
+	return *v
+}
+
+func (v *Visibility) SetVisibility(visibility Visibility) {
+	*v = visibility
+}
+
+// VisibleTo reports whether rays of the given kind can hit s, which they
+// can't if s or any group it's in is hidden from them
+func VisibleTo(s Shape, kind RayKind) bool {
+	for ; s != nil; s = s.GetParent() {
+		v := s.GetVisibility()
+
+		switch {
+		case kind == CameraRay && v.HiddenFromCamera,
+			kind == ShadowRay && v.NoShadow,
+			kind == ReflectionRay && v.HiddenFromReflection,
+			kind == RefractionRay && v.HiddenFromRefraction:
+			return false
+		}
+	}
+
+	return true
+}
+
+// ReceivesShadows reports whether s, and every group it's in, is shaded
+// with shadows
+func ReceivesShadows(s Shape) bool {
+	for ; s != nil; s = s.GetParent() {
+		if s.GetVisibility().NoReceiveShadow {
+			return false
+		}
+	}
+
+	return true
+}
+
 // ByT implements sort.Interface for []Intersection based on the T field
 type ByT []Intersection
 
@@ -161,17 +225,45 @@ func Schlick(comp Computation) float64 {
 	return r0 + (1-r0)*math.Pow(1-cos, 5)
 }
 
+// UVShape is a shape with texture coordinates of its own, such as a triangle
+// from an OBJ file with vt coordinates. UVAt takes the barycentric u and v of
+// a hit, ok is false when the shape has no texture coordinates.
+type UVShape interface {
+	UVAt(u, v float64) (tu, tv float64, ok bool)
+}
+
+// AtObj returns the pattern's color at a world space point on shape, with the
+// point in pattern space
 func AtObj(p raytracing.Pattern, shape Shape, point datatypes.Tuple) raytracing.RGB {
-	patternTransform := p.GetTransform()
+	objPoint := WorldToObject(shape, point)
 
+	patternTransform := p.GetTransform()
 	patternTransformInv, _ := patternTransform.Inverse()
 
-	objPoint := WorldToObject(shape, point)
 	patternPoint := datatypes.TupleMultiply(patternTransformInv, objPoint)
 
 	return p.At(patternPoint)
 }
 
+// AtHit is AtObj for a hit at point with barycentric coordinates u and v. UV
+// patterns on shapes with texture coordinates of their own use those, moved
+// by the pattern's transform as the point (u, v, 0).
+func AtHit(p raytracing.Pattern, shape Shape, point datatypes.Tuple, u, v float64) raytracing.RGB {
+	if uvPattern, ok := p.(raytracing.UVPattern); ok {
+		if uvShape, ok := shape.(UVShape); ok {
+			if tu, tv, ok := uvShape.UVAt(u, v); ok {
+				patternTransform := p.GetTransform()
+				patternTransformInv, _ := patternTransform.Inverse()
+
+				uv := datatypes.TupleMultiply(patternTransformInv, datatypes.Point(tu, tv, 0))
+				return uvPattern.AtUV(uv.X, uv.Y)
+			}
+		}
+	}
+
+	return AtObj(p, shape, point)
+}
+
 func WorldToObject(shape Shape, point datatypes.Tuple) datatypes.Tuple {
 	parent := shape.GetParent()
 	if parent != nil {
@@ -203,3 +295,22 @@ func NormalAt(shape Shape, worldPoint datatypes.Tuple) datatypes.Tuple {
 	localNormal := shape.Normal(localPoint)
 	return NormalToWorld(shape, localNormal)
 }
+
+// uvNormaler is implemented by shapes whose normal is interpolated from the
+// u/v of the hit rather than derived from the point alone
+type uvNormaler interface {
+	NormalUV(obj_p datatypes.Tuple, u, v float64) datatypes.Tuple
+}
+
+func NormalAtHit(hit Intersection, worldPoint datatypes.Tuple) datatypes.Tuple {
+	localPoint := WorldToObject(hit.Object, worldPoint)
+
+	var localNormal datatypes.Tuple
+	if s, ok := hit.Object.(uvNormaler); ok {
+		localNormal = s.NormalUV(localPoint, hit.U, hit.V)
+	} else {
+		localNormal = hit.Object.Normal(localPoint)
+	}
+
+	return NormalToWorld(hit.Object, localNormal)
+}
@@ -78,8 +78,8 @@ func TestShapes(t *testing.T) {
 	t.Run("Aggregating intersections", func(t *testing.T) {
 		s := GetSphere()
 
-		i1 := Intersection{1, s}
-		i2 := Intersection{2, s}
+		i1 := Intersection{T: 1, Object: s}
+		i2 := Intersection{T: 2, Object: s}
 
 		xs := [...]Intersection{i1, i2}
 
@@ -111,8 +111,8 @@ func TestShapes(t *testing.T) {
 
 	t.Run("The hit when all intersections have positive t", func(t *testing.T) {
 		s := GetSphere()
-		i1 := Intersection{1, s}
-		i2 := Intersection{2, s}
+		i1 := Intersection{T: 1, Object: s}
+		i2 := Intersection{T: 2, Object: s}
 
 		xs := []Intersection{i1, i2}
 
@@ -125,8 +125,8 @@ func TestShapes(t *testing.T) {
 
 	t.Run("The hit where some intersections have negative t", func(t *testing.T) {
 		s := GetSphere()
-		i1 := Intersection{-1, s}
-		i2 := Intersection{1, s}
+		i1 := Intersection{T: -1, Object: s}
+		i2 := Intersection{T: 1, Object: s}
 
 		xs := []Intersection{i1, i2}
 
@@ -139,8 +139,8 @@ func TestShapes(t *testing.T) {
 
 	t.Run("The hit where all intersections have negative t", func(t *testing.T) {
 		s := GetSphere()
-		i1 := Intersection{-2, s}
-		i2 := Intersection{-1, s}
+		i1 := Intersection{T: -2, Object: s}
+		i2 := Intersection{T: -1, Object: s}
 
 		xs := []Intersection{i1, i2}
 
@@ -153,10 +153,10 @@ func TestShapes(t *testing.T) {
 
 	t.Run("The hit is always the lower nonnegative intersection", func(t *testing.T) {
 		s := GetSphere()
-		i1 := Intersection{5, s}
-		i2 := Intersection{7, s}
-		i3 := Intersection{-3, s}
-		i4 := Intersection{2, s}
+		i1 := Intersection{T: 5, Object: s}
+		i2 := Intersection{T: 7, Object: s}
+		i3 := Intersection{T: -3, Object: s}
+		i4 := Intersection{T: 2, Object: s}
 
 		xs := []Intersection{i1, i2, i3, i4}
 		i, _ := Hit(xs)
@@ -179,7 +179,7 @@ func TestShapes(t *testing.T) {
 		s := GetGlassSphere()
 
 		r := datatypes.Ray{datatypes.Point(0, 0, math.Sqrt(2)/2), datatypes.Vector(0, 1, 0)}
-		xs := []Intersection{Intersection{-math.Sqrt(2) / 2, s}, Intersection{math.Sqrt(2) / 2, s}}
+		xs := []Intersection{Intersection{T: -math.Sqrt(2) / 2, Object: s}, Intersection{T: math.Sqrt(2) / 2, Object: s}}
 		comps := xs[1].PrepareComputations(r, xs)
 
 		reflectance := Schlick(comps)
@@ -191,7 +191,7 @@ func TestShapes(t *testing.T) {
 		s := GetGlassSphere()
 
 		r := datatypes.Ray{datatypes.Point(0, 0, 0), datatypes.Vector(0, 1, 0)}
-		xs := []Intersection{Intersection{-1, s}, Intersection{1, s}}
+		xs := []Intersection{Intersection{T: -1, Object: s}, Intersection{T: 1, Object: s}}
 		comps := xs[1].PrepareComputations(r, xs)
 
 		assertVal(t, Schlick(comps), 0.04)
@@ -201,7 +201,7 @@ func TestShapes(t *testing.T) {
 		s := GetGlassSphere()
 
 		r := datatypes.Ray{datatypes.Point(0, 0.99, -2), datatypes.Vector(0, 0, 1)}
-		xs := []Intersection{Intersection{1.8589, s}}
+		xs := []Intersection{Intersection{T: 1.8589, Object: s}}
 		comps := xs[0].PrepareComputations(r, xs)
 
 		assertVal(t, Schlick(comps), 0.48873)
@@ -244,4 +244,63 @@ func TestShapes(t *testing.T) {
 			t.Error("expected parent to be nil")
 		}
 	})
+
+	t.Run("Shapes are visible to every kind of ray by default", func(t *testing.T) {
+		obj := GetSphere()
+
+		for _, kind := range []RayKind{CameraRay, ShadowRay, ReflectionRay, RefractionRay} {
+			if !VisibleTo(obj, kind) {
+				t.Errorf("expected the sphere to be visible to ray kind %d", kind)
+			}
+		}
+		if !ReceivesShadows(obj) {
+			t.Error("expected the sphere to receive shadows")
+		}
+	})
+
+	t.Run("Flags hide a shape from one kind of ray", func(t *testing.T) {
+		obj := GetSphere()
+		v := obj.GetVisibility()
+		v.HiddenFromReflection = true
+		obj.SetVisibility(v)
+
+		if VisibleTo(obj, ReflectionRay) {
+			t.Error("expected the sphere to be hidden from reflections")
+		}
+		if !VisibleTo(obj, CameraRay) || !VisibleTo(obj, ShadowRay) || !VisibleTo(obj, RefractionRay) {
+			t.Error("expected the sphere to be visible to other rays")
+		}
+	})
+
+	t.Run("Children inherit the flags of their groups", func(t *testing.T) {
+		outer, inner := GetGroup(), GetGroup()
+		obj := GetSphere()
+		inner.AddChild(obj)
+		outer.AddChild(inner)
+
+		v := outer.GetVisibility()
+		v.NoShadow = true
+		v.NoReceiveShadow = true
+		outer.SetVisibility(v)
+
+		if VisibleTo(obj, ShadowRay) {
+			t.Error("expected the sphere not to cast shadows")
+		}
+		if ReceivesShadows(obj) {
+			t.Error("expected the sphere not to receive shadows")
+		}
+		if !VisibleTo(obj, CameraRay) {
+			t.Error("expected the sphere to be visible to the camera")
+		}
+	})
+
+	t.Run("Setting a material keeps the flags", func(t *testing.T) {
+		obj := GetSphere()
+		obj.SetVisibility(Visibility{HiddenFromCamera: true})
+		obj.SetMaterial(raytracing.GetMaterial())
+
+		if VisibleTo(obj, CameraRay) {
+			t.Error("expected the sphere to stay hidden from the camera")
+		}
+	})
 }
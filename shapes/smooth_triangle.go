@@ -0,0 +1,98 @@
+package shapes
+
+import (
+	"github.com/seantur/ray_tracer_challenge/datatypes"
+	"github.com/seantur/ray_tracer_challenge/raytracing"
+)
+
+type SmoothTriangle struct {
+	Transform datatypes.Matrix
+	raytracing.Material
+	P1, P2, P3 datatypes.Tuple
+	N1, N2, N3 datatypes.Tuple
+	E1, E2     datatypes.Tuple
+	Parent     Shape
+	Visibility
+
+	// texture coordinates of the corners, only used when HasUVs is set
+	UV1, UV2, UV3 datatypes.Tuple
+	HasUVs        bool
+}
+
+func GetSmoothTriangle(p1, p2, p3, n1, n2, n3 datatypes.Tuple) *SmoothTriangle {
+	t := SmoothTriangle{P1: p1, P2: p2, P3: p3, N1: n1, N2: n2, N3: n3}
+	t.Transform = datatypes.GetIdentity()
+	t.Material = raytracing.GetMaterial()
+
+	t.E1 = datatypes.Subtract(p2, p1)
+	t.E2 = datatypes.Subtract(p3, p1)
+
+	return &t
+}
+
+func (t *SmoothTriangle) GetParent() Shape {
+	return t.Parent
+}
+
+func (t *SmoothTriangle) SetParent(s Shape) {
+	t.Parent = s
+}
+
+func (t *SmoothTriangle) GetMaterial() raytracing.Material {
+	return t.Material
+}
+
+func (t *SmoothTriangle) SetMaterial(m raytracing.Material) {
+	t.Material = m
+}
+
+func (t *SmoothTriangle) GetTransform() datatypes.Matrix {
+	return t.Transform
+}
+
+func (t *SmoothTriangle) SetTransform(m datatypes.Matrix) {
+	t.Transform = m
+	invalidateBounds(t.Parent)
+}
+
+func (t *SmoothTriangle) Bounds() BoundingBox {
+	b := GetBoundingBox()
+	b.AddPoint(t.P1)
+	b.AddPoint(t.P2)
+	b.AddPoint(t.P3)
+
+	return b
+}
+
+func (t *SmoothTriangle) Intersect(r datatypes.Ray) []Intersection {
+	tVal, u, v, ok := intersectTriangle(r, t.P1, t.E1, t.E2)
+	if !ok {
+		return []Intersection{}
+	}
+
+	return []Intersection{Intersection{T: tVal, Object: t, U: u, V: v}}
+}
+
+// Normal recovers the barycentric u/v of a point on the triangle, for callers
+// that don't have the intersection at hand
+func (t *SmoothTriangle) Normal(obj_p datatypes.Tuple) datatypes.Tuple {
+	u, v := barycentric(obj_p, t.P1, t.E1, t.E2)
+	return t.NormalUV(obj_p, u, v)
+}
+
+func (t *SmoothTriangle) NormalUV(obj_p datatypes.Tuple, u, v float64) datatypes.Tuple {
+	return datatypes.Add(
+		datatypes.Add(t.N2.Multiply(u), t.N3.Multiply(v)),
+		t.N1.Multiply(1-u-v))
+}
+
+// UVAt blends the corners' texture coordinates at the hit with barycentric
+// coordinates u and v, the same ones its normal is blended by
+func (t *SmoothTriangle) UVAt(u, v float64) (tu, tv float64, ok bool) {
+	if !t.HasUVs {
+		return 0, 0, false
+	}
+
+	tu, tv = interpolateUVs(u, v, t.UV1, t.UV2, t.UV3)
+	return tu, tv, true
+}
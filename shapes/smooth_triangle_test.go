@@ -0,0 +1,86 @@
+package shapes
+
+import (
+	"github.com/seantur/ray_tracer_challenge/datatypes"
+	"math"
+	"testing"
+)
+
+func TestSmoothTriangles(t *testing.T) {
+
+	getTri := func() *SmoothTriangle {
+		return GetSmoothTriangle(
+			datatypes.Point(0, 1, 0), datatypes.Point(-1, 0, 0), datatypes.Point(1, 0, 0),
+			datatypes.Vector(0, 1, 0), datatypes.Vector(-1, 0, 0), datatypes.Vector(1, 0, 0))
+	}
+
+	t.Run("Constructing a smooth triangle", func(t *testing.T) {
+		tri := getTri()
+
+		datatypes.AssertTupleEqual(t, tri.P1, datatypes.Point(0, 1, 0))
+		datatypes.AssertTupleEqual(t, tri.P2, datatypes.Point(-1, 0, 0))
+		datatypes.AssertTupleEqual(t, tri.P3, datatypes.Point(1, 0, 0))
+		datatypes.AssertTupleEqual(t, tri.N1, datatypes.Vector(0, 1, 0))
+		datatypes.AssertTupleEqual(t, tri.N2, datatypes.Vector(-1, 0, 0))
+		datatypes.AssertTupleEqual(t, tri.N3, datatypes.Vector(1, 0, 0))
+	})
+
+	t.Run("An intersection with a smooth triangle stores u/v", func(t *testing.T) {
+		tri := getTri()
+		r := datatypes.Ray{Origin: datatypes.Point(-0.2, 0.3, -2), Direction: datatypes.Vector(0, 0, 1)}
+
+		xs := tri.Intersect(r)
+
+		if len(xs) != 1 {
+			t.Fatalf("expected 1 intersection got %v", len(xs))
+		}
+		datatypes.AssertVal(t, math.Round(xs[0].U*100)/100, 0.45)
+		datatypes.AssertVal(t, math.Round(xs[0].V*100)/100, 0.25)
+	})
+
+	t.Run("A smooth triangle uses u/v to interpolate the normal", func(t *testing.T) {
+		tri := getTri()
+		i := Intersection{T: 1, Object: tri, U: 0.45, V: 0.25}
+
+		n := NormalAtHit(i, datatypes.Point(0, 0, 0))
+		datatypes.AssertTupleEqual(t, n, datatypes.Vector(-0.5547, 0.83205, 0))
+	})
+
+	t.Run("A smooth triangle recovers u/v from the point", func(t *testing.T) {
+		tri := getTri()
+
+		n := NormalAt(tri, datatypes.Point(-0.2, 0.3, 0))
+		datatypes.AssertTupleEqual(t, n, datatypes.Vector(-0.5547, 0.83205, 0))
+	})
+
+	t.Run("Preparing the normal on a smooth triangle", func(t *testing.T) {
+		tri := getTri()
+		i := Intersection{T: 1, Object: tri, U: 0.45, V: 0.25}
+		r := datatypes.Ray{Origin: datatypes.Point(-0.2, 0.3, -2), Direction: datatypes.Vector(0, 0, 1)}
+
+		comps := i.PrepareComputations(r, []Intersection{i})
+		datatypes.AssertTupleEqual(t, comps.Normalv, datatypes.Vector(-0.5547, 0.83205, 0))
+	})
+
+	t.Run("Preparing the normal on a smooth triangle in a nested group", func(t *testing.T) {
+		g1 := GetGroup()
+		g1.SetTransform(datatypes.GetRotationY(math.Pi / 2))
+
+		g2 := GetGroup()
+		g2.SetTransform(datatypes.GetScaling(2, 2, 2))
+		g1.AddChild(g2)
+
+		tri := getTri()
+		g2.AddChild(tri)
+
+		r := datatypes.Ray{Origin: datatypes.Point(-4, 0.6, 0.4), Direction: datatypes.Vector(1, 0, 0)}
+		xs := Intersect(g1, r)
+
+		if len(xs) != 1 {
+			t.Fatalf("expected 1 intersection got %v", len(xs))
+		}
+
+		comps := xs[0].PrepareComputations(r, xs)
+		datatypes.AssertTupleEqual(t, comps.Normalv, datatypes.Vector(-0, 0.83205, 0.5547))
+	})
+}
@@ -10,6 +10,7 @@ type Sphere struct {
 	Transform datatypes.Matrix
 	raytracing.Material
 	Parent Shape
+	Visibility
 }
 
 func GetSphere() *Sphere {
@@ -42,6 +43,11 @@ func (s *Sphere) GetTransform() datatypes.Matrix {
 
 func (s *Sphere) SetTransform(m datatypes.Matrix) {
 	s.Transform = m
+	invalidateBounds(s.Parent)
+}
+
+func (s *Sphere) Bounds() BoundingBox {
+	return BoundingBox{Min: datatypes.Point(-1, -1, -1), Max: datatypes.Point(1, 1, 1)}
 }
 
 func (s *Sphere) Intersect(r datatypes.Ray) (xs []Intersection) {
@@ -58,8 +64,8 @@ func (s *Sphere) Intersect(r datatypes.Ray) (xs []Intersection) {
 	}
 
 	xs = []Intersection{
-		Intersection{(-b - math.Sqrt(discriminant)) / (2 * a), s},
-		Intersection{(-b + math.Sqrt(discriminant)) / (2 * a), s}}
+		Intersection{T: (-b - math.Sqrt(discriminant)) / (2 * a), Object: s},
+		Intersection{T: (-b + math.Sqrt(discriminant)) / (2 * a), Object: s}}
 
 	return
 }
@@ -210,12 +210,12 @@ func TestSpheres(t *testing.T) {
 
 		r := datatypes.Ray{Origin: datatypes.Point(0, 0, -4), Direction: datatypes.Vector(0, 0, 1)}
 
-		xs := []Intersection{Intersection{2, A},
-			Intersection{2.75, B},
-			Intersection{3.25, C},
-			Intersection{4.75, B},
-			Intersection{5.25, C},
-			Intersection{6, A}}
+		xs := []Intersection{Intersection{T: 2, Object: A},
+			Intersection{T: 2.75, Object: B},
+			Intersection{T: 3.25, Object: C},
+			Intersection{T: 4.75, Object: B},
+			Intersection{T: 5.25, Object: C},
+			Intersection{T: 6, Object: A}}
 
 		n1 := []float64{1.0, 1.5, 2.0, 2.5, 2.5, 1.5}
 		n2 := []float64{1.5, 2.0, 2.5, 2.5, 1.5, 1.0}
@@ -232,7 +232,7 @@ func TestSpheres(t *testing.T) {
 		sphere.SetTransform(datatypes.GetTranslation(0, 0, 1))
 		r := datatypes.Ray{Origin: datatypes.Point(0, 0, -5), Direction: datatypes.Vector(0, 0, 1)}
 
-		i := Intersection{5, sphere}
+		i := Intersection{T: 5, Object: sphere}
 
 		comps := i.PrepareComputations(r, []Intersection{i})
 
@@ -0,0 +1,61 @@
+package shapes
+
+import (
+	"github.com/seantur/ray_tracer_challenge/datatypes"
+	"github.com/seantur/ray_tracer_challenge/raytracing"
+)
+
+// TestShape records the last ray it was intersected with
+type TestShape struct {
+	Transform datatypes.Matrix
+	raytracing.Material
+	Parent Shape
+	Visibility
+	SavedRay *datatypes.Ray
+}
+
+func GetTestShape() *TestShape {
+	s := TestShape{}
+	s.Transform = datatypes.GetIdentity()
+	s.Material = raytracing.GetMaterial()
+
+	return &s
+}
+
+func (s *TestShape) GetParent() Shape {
+	return s.Parent
+}
+
+func (s *TestShape) SetParent(shape Shape) {
+	s.Parent = shape
+}
+
+func (s *TestShape) GetMaterial() raytracing.Material {
+	return s.Material
+}
+
+func (s *TestShape) SetMaterial(m raytracing.Material) {
+	s.Material = m
+}
+
+func (s *TestShape) GetTransform() datatypes.Matrix {
+	return s.Transform
+}
+
+func (s *TestShape) SetTransform(m datatypes.Matrix) {
+	s.Transform = m
+	invalidateBounds(s.Parent)
+}
+
+func (s *TestShape) Bounds() BoundingBox {
+	return BoundingBox{Min: datatypes.Point(-1, -1, -1), Max: datatypes.Point(1, 1, 1)}
+}
+
+func (s *TestShape) Intersect(r datatypes.Ray) []Intersection {
+	s.SavedRay = &r
+	return []Intersection{}
+}
+
+func (s *TestShape) Normal(obj_p datatypes.Tuple) datatypes.Tuple {
+	return datatypes.Vector(obj_p.X, obj_p.Y, obj_p.Z)
+}
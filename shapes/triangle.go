@@ -0,0 +1,153 @@
+package shapes
+
+import (
+	"github.com/seantur/ray_tracer_challenge/datatypes"
+	"github.com/seantur/ray_tracer_challenge/raytracing"
+	"math"
+)
+
+type Triangle struct {
+	Transform datatypes.Matrix
+	raytracing.Material
+	P1, P2, P3 datatypes.Tuple
+	E1, E2     datatypes.Tuple
+	Parent     Shape
+	Visibility
+	normal datatypes.Tuple
+
+	// texture coordinates of the corners, only used when HasUVs is set
+	UV1, UV2, UV3 datatypes.Tuple
+	HasUVs        bool
+}
+
+func GetTriangle(p1, p2, p3 datatypes.Tuple) *Triangle {
+	t := Triangle{P1: p1, P2: p2, P3: p3}
+	t.Transform = datatypes.GetIdentity()
+	t.Material = raytracing.GetMaterial()
+
+	t.E1 = datatypes.Subtract(p2, p1)
+	t.E2 = datatypes.Subtract(p3, p1)
+
+	normal := datatypes.Cross(t.E2, t.E1)
+	t.normal = normal.Normalize()
+
+	return &t
+}
+
+func (t *Triangle) GetParent() Shape {
+	return t.Parent
+}
+
+func (t *Triangle) SetParent(s Shape) {
+	t.Parent = s
+}
+
+func (t *Triangle) GetMaterial() raytracing.Material {
+	return t.Material
+}
+
+func (t *Triangle) SetMaterial(m raytracing.Material) {
+	t.Material = m
+}
+
+func (t *Triangle) GetTransform() datatypes.Matrix {
+	return t.Transform
+}
+
+func (t *Triangle) SetTransform(m datatypes.Matrix) {
+	t.Transform = m
+	invalidateBounds(t.Parent)
+}
+
+func (t *Triangle) Bounds() BoundingBox {
+	b := GetBoundingBox()
+	b.AddPoint(t.P1)
+	b.AddPoint(t.P2)
+	b.AddPoint(t.P3)
+
+	return b
+}
+
+func (t *Triangle) Intersect(r datatypes.Ray) []Intersection {
+	tVal, u, v, ok := intersectTriangle(r, t.P1, t.E1, t.E2)
+	if !ok {
+		return []Intersection{}
+	}
+
+	return []Intersection{Intersection{T: tVal, Object: t, U: u, V: v}}
+}
+
+func (t *Triangle) Normal(obj_p datatypes.Tuple) datatypes.Tuple {
+	return t.normal
+}
+
+// Möller–Trumbore ray/triangle intersection
+func intersectTriangle(r datatypes.Ray, p1, e1, e2 datatypes.Tuple) (t, u, v float64, ok bool) {
+	dirCrossE2 := datatypes.Cross(r.Direction, e2)
+	det := datatypes.Dot(e1, dirCrossE2)
+
+	if math.Abs(det) < datatypes.EPSILON {
+		return
+	}
+
+	f := 1.0 / det
+	p1ToOrigin := datatypes.Subtract(r.Origin, p1)
+
+	u = f * datatypes.Dot(p1ToOrigin, dirCrossE2)
+	if u < 0 || u > 1 {
+		return
+	}
+
+	originCrossE1 := datatypes.Cross(p1ToOrigin, e1)
+
+	v = f * datatypes.Dot(r.Direction, originCrossE1)
+	if v < 0 || (u+v) > 1 {
+		return
+	}
+
+	t = f * datatypes.Dot(e2, originCrossE1)
+
+	return t, u, v, true
+}
+
+// barycentric returns the weights of p2 (u) and p3 (v) for a point on the triangle
+func barycentric(p, p1, e1, e2 datatypes.Tuple) (u, v float64) {
+	p1ToP := datatypes.Subtract(p, p1)
+
+	d11 := datatypes.Dot(e1, e1)
+	d12 := datatypes.Dot(e1, e2)
+	d22 := datatypes.Dot(e2, e2)
+	dp1 := datatypes.Dot(p1ToP, e1)
+	dp2 := datatypes.Dot(p1ToP, e2)
+
+	// denom is |e1|²|e2|² sin² of the angle between the edges, so compare the
+	// angle rather than the area to work for triangles of any size
+	denom := d11*d22 - d12*d12
+	if denom <= datatypes.EPSILON*d11*d22 {
+		return
+	}
+
+	u = (d22*dp1 - d12*dp2) / denom
+	v = (d11*dp2 - d12*dp1) / denom
+
+	return
+}
+
+// UVAt blends the corners' texture coordinates at the hit with barycentric
+// coordinates u and v
+func (t *Triangle) UVAt(u, v float64) (tu, tv float64, ok bool) {
+	if !t.HasUVs {
+		return 0, 0, false
+	}
+
+	tu, tv = interpolateUVs(u, v, t.UV1, t.UV2, t.UV3)
+	return tu, tv, true
+}
+
+// interpolateUVs blends the corners' uvs by the weights u of the second
+// corner and v of the third
+func interpolateUVs(u, v float64, uv1, uv2, uv3 datatypes.Tuple) (tu, tv float64) {
+	w := 1 - u - v
+
+	return w*uv1.X + u*uv2.X + v*uv3.X, w*uv1.Y + u*uv2.Y + v*uv3.Y
+}
@@ -0,0 +1,161 @@
+package shapes
+
+import (
+	"github.com/seantur/ray_tracer_challenge/datatypes"
+	"github.com/seantur/ray_tracer_challenge/raytracing"
+	"testing"
+)
+
+func TestTriangles(t *testing.T) {
+
+	t.Run("Constructing a triangle", func(t *testing.T) {
+		p1 := datatypes.Point(0, 1, 0)
+		p2 := datatypes.Point(-1, 0, 0)
+		p3 := datatypes.Point(1, 0, 0)
+		tri := GetTriangle(p1, p2, p3)
+
+		datatypes.AssertTupleEqual(t, tri.P1, p1)
+		datatypes.AssertTupleEqual(t, tri.P2, p2)
+		datatypes.AssertTupleEqual(t, tri.P3, p3)
+		datatypes.AssertTupleEqual(t, tri.E1, datatypes.Vector(-1, -1, 0))
+		datatypes.AssertTupleEqual(t, tri.E2, datatypes.Vector(1, -1, 0))
+		datatypes.AssertTupleEqual(t, tri.normal, datatypes.Vector(0, 0, -1))
+	})
+
+	t.Run("Finding the normal on a triangle", func(t *testing.T) {
+		tri := GetTriangle(datatypes.Point(0, 1, 0), datatypes.Point(-1, 0, 0), datatypes.Point(1, 0, 0))
+
+		datatypes.AssertTupleEqual(t, tri.Normal(datatypes.Point(0, 0.5, 0)), tri.normal)
+		datatypes.AssertTupleEqual(t, tri.Normal(datatypes.Point(-0.5, 0.75, 0)), tri.normal)
+		datatypes.AssertTupleEqual(t, tri.Normal(datatypes.Point(0.5, 0.25, 0)), tri.normal)
+	})
+
+	t.Run("Intersecting a ray parallel to the triangle", func(t *testing.T) {
+		tri := GetTriangle(datatypes.Point(0, 1, 0), datatypes.Point(-1, 0, 0), datatypes.Point(1, 0, 0))
+		r := datatypes.Ray{Origin: datatypes.Point(0, -1, -2), Direction: datatypes.Vector(0, 1, 0)}
+
+		datatypes.AssertVal(t, float64(len(tri.Intersect(r))), 0)
+	})
+
+	t.Run("A ray misses the triangle edges", func(t *testing.T) {
+		tri := GetTriangle(datatypes.Point(0, 1, 0), datatypes.Point(-1, 0, 0), datatypes.Point(1, 0, 0))
+
+		rays := []datatypes.Ray{
+			datatypes.Ray{Origin: datatypes.Point(1, 1, -2), Direction: datatypes.Vector(0, 0, 1)},
+			datatypes.Ray{Origin: datatypes.Point(-1, 1, -2), Direction: datatypes.Vector(0, 0, 1)},
+			datatypes.Ray{Origin: datatypes.Point(0, -1, -2), Direction: datatypes.Vector(0, 0, 1)}}
+
+		for _, r := range rays {
+			datatypes.AssertVal(t, float64(len(tri.Intersect(r))), 0)
+		}
+	})
+
+	t.Run("A ray strikes a triangle", func(t *testing.T) {
+		tri := GetTriangle(datatypes.Point(0, 1, 0), datatypes.Point(-1, 0, 0), datatypes.Point(1, 0, 0))
+		r := datatypes.Ray{Origin: datatypes.Point(0, 0.5, -2), Direction: datatypes.Vector(0, 0, 1)}
+
+		xs := tri.Intersect(r)
+
+		if len(xs) != 1 {
+			t.Fatalf("expected 1 intersection got %v", len(xs))
+		}
+		datatypes.AssertVal(t, xs[0].T, 2)
+		if xs[0].Object != tri {
+			t.Error("Expected intersection object didn't match")
+		}
+	})
+
+	t.Run("Intersecting a triangle inside a transformed group", func(t *testing.T) {
+		g := GetGroup()
+		g.SetTransform(datatypes.GetTranslation(0, 0, 3))
+
+		tri := GetTriangle(datatypes.Point(0, 1, 0), datatypes.Point(-1, 0, 0), datatypes.Point(1, 0, 0))
+		g.AddChild(tri)
+
+		r := datatypes.Ray{Origin: datatypes.Point(0, 0.5, -2), Direction: datatypes.Vector(0, 0, 1)}
+		xs := Intersect(g, r)
+
+		if len(xs) != 1 {
+			t.Fatalf("expected 1 intersection got %v", len(xs))
+		}
+		datatypes.AssertVal(t, xs[0].T, 5)
+
+		comps := xs[0].PrepareComputations(r, xs)
+		datatypes.AssertTupleEqual(t, comps.Normalv, datatypes.Vector(0, 0, -1))
+	})
+	t.Run("A triangle interpolates the texture coordinates of its corners", func(t *testing.T) {
+		tri := GetTriangle(datatypes.Point(0, 1, 0), datatypes.Point(-1, 0, 0), datatypes.Point(1, 0, 0))
+
+		if _, _, ok := tri.UVAt(0.25, 0.25); ok {
+			t.Error("expected no texture coordinates without uvs")
+		}
+
+		tri.UV1, tri.UV2, tri.UV3, tri.HasUVs = datatypes.Tuple{X: 0.5, Y: 1}, datatypes.Tuple{X: 0, Y: 0}, datatypes.Tuple{X: 1, Y: 0}, true
+
+		u, v, ok := tri.UVAt(0, 0)
+		if !ok {
+			t.Fatal("expected texture coordinates")
+		}
+		datatypes.AssertVal(t, u, 0.5)
+		datatypes.AssertVal(t, v, 1)
+
+		// the hit at (0.5, 0.25) is a quarter of the way along e1 and half
+		// along e2
+		r := datatypes.Ray{Origin: datatypes.Point(0.5, 0.25, -2), Direction: datatypes.Vector(0, 0, 1)}
+		xs := tri.Intersect(r)
+		comps := xs[0].PrepareComputations(r, xs)
+		datatypes.AssertVal(t, comps.U, 0.125)
+		datatypes.AssertVal(t, comps.V, 0.625)
+
+		u, v, _ = tri.UVAt(comps.U, comps.V)
+		datatypes.AssertVal(t, u, 0.75)
+		datatypes.AssertVal(t, v, 0.25)
+	})
+
+	t.Run("Small triangles interpolate their texture coordinates too", func(t *testing.T) {
+		s := 0.02
+		tri := GetTriangle(datatypes.Point(0, s, 0), datatypes.Point(-s, 0, 0), datatypes.Point(s, 0, 0))
+		tri.UV1, tri.UV2, tri.UV3, tri.HasUVs = datatypes.Tuple{X: 0.5, Y: 1}, datatypes.Tuple{X: 0, Y: 0}, datatypes.Tuple{X: 1, Y: 0}, true
+
+		p := datatypes.Point(0.25*s, 0.25*s, 0)
+		xs := tri.Intersect(datatypes.Ray{Origin: datatypes.Point(p.X, p.Y, -1), Direction: datatypes.Vector(0, 0, 1)})
+		if len(xs) != 1 {
+			t.Fatalf("expected a hit, got %v", xs)
+		}
+
+		u, v, _ := tri.UVAt(xs[0].U, xs[0].V)
+		if !datatypes.IsClose(u, 0.625) || !datatypes.IsClose(v, 0.25) {
+			t.Errorf("got uv (%f, %f) want (0.625, 0.25)", u, v)
+		}
+
+		// solving from the point agrees with the hit
+		bu, bv := barycentric(p, tri.P1, tri.E1, tri.E2)
+		if !datatypes.IsClose(bu, 0.25) || !datatypes.IsClose(bv, 0.5) {
+			t.Errorf("got barycentric (%f, %f) want (0.25, 0.5)", bu, bv)
+		}
+	})
+
+	t.Run("UV patterns use a triangle's own texture coordinates", func(t *testing.T) {
+		red := raytracing.RGB{Red: 1, Green: 0, Blue: 0}
+		green := raytracing.RGB{Red: 0, Green: 1, Blue: 0}
+		tex := &raytracing.ImageTexture{Width: 2, Height: 1, Pixels: []raytracing.RGB{red, green}, Nearest: true, Transform: datatypes.GetIdentity()}
+
+		tri := GetTriangle(datatypes.Point(0, 1, 0), datatypes.Point(-1, 0, 0), datatypes.Point(1, 0, 0))
+		tri.UV1, tri.UV2, tri.UV3, tri.HasUVs = datatypes.Tuple{X: 0.75}, datatypes.Tuple{X: 0.75}, datatypes.Tuple{X: 0.75}, true
+
+		p := datatypes.Point(-0.6, 0.2, 0)
+		raytracing.AssertColorsEqual(t, AtHit(tex, tri, p, 0.6, 0.2), green)
+
+		// the pattern's transform moves the texture coordinates
+		tex.Transform = datatypes.GetTranslation(0.5, 0, 0)
+		raytracing.AssertColorsEqual(t, AtHit(tex, tri, p, 0.6, 0.2), red)
+		tex.Transform = datatypes.GetScaling(3, 1, 1)
+		raytracing.AssertColorsEqual(t, AtHit(tex, tri, p, 0.6, 0.2), red)
+		tex.Transform = datatypes.GetIdentity()
+
+		// without uvs, or looked up by point alone, the planar mapping is used
+		raytracing.AssertColorsEqual(t, AtObj(tex, tri, p), red)
+		tri.HasUVs = false
+		raytracing.AssertColorsEqual(t, AtHit(tex, tri, p, 0.6, 0.2), red)
+	})
+}